@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"rental-server/internal/backup"
+	"rental-server/internal/repository"
+)
+
+// runBackup writes the data of a user to a file, or to stdout without one:
+//
+//	main backup -user 1 [backup.json]
+func runBackup(rep repository.RentObjectRepository, args []string) error {
+	flags := flag.NewFlagSet("backup", flag.ContinueOnError)
+	userID := flags.Int64("user", 0, "user id")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	b, err := backup.Create(context.Background(), rep, *userID)
+	if err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		return backup.Write(os.Stdout, b)
+	}
+	file, err := os.Create(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	if err := backup.Write(file, b); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// runRestore restores a backup file to a user:
+//
+//	main restore -user 1 [-mode merge|replace] backup.json
+func runRestore(rep repository.RentObjectRepository, args []string) error {
+	flags := flag.NewFlagSet("restore", flag.ContinueOnError)
+	userID := flags.Int64("user", 0, "user id")
+	modeName := flags.String("mode", string(backup.Merge), "merge or replace")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("restore: backup file is required")
+	}
+	mode, err := backup.ParseMode(*modeName)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	b, err := backup.Read(file)
+	if err != nil {
+		return err
+	}
+	report, err := backup.Restore(context.Background(), rep, *userID, b, mode)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"rental-server/internal/repository"
+	"strings"
+	"time"
+)
+
+// invalidateCachePath is where instances take the changes of objects made
+// by their peers.
+const invalidateCachePath = "/invalidateCache"
+
+type cacheInvalidation struct {
+	UserID     int64  `json:"user_id"`
+	ObjectName string `json:"object_name"`
+}
+
+// notifyPeers sends each change of an object to the peers, base URLs such
+// as http://10.0.0.2:8080, in the background. Failures are logged, and the
+// peers then drop the object after their TTL.
+func notifyPeers(peers []string) func(userID int64, objectName string) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return func(userID int64, objectName string) {
+		body, _ := json.Marshal(cacheInvalidation{UserID: userID, ObjectName: objectName})
+		for _, peer := range peers {
+			go func(url string) {
+				resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+				if err == nil {
+					resp.Body.Close()
+					if resp.StatusCode != http.StatusNoContent {
+						err = fmt.Errorf("unexpected status %s", resp.Status)
+					}
+				}
+				if err != nil {
+					slog.Error("Cache invalidation", "peer", url, "error", err)
+				}
+			}(strings.TrimSuffix(peer, "/") + invalidateCachePath)
+		}
+	}
+}
+
+// invalidateCache drops the objects that peers changed from the cache.
+func invalidateCache(cache *repository.CachingRepository) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var invalidation cacheInvalidation
+		if err := json.NewDecoder(r.Body).Decode(&invalidation); err != nil {
+			http.Error(w, "Error while parsing body", http.StatusUnprocessableEntity)
+			return
+		}
+		cache.Invalidate(invalidation.UserID, invalidation.ObjectName)
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
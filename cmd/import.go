@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"rental-server/internal/importer"
+	"rental-server/internal/repository"
+	"strings"
+)
+
+type mappingFlag []string
+
+func (m *mappingFlag) String() string {
+	return strings.Join(*m, ",")
+}
+
+func (m *mappingFlag) Set(value string) error {
+	*m = append(*m, value)
+	return nil
+}
+
+// runImport adds records to an object from a CSV file:
+//
+//	main import -user 1 -object Name -map date=Месяц -map rent=Аренда [-dry-run] records.csv
+func runImport(rep repository.RentObjectRepository, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	userID := flags.Int64("user", 0, "user id")
+	objectName := flags.String("object", "", "name of the rent object")
+	dryRun := flags.Bool("dry-run", false, "only validate the rows")
+	comma := flags.String("comma", "", "field separator, guessed from the header by default")
+	dateLayout := flags.String("date-layout", "", "Go time layout of dates, e.g. 02.01.2006")
+	var pairs mappingFlag
+	flags.Var(&pairs, "map", "column mapping as field=Column, may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *objectName == "" || flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("import: object name and CSV file are required")
+	}
+
+	options := importer.Options{DateLayout: *dateLayout}
+	if len(pairs) != 0 {
+		mapping, err := importer.ParseMapping(pairs)
+		if err != nil {
+			return err
+		}
+		options.Mapping = mapping
+	}
+	if *comma != "" {
+		options.Comma = []rune(*comma)[0]
+	}
+
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	report, err := importer.ImportCSV(context.Background(), rep, *userID, *objectName, file, options, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+	if len(report.Errors) != 0 {
+		return fmt.Errorf("import: %d invalid rows", len(report.Errors))
+	}
+	return nil
+}
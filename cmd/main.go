@@ -1,30 +1,353 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"rental-server/internal/metrics"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/memory"
 	mongorep "rental-server/internal/repository/mongo"
+	postgresrep "rental-server/internal/repository/postgres"
+	sqliterep "rental-server/internal/repository/sqlite"
 	"rental-server/internal/server"
+	grpcapi "rental-server/internal/server/grpc"
+	"rental-server/internal/tracing"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 )
 
 func main() {
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found")
 	}
+	logger, err := newLogger()
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+	// TRACES_EXPORTER is none, otlp, stdout or file, which appends to
+	// TRACES_FILE, traces.json by default.
+	stopTracing, err := tracing.Start(context.Background(), os.Getenv("TRACES_EXPORTER"), os.Getenv("TRACES_FILE"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer stopTracing(context.Background())
+
+	storage, err := newRepository(os.Getenv("REPOSITORY"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	timeouts, err := repositoryTimeouts()
+	if err != nil {
+		log.Fatal(err)
+	}
+	appMetrics := metrics.New()
+	if counter, ok := storage.(repository.Counter); ok {
+		appMetrics.CountStorage(counter, timeouts.Read)
+	}
+	rep := repository.WithObserver(repository.WithTimeouts(storage, timeouts), appMetrics)
+	cacheOptions, err := repositoryCache()
+	if err != nil {
+		log.Fatal(err)
+	}
+	var cache *repository.CachingRepository
+	if cacheOptions.Size > 0 {
+		cache = repository.NewCachingRepository(rep, cacheOptions)
+		appMetrics.ObserveCache(cache)
+		rep = cache
+	}
+	rep = repository.WithTracing(rep)
+
+	commands := map[string]func(repository.RentObjectRepository, []string) error{
+		"import":  runImport,
+		"backup":  runBackup,
+		"restore": runRestore,
+		// Migrations are not limited by the timeouts of operations.
+		"migrate": func(_ repository.RentObjectRepository, args []string) error {
+			return runMigrate(storage, args)
+		},
+	}
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			err := command(rep, os.Args[2:])
+			if closeErr := closeRepository(context.Background(), storage); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	defaultDatabase := os.Getenv("MONGODB_DATABASE")
-	uri := os.Getenv("MONGODB_URI")
-	rep, err := mongorep.NewMongoDBRepository(uri, defaultDatabase)
+	grpcAddr := os.Getenv("GRPC_ADDR")
+	if grpcAddr == "" {
+		grpcAddr = ":9090"
+	}
+	listener, err := net.Listen("tcp", grpcAddr)
 	if err != nil {
 		log.Fatal(err)
 	}
+	grpcServer := grpcapi.NewServer(rep, grpcapi.Instrument(appMetrics, logger))
+	go func() {
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	router := http.NewServeMux()
+	router.Handle("/metrics", appMetrics.Handler())
+	if cache != nil {
+		router.Handle(invalidateCachePath, invalidateCache(cache))
+	}
+	options := []server.Option{server.WithRequestObserver(appMetrics), server.WithLogger(logger)}
+	if pinger, ok := storage.(repository.Pinger); ok {
+		options = append(options, server.WithPinger(pinger))
+	}
+	router.Handle("/", server.NewRentObjectServer(rep, options...))
 
-	server := server.NewRentObjectServer(rep)
+	httpServer, err := newHTTPServer(router)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go func() {
+		if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+	slog.Info("Serving", "http", httpServer.Addr, "grpc", grpcAddr)
 
-	if err := http.ListenAndServe(":8080", server); err != nil {
+	<-ctx.Done()
+	// A second signal stops the process at once.
+	stop()
+	shutdownTimeout := 25 * time.Second
+	if err := durationEnv("SHUTDOWN_TIMEOUT", &shutdownTimeout); err != nil {
 		log.Fatal(err)
 	}
+	slog.Info("Shutting down", "timeout", shutdownTimeout)
+	shutdown(httpServer, grpcServer, storage, shutdownTimeout)
+}
+
+// shutdown drains requests in progress and closes the storage, giving up on
+// requests that do not finish in time. SHUTDOWN_TIMEOUT, 25s by default, is
+// less than the 30s that Kubernetes waits after SIGTERM by default.
+func shutdown(httpServer *http.Server, grpcServer *grpc.Server, storage repository.RentObjectRepository, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := httpServer.Shutdown(ctx); err != nil {
+			slog.Error("Draining HTTP requests", "error", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			slog.Error("Draining gRPC requests", "error", ctx.Err())
+			grpcServer.Stop()
+		}
+	}()
+	wg.Wait()
+
+	if err := closeRepository(ctx, storage); err != nil {
+		slog.Error("Closing the storage", "error", err)
+	}
+}
+
+// closeRepository closes the storage, which disconnects from a database or
+// takes a final snapshot of memory.
+func closeRepository(ctx context.Context, storage repository.RentObjectRepository) error {
+	switch storage := storage.(type) {
+	case interface{ Close(context.Context) error }:
+		return storage.Close(ctx)
+	case io.Closer:
+		return storage.Close()
+	case interface{ Close() }:
+		storage.Close()
+	}
+	return nil
+}
+
+// newHTTPServer serves on HTTP_ADDR, :8080 by default. HTTP_READ_TIMEOUT,
+// 30s by default, limits reading requests, HTTP_WRITE_TIMEOUT, 60s, writing
+// responses, which includes exports, and HTTP_IDLE_TIMEOUT, 120s, keeps idle
+// connections.
+func newHTTPServer(handler http.Handler) (*http.Server, error) {
+	httpServer := &http.Server{
+		Addr:         os.Getenv("HTTP_ADDR"),
+		Handler:      handler,
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 60 * time.Second,
+		IdleTimeout:  120 * time.Second,
+	}
+	if httpServer.Addr == "" {
+		httpServer.Addr = ":8080"
+	}
+	for name, timeout := range map[string]*time.Duration{
+		"HTTP_READ_TIMEOUT":  &httpServer.ReadTimeout,
+		"HTTP_WRITE_TIMEOUT": &httpServer.WriteTimeout,
+		"HTTP_IDLE_TIMEOUT":  &httpServer.IdleTimeout,
+	} {
+		if err := durationEnv(name, timeout); err != nil {
+			return nil, err
+		}
+	}
+	return httpServer, nil
+}
+
+// durationEnv sets the duration from the environment variable of the name,
+// if it is set.
+func durationEnv(name string, duration *time.Duration) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	var err error
+	if *duration, err = time.ParseDuration(value); err != nil {
+		return fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return nil
+}
+
+// newRepository connects to the storage of the name, MongoDB by default:
+//
+//	mongo     MONGODB_URI of a replica set, which may have one member,
+//	          and MONGODB_DATABASE, and MONGODB_MIGRATE=false to leave
+//	          migrations to the migrate command
+//	postgres  POSTGRES_URL
+//	sqlite    SQLITE_PATH, rental.db by default
+//	memory    MEMORY_DIR to keep a snapshot and write-ahead log, and
+//	          MEMORY_SNAPSHOT_INTERVAL, e.g. 5m; nothing is kept without
+//	          the directory
+func newRepository(name string) (repository.RentObjectRepository, error) {
+	switch name {
+	case "", "mongo":
+		if os.Getenv("MONGODB_MIGRATE") == "false" {
+			return mongorep.ConnectMongoDBRepository(os.Getenv("MONGODB_URI"), os.Getenv("MONGODB_DATABASE"))
+		}
+		return mongorep.NewMongoDBRepository(os.Getenv("MONGODB_URI"), os.Getenv("MONGODB_DATABASE"))
+	case "postgres":
+		return postgresrep.NewPostgresRepository(os.Getenv("POSTGRES_URL"))
+	case "sqlite":
+		path := os.Getenv("SQLITE_PATH")
+		if path == "" {
+			path = "rental.db"
+		}
+		return sqliterep.NewSQLiteRepository(path)
+	case "memory":
+		dir := os.Getenv("MEMORY_DIR")
+		if dir == "" {
+			return memory.NewMemoryObjectRepository(nil), nil
+		}
+		var options memory.Options
+		if interval := os.Getenv("MEMORY_SNAPSHOT_INTERVAL"); interval != "" {
+			var err error
+			if options.SnapshotInterval, err = time.ParseDuration(interval); err != nil {
+				return nil, fmt.Errorf("invalid MEMORY_SNAPSHOT_INTERVAL: %w", err)
+			}
+		}
+		return memory.OpenMemoryObjectRepository(dir, options)
+	default:
+		return nil, fmt.Errorf("unknown REPOSITORY %q, want mongo, postgres, sqlite or memory", name)
+	}
+}
+
+// repositoryTimeouts limits repository operations by REPOSITORY_READ_TIMEOUT
+// and REPOSITORY_WRITE_TIMEOUT, 5s and 10s by default, and
+// REPOSITORY_OPERATION_TIMEOUTS for single operations, e.g.
+// "FindRecords=30s,GetAll=10s". A zero duration disables the limit.
+func repositoryTimeouts() (repository.Timeouts, error) {
+	timeouts := repository.Timeouts{Read: 5 * time.Second, Write: 10 * time.Second}
+	for name, timeout := range map[string]*time.Duration{
+		"REPOSITORY_READ_TIMEOUT":  &timeouts.Read,
+		"REPOSITORY_WRITE_TIMEOUT": &timeouts.Write,
+	} {
+		if value := os.Getenv(name); value != "" {
+			var err error
+			if *timeout, err = time.ParseDuration(value); err != nil {
+				return timeouts, fmt.Errorf("invalid %s: %w", name, err)
+			}
+		}
+	}
+
+	var err error
+	timeouts.Operations, err = repository.ParseOperationTimeouts(os.Getenv("REPOSITORY_OPERATION_TIMEOUTS"))
+	if err != nil {
+		return timeouts, fmt.Errorf("invalid REPOSITORY_OPERATION_TIMEOUTS: %w", err)
+	}
+	return timeouts, nil
+}
+
+// repositoryCache keeps up to CACHE_SIZE objects, 1000 by default, for
+// CACHE_TTL, 1m by default. A zero size disables the cache, and a zero TTL
+// keeps objects until they change. CACHE_PEERS, comma-separated base URLs
+// of the other instances, are told of changes, so that they drop the
+// objects too.
+func repositoryCache() (repository.CacheOptions, error) {
+	options := repository.CacheOptions{Size: 1000, TTL: time.Minute}
+	if value := os.Getenv("CACHE_SIZE"); value != "" {
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 0 {
+			return options, fmt.Errorf("invalid CACHE_SIZE %q", value)
+		}
+		options.Size = size
+	}
+	if value := os.Getenv("CACHE_TTL"); value != "" {
+		var err error
+		if options.TTL, err = time.ParseDuration(value); err != nil {
+			return options, fmt.Errorf("invalid CACHE_TTL: %w", err)
+		}
+	}
+	if value := os.Getenv("CACHE_PEERS"); value != "" {
+		options.OnInvalidate = notifyPeers(strings.Split(value, ","))
+	}
+	return options, nil
+}
+
+// newLogger logs in LOG_FORMAT, text or json, from LOG_LEVEL, e.g. debug or
+// warn, which is info by default. Records of traced requests have the IDs of
+// their trace and span.
+func newLogger() (*slog.Logger, error) {
+	options := &slog.HandlerOptions{}
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid LOG_LEVEL: %w", err)
+		}
+		options.Level = l
+	}
+	switch format := os.Getenv("LOG_FORMAT"); format {
+	case "", "text":
+		return slog.New(tracing.NewLogHandler(slog.NewTextHandler(os.Stderr, options))), nil
+	case "json":
+		return slog.New(tracing.NewLogHandler(slog.NewJSONHandler(os.Stderr, options))), nil
+	default:
+		return nil, fmt.Errorf("unknown LOG_FORMAT %q, want text or json", format)
+	}
 }
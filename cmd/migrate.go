@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"rental-server/internal/repository"
+)
+
+// migrator is a repository with a schema to migrate.
+type migrator interface {
+	Migrate(ctx context.Context) (int, error)
+}
+
+// runMigrate applies the migrations that the storage lacks, which lets them
+// run before servers started with MONGODB_MIGRATE=false:
+//
+//	main migrate
+func runMigrate(rep repository.RentObjectRepository, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	m, ok := rep.(migrator)
+	if !ok {
+		return errors.New("migrate: the repository has no schema to migrate")
+	}
+	version, err := m.Migrate(context.Background())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Schema version %d\n", version)
+	return nil
+}
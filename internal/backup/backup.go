@@ -0,0 +1,238 @@
+// Package backup saves all the data of a user to a versioned JSON document
+// and restores it into any repository.
+package backup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"slices"
+	"time"
+)
+
+// Version of the backup format. Restore reads backups of this version and
+// older.
+const Version = 1
+
+type Mode string
+
+const (
+	// Merge keeps the data of the user and adds the objects, records and
+	// match rules of the backup that are missing. The description and area
+	// of existing objects are taken from the backup.
+	Merge Mode = "merge"
+	// Replace deletes all the data of the user before the backup is
+	// restored.
+	Replace Mode = "replace"
+)
+
+var UnknownModeError = errors.New("Restore mode should be merge or replace")
+
+type UnsupportedVersionError struct {
+	Version int
+}
+
+func (e UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("Unsupported backup version %d, want 1 to %d", e.Version, Version)
+}
+
+type DuplicateObjectError struct {
+	Name string
+}
+
+func (e DuplicateObjectError) Error() string {
+	return fmt.Sprintf("Object %q is repeated in backup", e.Name)
+}
+
+type Backup struct {
+	Version    int                 `json:"version"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UserID     int64               `json:"user_id"`
+	Objects    []domain.RentObject `json:"objects"`
+	MatchRules []domain.MatchRule  `json:"match_rules"`
+}
+
+type Report struct {
+	Mode           Mode `json:"mode"`
+	ObjectsDeleted int  `json:"objects_deleted"`
+	ObjectsAdded   int  `json:"objects_added"`
+	ObjectsUpdated int  `json:"objects_updated"`
+	RecordsAdded   int  `json:"records_added"`
+	RulesAdded     int  `json:"rules_added"`
+}
+
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case "":
+		return Merge, nil
+	case Merge, Replace:
+		return Mode(s), nil
+	default:
+		return "", UnknownModeError
+	}
+}
+
+// Create collects the objects with their records and the match rules of the
+// user.
+func Create(ctx context.Context, rep repository.RentObjectRepository, userID int64) (Backup, error) {
+	backup := Backup{Version: Version, CreatedAt: time.Now().UTC(), UserID: userID}
+
+	objects, err := rep.GetAll(ctx, userID)
+	if err != nil {
+		return backup, err
+	}
+	rules, err := rep.GetMatchRules(ctx, userID)
+	if err != nil {
+		return backup, err
+	}
+
+	backup.Objects = append([]domain.RentObject{}, objects...)
+	backup.MatchRules = append([]domain.MatchRule{}, rules...)
+	return backup, nil
+}
+
+func Write(w io.Writer, backup Backup) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(backup)
+}
+
+// Read decodes a backup and checks that it can be restored.
+func Read(r io.Reader) (Backup, error) {
+	var backup Backup
+	if err := json.NewDecoder(r).Decode(&backup); err != nil {
+		return backup, err
+	}
+	return backup, backup.validate()
+}
+
+func (b Backup) validate() error {
+	if b.Version < 1 || b.Version > Version {
+		return UnsupportedVersionError{b.Version}
+	}
+	names := map[string]bool{}
+	for _, object := range b.Objects {
+		if names[object.Name] {
+			return DuplicateObjectError{object.Name}
+		}
+		names[object.Name] = true
+	}
+	return nil
+}
+
+// Restore writes the backup to the user, who may differ from the user of the
+// backup. The repository has no transactions, so a failed restore leaves a
+// part of the backup restored, and restoring it again in merge mode
+// completes it.
+func Restore(ctx context.Context, rep repository.RentObjectRepository, userID int64, backup Backup, mode Mode) (Report, error) {
+	report := Report{Mode: mode}
+	if err := backup.validate(); err != nil {
+		return report, err
+	}
+	if mode != Merge && mode != Replace {
+		return report, UnknownModeError
+	}
+
+	if mode == Replace {
+		if err := clearUser(ctx, rep, userID, &report); err != nil {
+			return report, err
+		}
+	}
+
+	for _, object := range backup.Objects {
+		if err := restoreObject(ctx, rep, userID, object, &report); err != nil {
+			return report, err
+		}
+	}
+
+	rules, err := rep.GetMatchRules(ctx, userID)
+	if err != nil {
+		return report, err
+	}
+	for _, rule := range backup.MatchRules {
+		if slices.Contains(rules, rule) {
+			continue
+		}
+		if _, err := rep.AddMatchRule(ctx, userID, rule); err != nil {
+			return report, err
+		}
+		rules = append(rules, rule)
+		report.RulesAdded++
+	}
+	return report, nil
+}
+
+func clearUser(ctx context.Context, rep repository.RentObjectRepository, userID int64, report *Report) error {
+	objects, err := rep.GetAll(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, object := range objects {
+		if err := rep.Delete(ctx, userID, object.Name); err != nil {
+			return err
+		}
+		report.ObjectsDeleted++
+	}
+
+	rules, err := rep.GetMatchRules(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for i := len(rules) - 1; i >= 0; i-- {
+		if err := rep.DeleteMatchRule(ctx, userID, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func restoreObject(ctx context.Context, rep repository.RentObjectRepository, userID int64, object domain.RentObject, report *Report) error {
+	existing, err := rep.GetByName(ctx, userID, object.Name)
+	if err == repository.ObjectNotFoundError {
+		if object.Records == nil {
+			object.Records = []domain.Record{}
+		}
+		if err := rep.Add(ctx, userID, object); err != nil {
+			return err
+		}
+		report.ObjectsAdded++
+		report.RecordsAdded += len(object.Records)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if existing.Description != object.Description || existing.Area != object.Area {
+		input := domain.UpdateRentObjectInput{Description: &object.Description, Area: &object.Area}
+		if err := rep.Update(ctx, userID, object.Name, input); err != nil {
+			return err
+		}
+		report.ObjectsUpdated++
+	}
+
+	for _, record := range object.Records {
+		if containsRecord(existing.Records, record) {
+			continue
+		}
+		if _, err := rep.AddRecord(ctx, userID, object.Name, record); err != nil {
+			return err
+		}
+		existing.Records = append(existing.Records, record)
+		report.RecordsAdded++
+	}
+	return nil
+}
+
+func containsRecord(records []domain.Record, record domain.Record) bool {
+	for _, r := range records {
+		if r.Equal(record) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,124 @@
+package backup_test
+
+import (
+	"bytes"
+	"context"
+	"rental-server/internal/backup"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository/memory"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+var userID int64 = 1
+
+var january = domain.Record{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 1000}
+var february = domain.Record{Date: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Rent: 1000, Heat: 100}
+var rule = domain.MatchRule{ObjectName: "Склад", Field: "rent", Counterparty: "7701234567"}
+
+func newRepository() *memory.MemoryObjectRepository {
+	rep := memory.NewMemoryObjectRepository(nil)
+	object := domain.NewRentObject("Склад", "Description", 100)
+	object.AddRecord(january)
+	_ = rep.Add(ctx, userID, object)
+	_ = rep.Add(ctx, userID, domain.NewRentObject("Office", "", 50))
+	_, _ = rep.AddMatchRule(ctx, userID, rule)
+	return rep
+}
+
+func TestBackup(t *testing.T) {
+	t.Run("should restore written backup to another user", func(t *testing.T) {
+		b, err := backup.Create(ctx, newRepository(), userID)
+		assert.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		assert.NoError(t, backup.Write(buf, b))
+		read, err := backup.Read(buf)
+		assert.NoError(t, err)
+
+		rep := memory.NewMemoryObjectRepository(nil)
+		report, err := backup.Restore(ctx, rep, userID+1, read, backup.Merge)
+		assert.NoError(t, err)
+		assert.Equal(t, backup.Report{Mode: backup.Merge, ObjectsAdded: 2, RecordsAdded: 1, RulesAdded: 1}, report)
+
+		objects, _ := rep.GetAll(ctx, userID+1)
+		assert.Equal(t, b.Objects, objects)
+		rules, _ := rep.GetMatchRules(ctx, userID+1)
+		assert.Equal(t, []domain.MatchRule{rule}, rules)
+	})
+
+	t.Run("should merge missing records only", func(t *testing.T) {
+		rep := newRepository()
+		b, _ := backup.Create(ctx, rep, userID)
+		b.Objects[1].Records = []domain.Record{january, february}
+		b.Objects[1].Area = 120
+
+		report, err := backup.Restore(ctx, rep, userID, b, backup.Merge)
+		assert.NoError(t, err)
+		assert.Equal(t, backup.Report{Mode: backup.Merge, ObjectsUpdated: 1, RecordsAdded: 1}, report)
+
+		object, _ := rep.GetByName(ctx, userID, "Склад")
+		assert.Equal(t, []domain.Record{january, february}, object.Records)
+		assert.Equal(t, 120.0, object.Area)
+
+		_, _ = backup.Restore(ctx, rep, userID, b, backup.Merge)
+		rules, _ := rep.GetMatchRules(ctx, userID)
+		assert.Len(t, rules, 1, "restoring twice adds nothing")
+	})
+
+	t.Run("should merge records of the same instant in another location", func(t *testing.T) {
+		rep := newRepository()
+		b, _ := backup.Create(ctx, rep, userID)
+		moscow := january
+		moscow.Date = january.Date.In(time.FixedZone("MSK", 3*60*60))
+		b.Objects[1].Records = []domain.Record{moscow}
+
+		report, err := backup.Restore(ctx, rep, userID, b, backup.Merge)
+		assert.NoError(t, err)
+		assert.Equal(t, backup.Report{Mode: backup.Merge}, report)
+
+		object, _ := rep.GetByName(ctx, userID, "Склад")
+		assert.Equal(t, []domain.Record{january}, object.Records)
+	})
+
+	t.Run("should replace all the data of the user", func(t *testing.T) {
+		rep := newRepository()
+		b := backup.Backup{Version: backup.Version, Objects: []domain.RentObject{domain.NewRentObject("New", "", 1)}}
+
+		report, err := backup.Restore(ctx, rep, userID, b, backup.Replace)
+		assert.NoError(t, err)
+		assert.Equal(t, backup.Report{Mode: backup.Replace, ObjectsDeleted: 2, ObjectsAdded: 1}, report)
+
+		objects, _ := rep.GetAll(ctx, userID)
+		assert.Equal(t, b.Objects, objects)
+		rules, _ := rep.GetMatchRules(ctx, userID)
+		assert.Empty(t, rules)
+	})
+
+	t.Run("should reject invalid backups before restoring", func(t *testing.T) {
+		_, err := backup.Read(strings.NewReader(`{"version": 2, "objects": []}`))
+		assert.Equal(t, backup.UnsupportedVersionError{Version: 2}, err)
+
+		rep := newRepository()
+		b := backup.Backup{Version: backup.Version, Objects: []domain.RentObject{domain.NewRentObject("New", "", 1), domain.NewRentObject("New", "", 2)}}
+		_, err = backup.Restore(ctx, rep, userID, b, backup.Replace)
+		assert.Equal(t, backup.DuplicateObjectError{Name: "New"}, err)
+
+		objects, _ := rep.GetAll(ctx, userID)
+		assert.Len(t, objects, 2)
+	})
+
+	t.Run("should parse mode", func(t *testing.T) {
+		mode, err := backup.ParseMode("")
+		assert.NoError(t, err)
+		assert.Equal(t, backup.Merge, mode)
+
+		_, err = backup.ParseMode("overwrite")
+		assert.Equal(t, backup.UnknownModeError, err)
+	})
+}
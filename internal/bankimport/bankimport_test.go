@@ -0,0 +1,149 @@
+package bankimport_test
+
+import (
+	"rental-server/internal/bankimport"
+	"rental-server/internal/domain"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/text/encoding/charmap"
+)
+
+const statement = `1CClientBankExchange
+ВерсияФормата=1.03
+Кодировка=Windows
+Отправитель=Бухгалтерия предприятия
+ДатаНачала=01.01.2025
+ДатаКонца=31.01.2025
+РасчСчет=40702810900000000001
+СекцияРасчСчет
+ДатаНачала=01.01.2025
+РасчСчет=40702810900000000001
+КонецРасчСчет
+СекцияДокумент=Платежное поручение
+Номер=15
+Дата=09.01.2025
+Сумма=50000.00
+ПлательщикСчет=40702810500000000002
+Плательщик=ИНН 7701234567 ООО "Ромашка"
+ПлательщикИНН=7701234567
+Плательщик1=ООО "Ромашка"
+ПолучательСчет=40702810900000000001
+Получатель1=ИП Иванов И.И.
+ПолучательИНН=500100732259
+ДатаПоступило=10.01.2025
+НазначениеПлатежа=Арендная плата за январь 2025 по договору 7
+КонецДокумента
+СекцияДокумент=Платежное поручение
+Номер=3
+Дата=20.01.2025
+Сумма=1200.50
+ПлательщикСчет=40702810900000000001
+Плательщик1=ИП Иванов И.И.
+ПолучательСчет=40702810100000000003
+Получатель1=ООО "Охрана-Сервис"
+ПолучательИНН=7709876543
+ДатаСписано=20.01.2025
+НазначениеПлатежа=Охрана склада за январь
+КонецДокумента
+СекцияДокумент=Банковский ордер
+Номер=4
+Дата=31.01.2025
+Сумма=300
+ПлательщикСчет=40702810900000000001
+ПолучательСчет=47423810000000000000
+Получатель1=ПАО Банк
+ДатаСписано=31.01.2025
+НазначениеПлатежа=Комиссия за обслуживание
+КонецДокумента
+КонецФайла
+`
+
+func encode(t *testing.T, s string) string {
+	encoded, err := charmap.Windows1251.NewEncoder().String(strings.ReplaceAll(s, "\n", "\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+func TestParseStatement(t *testing.T) {
+	t.Run("should read payments in Windows-1251", func(t *testing.T) {
+		got, err := bankimport.ParseStatement(strings.NewReader(encode(t, statement)))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"40702810900000000001"}, got.Accounts)
+		assert.Equal(t, []bankimport.Payment{
+			{
+				Number: "15", Date: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), Amount: 50000,
+				Direction: domain.IncomingPayment, Counterparty: `ООО "Ромашка"`, CounterpartyINN: "7701234567",
+				Purpose: "Арендная плата за январь 2025 по договору 7",
+			},
+			{
+				Number: "3", Date: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Amount: 1200.5,
+				Direction: domain.OutgoingPayment, Counterparty: `ООО "Охрана-Сервис"`, CounterpartyINN: "7709876543",
+				Purpose: "Охрана склада за январь",
+			},
+			{
+				Number: "4", Date: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), Amount: 300,
+				Direction: domain.OutgoingPayment, Counterparty: "ПАО Банк", Purpose: "Комиссия за обслуживание",
+			},
+		}, got.Payments)
+	})
+
+	t.Run("should read UTF-8", func(t *testing.T) {
+		got, err := bankimport.ParseStatement(strings.NewReader(statement))
+		assert.NoError(t, err)
+		assert.Len(t, got.Payments, 3)
+	})
+
+	t.Run("should reject other files", func(t *testing.T) {
+		_, err := bankimport.ParseStatement(strings.NewReader("date,rent\n"))
+		assert.Equal(t, bankimport.NotStatementError, err)
+	})
+
+	t.Run("should point to invalid document", func(t *testing.T) {
+		_, err := bankimport.ParseStatement(strings.NewReader(strings.Replace(statement, "Сумма=300", "Сумма=", 1)))
+		assert.Equal(t, bankimport.InvalidDocumentError{Line: 38, Message: `Сумма ""`}, err)
+	})
+}
+
+func TestMatch(t *testing.T) {
+	payments := []bankimport.Payment{
+		{Date: time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), Amount: 50000, Direction: domain.IncomingPayment, Counterparty: `ООО "Ромашка"`, CounterpartyINN: "7701234567", Purpose: "Аренда за январь"},
+		{Date: time.Date(2025, 1, 25, 0, 0, 0, 0, time.UTC), Amount: 1000, Direction: domain.IncomingPayment, Counterparty: `ООО "Ромашка"`, CounterpartyINN: "7701234567", Purpose: "Возмещение электроэнергии"},
+		{Date: time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC), Amount: 50000, Direction: domain.IncomingPayment, Counterparty: `ООО "Ромашка"`, CounterpartyINN: "7701234567", Purpose: "Аренда за февраль"},
+		{Date: time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC), Amount: 1200.5, Direction: domain.OutgoingPayment, Counterparty: `ООО "Охрана-Сервис"`, Purpose: "Охрана склада"},
+		{Date: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC), Amount: 300, Direction: domain.OutgoingPayment, Counterparty: "ПАО Банк"},
+	}
+	rules := []domain.MatchRule{
+		{ObjectName: "Склад", Field: "electricity", Counterparty: "7701234567", Purpose: "ЭЛЕКТРОЭНЕРГ"},
+		{ObjectName: "Склад", Field: "rent", Direction: domain.IncomingPayment, Counterparty: "7701234567"},
+		{ObjectName: "Склад", Field: "security", Direction: domain.OutgoingPayment, Counterparty: "охрана"},
+	}
+
+	got := bankimport.Match(payments, rules)
+
+	assert.Equal(t, []bankimport.ProposedRecord{
+		{ObjectName: "Склад", Record: domain.Record{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 50000, Electricity: 1000, Security: 1200.5}},
+		{ObjectName: "Склад", Record: domain.Record{Date: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Rent: 50000}},
+	}, got.Records)
+	assert.Equal(t, []int{1, 0, 1, 2}, []int{got.Matched[0].RuleIndex, got.Matched[1].RuleIndex, got.Matched[2].RuleIndex, got.Matched[3].RuleIndex})
+	assert.Equal(t, []bankimport.Payment{payments[4]}, got.Unmatched)
+}
+
+func TestValidateRule(t *testing.T) {
+	valid := domain.MatchRule{ObjectName: "Склад", Field: "rent", Counterparty: "Ромашка"}
+	assert.NoError(t, bankimport.ValidateRule(valid))
+
+	invalid := []domain.MatchRule{
+		{Field: "rent", Counterparty: "Ромашка"},
+		{ObjectName: "Склад", Field: "income", Counterparty: "Ромашка"},
+		{ObjectName: "Склад", Field: "rent", Direction: "both", Counterparty: "Ромашка"},
+		{ObjectName: "Склад", Field: "rent", Counterparty: " "},
+	}
+	for _, rule := range invalid {
+		assert.Error(t, bankimport.ValidateRule(rule), rule)
+	}
+}
@@ -0,0 +1,145 @@
+package bankimport
+
+import (
+	"errors"
+	"rental-server/internal/domain"
+	"rental-server/internal/importer"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var NoConditionError = errors.New("Match rule needs a counterparty or a purpose")
+
+type InvalidRuleError struct {
+	Message string
+}
+
+func (e InvalidRuleError) Error() string {
+	return "Invalid match rule: " + e.Message
+}
+
+// ValidateRule checks that the rule points to a record field and can match
+// only a part of the payments.
+func ValidateRule(rule domain.MatchRule) error {
+	if rule.ObjectName == "" {
+		return InvalidRuleError{"no object name"}
+	}
+	if _, ok := importer.AmountFields[rule.Field]; !ok {
+		return importer.UnknownFieldError{Field: rule.Field}
+	}
+	switch rule.Direction {
+	case "", domain.IncomingPayment, domain.OutgoingPayment:
+	default:
+		return InvalidRuleError{"direction is neither " + domain.IncomingPayment + " nor " + domain.OutgoingPayment}
+	}
+	if strings.TrimSpace(rule.Counterparty) == "" && strings.TrimSpace(rule.Purpose) == "" {
+		return NoConditionError
+	}
+	return nil
+}
+
+// ProposedRecord is a record that the payments of a month add up to. It is
+// added to the object once the user confirms it.
+type ProposedRecord struct {
+	ObjectName string        `json:"object_name"`
+	Record     domain.Record `json:"record"`
+}
+
+type MatchedPayment struct {
+	Payment
+	RuleIndex  int    `json:"rule_index"`
+	ObjectName string `json:"object_name"`
+	Field      string `json:"field"`
+}
+
+type Proposal struct {
+	Records   []ProposedRecord `json:"records"`
+	Matched   []MatchedPayment `json:"matched"`
+	Unmatched []Payment        `json:"unmatched"`
+}
+
+// Match assigns each payment to the first rule that it meets. Matched
+// payments are summed up into a record per object and month, dated with the
+// first day of the month, in the order the months first appear.
+func Match(payments []Payment, rules []domain.MatchRule) Proposal {
+	proposal := Proposal{Records: []ProposedRecord{}, Matched: []MatchedPayment{}, Unmatched: []Payment{}}
+
+	type key struct {
+		objectName string
+		month      time.Time
+	}
+	records := map[key]int{}
+
+	for _, payment := range payments {
+		ruleIndex := -1
+		for i, rule := range rules {
+			if matches(payment, rule) {
+				ruleIndex = i
+				break
+			}
+		}
+		if ruleIndex == -1 {
+			proposal.Unmatched = append(proposal.Unmatched, payment)
+			continue
+		}
+
+		rule := rules[ruleIndex]
+		proposal.Matched = append(proposal.Matched, MatchedPayment{payment, ruleIndex, rule.ObjectName, rule.Field})
+
+		month := time.Date(payment.Date.Year(), payment.Date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		k := key{rule.ObjectName, month}
+		i, ok := records[k]
+		if !ok {
+			i = len(proposal.Records)
+			records[k] = i
+			proposal.Records = append(proposal.Records, ProposedRecord{rule.ObjectName, domain.Record{Date: month}})
+		}
+		field, ok := importer.AmountFields[rule.Field]
+		if ok {
+			*field(&proposal.Records[i].Record) += payment.Amount
+		}
+	}
+	return proposal
+}
+
+func matches(payment Payment, rule domain.MatchRule) bool {
+	if rule.Direction != "" && rule.Direction != payment.Direction {
+		return false
+	}
+	if strings.TrimSpace(rule.Counterparty) == "" && strings.TrimSpace(rule.Purpose) == "" {
+		return false
+	}
+
+	if counterparty := strings.TrimSpace(rule.Counterparty); counterparty != "" {
+		if isINN(counterparty) {
+			if counterparty != payment.CounterpartyINN {
+				return false
+			}
+		} else if !containsFold(payment.Counterparty, counterparty) {
+			return false
+		}
+	}
+	if purpose := strings.TrimSpace(rule.Purpose); purpose != "" && !containsFold(payment.Purpose, purpose) {
+		return false
+	}
+	return true
+}
+
+// isINN tells an INN, of 10 digits for organizations and of 12 for
+// individuals, from a part of a name.
+func isINN(s string) bool {
+	if len(s) != 10 && len(s) != 12 {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
@@ -0,0 +1,167 @@
+// Package bankimport reads bank statements in the 1CClientBankExchange
+// format and matches their payments to records of rent objects.
+package bankimport
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"rental-server/internal/domain"
+	"rental-server/internal/importer"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+const header = "1CClientBankExchange"
+
+var NotStatementError = errors.New("Not a 1CClientBankExchange file")
+
+type InvalidDocumentError struct {
+	Line    int
+	Message string
+}
+
+func (e InvalidDocumentError) Error() string {
+	return fmt.Sprintf("Invalid document at line %d: %s", e.Line, e.Message)
+}
+
+type Payment struct {
+	Number string     `json:"number"`
+	Date   time.Time  `json:"date"`
+	Amount domain.RUB `json:"amount"`
+	// Direction is domain.IncomingPayment or domain.OutgoingPayment for the
+	// accounts of the statement.
+	Direction       string `json:"direction"`
+	Counterparty    string `json:"counterparty"`
+	CounterpartyINN string `json:"counterparty_inn"`
+	Purpose         string `json:"purpose"`
+}
+
+type Statement struct {
+	// Accounts are the accounts that the statement is given for.
+	Accounts []string  `json:"accounts"`
+	Payments []Payment `json:"payments"`
+}
+
+// ParseStatement reads a statement exported by a bank client. Files are
+// usually in Windows-1251, as the Кодировка field says, and are also read in
+// DOS (CP866) and UTF-8.
+func ParseStatement(r io.Reader) (Statement, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Statement{}, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	if !bytes.HasPrefix(data, []byte(header)) {
+		return Statement{}, NotStatementError
+	}
+
+	text, err := decode(data)
+	if err != nil {
+		return Statement{}, err
+	}
+
+	statement := Statement{Accounts: []string{}, Payments: []Payment{}}
+	var document map[string]string
+	var documentLine int
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(nil, 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		key, value, _ := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+
+		switch {
+		case key == "СекцияДокумент":
+			document = map[string]string{}
+			documentLine = line
+		case key == "КонецДокумента":
+			if document == nil {
+				return statement, InvalidDocumentError{line, "КонецДокумента without СекцияДокумент"}
+			}
+			payment, err := newPayment(document, statement.Accounts)
+			if err != nil {
+				return statement, InvalidDocumentError{documentLine, err.Error()}
+			}
+			statement.Payments = append(statement.Payments, payment)
+			document = nil
+		case document != nil:
+			document[key] = strings.TrimSpace(value)
+		case key == "РасчСчет" && !contains(statement.Accounts, strings.TrimSpace(value)):
+			statement.Accounts = append(statement.Accounts, strings.TrimSpace(value))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return statement, err
+	}
+	if document != nil {
+		return statement, InvalidDocumentError{documentLine, "no КонецДокумента"}
+	}
+	return statement, nil
+}
+
+func decode(data []byte) (string, error) {
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+
+	text, err := charmap.Windows1251.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.Contains(text, []byte("Кодировка=Windows")) {
+		if dos, err := charmap.CodePage866.NewDecoder().Bytes(data); err == nil && bytes.Contains(dos, []byte("Кодировка=DOS")) {
+			text = dos
+		}
+	}
+	return string(text), nil
+}
+
+func newPayment(document map[string]string, accounts []string) (Payment, error) {
+	payment := Payment{
+		Number:  document["Номер"],
+		Purpose: document["НазначениеПлатежа"],
+	}
+
+	amount, err := importer.ParseRUB(document["Сумма"])
+	if err != nil || document["Сумма"] == "" {
+		return payment, fmt.Errorf("Сумма %q", document["Сумма"])
+	}
+	payment.Amount = amount
+
+	incoming := contains(accounts, document["ПолучательСчет"]) ||
+		!contains(accounts, document["ПлательщикСчет"]) && document["ДатаПоступило"] != ""
+	party, date := "Получатель", document["ДатаСписано"]
+	payment.Direction = domain.OutgoingPayment
+	if incoming {
+		party, date = "Плательщик", document["ДатаПоступило"]
+		payment.Direction = domain.IncomingPayment
+	}
+
+	payment.Counterparty = document[party+"1"]
+	if payment.Counterparty == "" {
+		payment.Counterparty = document[party]
+	}
+	payment.CounterpartyINN = document[party+"ИНН"]
+
+	if date == "" {
+		date = document["Дата"]
+	}
+	if payment.Date, err = time.Parse("02.01.2006", date); err != nil {
+		return payment, fmt.Errorf("Дата %q", date)
+	}
+	return payment, nil
+}
+
+func contains(accounts []string, account string) bool {
+	for _, a := range accounts {
+		if account != "" && a == account {
+			return true
+		}
+	}
+	return false
+}
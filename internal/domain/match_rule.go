@@ -0,0 +1,25 @@
+package domain
+
+import "fmt"
+
+var MatchRuleNotFoundError = fmt.Errorf("Match rule not found")
+
+const (
+	IncomingPayment = "in"
+	OutgoingPayment = "out"
+)
+
+// MatchRule assigns bank payments to a record field of an object. A payment
+// matches when it meets every condition that is set.
+type MatchRule struct {
+	ObjectName string `json:"object_name"`
+	// Field is the record field that the amount goes to, named as in the JSON
+	// of Record, e.g. rent or security.
+	Field string `json:"field"`
+	// Direction is IncomingPayment, OutgoingPayment or empty for both.
+	Direction string `json:"direction,omitempty"`
+	// Counterparty is the INN or a part of the name of the counterparty.
+	Counterparty string `json:"counterparty,omitempty"`
+	// Purpose is a part of the purpose of payment.
+	Purpose string `json:"purpose,omitempty"`
+}
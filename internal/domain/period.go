@@ -0,0 +1,34 @@
+package domain
+
+import "time"
+
+// Period is the half-open interval [From, To) of record dates. A nil bound
+// leaves the period open on that side, so the zero Period covers all records.
+type Period struct {
+	From *time.Time `json:"from,omitempty"`
+	To   *time.Time `json:"to,omitempty"`
+}
+
+func (p Period) Contains(date time.Time) bool {
+	if p.From != nil && date.Before(*p.From) {
+		return false
+	}
+	if p.To != nil && !date.Before(*p.To) {
+		return false
+	}
+	return true
+}
+
+// InPeriod returns a copy of the object with only the records of the period,
+// so that its totals cover the period.
+func (r *RentObject) InPeriod(period Period) RentObject {
+	newRentObject := *r
+	newRentObject.Records = make([]Record, 0, len(r.Records))
+
+	for _, record := range r.Records {
+		if period.Contains(record.Date) {
+			newRentObject.Records = append(newRentObject.Records, record)
+		}
+	}
+	return newRentObject
+}
@@ -0,0 +1,39 @@
+package domain_test
+
+import (
+	"rental-server/internal/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeriod(t *testing.T) {
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Zero period should contain every date", func(t *testing.T) {
+		assert.True(t, domain.Period{}.Contains(time.Time{}))
+		assert.True(t, domain.Period{}.Contains(from))
+	})
+
+	t.Run("Period should include start and exclude end", func(t *testing.T) {
+		period := domain.Period{From: &from, To: &to}
+
+		assert.True(t, period.Contains(from))
+		assert.True(t, period.Contains(to.Add(-time.Nanosecond)))
+		assert.False(t, period.Contains(to))
+		assert.False(t, period.Contains(from.Add(-time.Nanosecond)))
+	})
+
+	t.Run("Object totals should cover only records of period", func(t *testing.T) {
+		object := domain.NewRentObject("Name", "Description", 100)
+		object.AddRecord(domain.Record{Date: from, Rent: 100})
+		object.AddRecord(domain.Record{Date: to, Rent: 1000})
+
+		got := object.InPeriod(domain.Period{To: &to})
+
+		assert.Equal(t, domain.RUB(100), got.Income())
+		assert.Equal(t, domain.RUB(1100), object.Income())
+	})
+}
@@ -54,6 +54,22 @@ func (r *Record) Profit() RUB {
 	return r.Income() - r.Expenses()
 }
 
+// Equal compares dates as instants, since repositories may return them in
+// another location than they were added in.
+func (r *Record) Equal(other Record) bool {
+	return r.Date.Equal(other.Date) &&
+		r.Rent == other.Rent &&
+		r.Heat == other.Heat &&
+		r.Exploitation == other.Exploitation &&
+		r.MOP == other.MOP &&
+		r.Renovation == other.Renovation &&
+		r.TBO == other.TBO &&
+		r.Electricity == other.Electricity &&
+		r.EarthRent == other.EarthRent &&
+		r.Other == other.Other &&
+		r.Security == other.Security
+}
+
 func (r *Record) Update(inp UpdateRecordInput) Record {
 	newRecord := *r
 
@@ -92,4 +92,13 @@ func TestRecord(t *testing.T) {
 
 	})
 
+	t.Run("records with the same date in another location should be equal", func(t *testing.T) {
+		other := record
+		other.Date = record.Date.In(time.FixedZone("MSK", 3*60*60))
+		assert.True(t, record.Equal(other))
+
+		other.Security++
+		assert.False(t, record.Equal(other))
+	})
+
 }
@@ -37,12 +37,21 @@ func NewUpdateRentObjectInput(name string, description string, area float64) Upd
 	}
 }
 
+// AddRecord keeps records sorted by date and returns the index of the new
+// record, which comes after the records of the same date.
 func (r *RentObject) AddRecord(record Record) int {
 	r.Records = append(r.Records, record)
-	sort.Slice(r.Records, func(i, j int) bool {
-		return r.Records[i].Date.Compare(r.Records[j].Date) < 0
+	sort.SliceStable(r.Records, func(i, j int) bool {
+		return r.Records[i].Date.Before(r.Records[j].Date)
 	})
-	return len(r.Records) - 1
+
+	index := -1
+	for _, other := range r.Records {
+		if !other.Date.After(record.Date) {
+			index++
+		}
+	}
+	return index
 }
 
 func (r *RentObject) DeleteRecord(recordIndex int) error {
@@ -1,9 +1,11 @@
 package domain
 
 type RentObjectInfo struct {
-	Name        string       `json:"name"`
-	Description string       `json:"description"`
-	Area        float64      `json:"area"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Area        float64 `json:"area"`
+	Period
+	Total       Summary      `json:"total"`
 	RecordsInfo []RecordInfo `json:"records_info"`
 }
 
@@ -17,33 +19,56 @@ type RecordInfo struct {
 	ProfitByArea   RUB `json:"profit_by_area"`
 }
 
+type Summary struct {
+	Income         RUB `json:"income"`
+	Expenses       RUB `json:"expenses"`
+	Profit         RUB `json:"profit"`
+	IncomeByArea   RUB `json:"income_by_area"`
+	ExpensesByArea RUB `json:"expenses_by_area"`
+	ProfitByArea   RUB `json:"profit_by_area"`
+}
+
 func NewRentObjectInfo(object RentObject) RentObjectInfo {
+	return NewRentObjectInfoForPeriod(object, Period{})
+}
+
+func NewRentObjectInfoForPeriod(object RentObject, period Period) RentObjectInfo {
+	object = object.InPeriod(period)
+
 	objectInfo := RentObjectInfo{
 		Name:        object.Name,
 		Description: object.Description,
 		Area:        object.Area,
+		Period:      period,
+		Total: Summary{
+			Income:         object.Income(),
+			Expenses:       object.Expenses(),
+			Profit:         object.Profit(),
+			IncomeByArea:   byArea(object.Income(), object.Area),
+			ExpensesByArea: byArea(object.Expenses(), object.Area),
+			ProfitByArea:   byArea(object.Profit(), object.Area),
+		},
 	}
 
 	for _, record := range object.GetAllRecords() {
-		var incomeByArea, expensesByArea, profitByArea RUB
-
-		if object.Area != 0 {
-			incomeByArea = RUB(float64(record.Income()) / object.Area)
-			expensesByArea = RUB(float64(record.Expenses()) / object.Area)
-			profitByArea = RUB(float64(record.Profit()) / object.Area)
-		}
-
 		recordInfo := RecordInfo{
 			Record:         record,
 			Income:         record.Income(),
 			Expenses:       record.Expenses(),
 			Profit:         record.Profit(),
-			IncomeByArea:   incomeByArea,
-			ExpensesByArea: expensesByArea,
-			ProfitByArea:   profitByArea,
+			IncomeByArea:   byArea(record.Income(), object.Area),
+			ExpensesByArea: byArea(record.Expenses(), object.Area),
+			ProfitByArea:   byArea(record.Profit(), object.Area),
 		}
 		objectInfo.RecordsInfo = append(objectInfo.RecordsInfo, recordInfo)
 	}
 
 	return objectInfo
 }
+
+func byArea(amount RUB, area float64) RUB {
+	if area == 0 {
+		return 0
+	}
+	return RUB(float64(amount) / area)
+}
@@ -3,6 +3,7 @@ package domain_test
 import (
 	"rental-server/internal/domain"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -24,6 +25,7 @@ func TestCreate(t *testing.T) {
 		Name:        object.Name,
 		Description: object.Description,
 		Area:        100,
+		Total:       domain.Summary{Income: 4000, Expenses: 2000, Profit: 2000, IncomeByArea: 40, ExpensesByArea: 20, ProfitByArea: 20},
 		RecordsInfo: []domain.RecordInfo{
 			{Record: domain.Record{Rent: 1000, EarthRent: 500}, Income: 1000, Expenses: 500, Profit: 500, IncomeByArea: 10, ExpensesByArea: 5, ProfitByArea: 5},
 			{Record: domain.Record{Rent: 1000, EarthRent: 500}, Income: 1000, Expenses: 500, Profit: 500, IncomeByArea: 10, ExpensesByArea: 5, ProfitByArea: 5},
@@ -34,3 +36,23 @@ func TestCreate(t *testing.T) {
 
 	assert.Equal(t, want, got)
 }
+
+func TestCreateForPeriod(t *testing.T) {
+	object := domain.RentObject{Area: 10}
+	for month := 1; month <= 12; month++ {
+		object.AddRecord(domain.Record{Date: time.Date(2025, time.Month(month), 1, 0, 0, 0, 0, time.UTC), Rent: 100})
+	}
+	object.AddRecord(domain.Record{Date: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), Rent: 100})
+	object.AddRecord(domain.Record{Date: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 100})
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	period := domain.Period{From: &from, To: &to}
+
+	got := domain.NewRentObjectInfoForPeriod(object, period)
+
+	assert.Equal(t, period, got.Period)
+	assert.Len(t, got.RecordsInfo, 12)
+	assert.Equal(t, domain.Summary{Income: 1200, Profit: 1200, IncomeByArea: 120, ProfitByArea: 120}, got.Total)
+	assert.Len(t, object.Records, 14, "object records should stay untouched")
+}
@@ -23,6 +23,16 @@ func TestRentObject(t *testing.T) {
 		assert.Contains(t, records, record, "Records slice '%v' should contain record '%v'", records, record)
 	})
 
+	t.Run("should return index of added record", func(t *testing.T) {
+		rentObject := domain.RentObject{}
+		rentObject.AddRecord(domain.Record{Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)})
+
+		index := rentObject.AddRecord(domain.Record{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 1})
+
+		assert.Equal(t, 0, index)
+		assert.Equal(t, domain.RUB(1), rentObject.Records[index].Rent)
+	})
+
 	t.Run("should return sorted slice of records", func(t *testing.T) {
 		rentObject := domain.RentObject{}
 
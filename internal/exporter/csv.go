@@ -0,0 +1,52 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"io"
+	"rental-server/internal/domain"
+	"strconv"
+	"time"
+)
+
+// WriteCSV writes the records of objects, each object followed by its totals
+// row. The first column is the object name, and a portfolio of several
+// objects ends with the totals row of all of them.
+func WriteCSV(w io.Writer, infos []domain.RentObjectInfo) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append([]string{"object"}, header()...)); err != nil {
+		return err
+	}
+
+	var totals []row
+	for _, info := range infos {
+		rows := objectRows(info)
+		for _, r := range rows {
+			if err := writer.Write(csvRow(info.Name, r)); err != nil {
+				return err
+			}
+		}
+		totals = append(totals, rows[len(rows)-1])
+	}
+
+	if len(infos) > 1 {
+		if err := writer.Write(csvRow(TotalLabel, portfolioTotal(infos, totals))); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func csvRow(name string, r row) []string {
+	date := TotalLabel
+	if r.date != nil {
+		date = r.date.Format(time.DateOnly)
+	}
+
+	values := []string{name, date}
+	for _, amount := range r.amounts {
+		values = append(values, strconv.FormatFloat(float64(amount), 'f', -1, 64))
+	}
+	return values
+}
@@ -0,0 +1,161 @@
+package exporter_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"rental-server/internal/domain"
+	"rental-server/internal/exporter"
+	"rental-server/internal/importer"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+func newInfos() []domain.RentObjectInfo {
+	first := domain.NewRentObject("Склад: корпус [1]", "", 100)
+	first.AddRecord(domain.Record{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 1000, Heat: 100})
+	first.AddRecord(domain.Record{Date: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Rent: 1000.5, Security: 200})
+
+	second := domain.NewRentObject("Office", "", 50)
+	second.AddRecord(domain.Record{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 500})
+
+	return []domain.RentObjectInfo{domain.NewRentObjectInfo(first), domain.NewRentObjectInfo(second)}
+}
+
+func TestWriteCSV(t *testing.T) {
+	t.Run("should write records with totals", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, exporter.WriteCSV(buf, newInfos()[:1]))
+
+		rows, err := csv.NewReader(buf).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, [][]string{
+			{"object", "date", "rent", "heat", "exploitation", "mop", "renovation", "tbo", "electricity", "earth_rent", "other", "security",
+				"income", "expenses", "profit", "income_by_area", "expenses_by_area", "profit_by_area"},
+			{"Склад: корпус [1]", "2025-01-01", "1000", "100", "0", "0", "0", "0", "0", "0", "0", "0", "1000", "100", "900", "10", "1", "9"},
+			{"Склад: корпус [1]", "2025-02-01", "1000.5", "0", "0", "0", "0", "0", "0", "0", "0", "200", "1000.5", "200", "800.5", "10.005", "2", "8.005"},
+			{"Склад: корпус [1]", "total", "2000.5", "100", "0", "0", "0", "0", "0", "0", "0", "200", "2000.5", "300", "1700.5", "20.005", "3", "17.005"},
+		}, rows)
+	})
+
+	t.Run("should end portfolio with its totals", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, exporter.WriteCSV(buf, newInfos()))
+
+		rows, _ := csv.NewReader(buf).ReadAll()
+		assert.Len(t, rows, 7)
+		assert.Equal(t, []string{"total", "total", "2500.5", "100", "0", "0", "0", "0", "0", "0", "0", "200", "2500.5", "300", "2200.5", "16.67", "2", "14.67"}, rows[6])
+	})
+
+	t.Run("should be imported back", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		infos := newInfos()[:1]
+		_ = exporter.WriteCSV(buf, infos)
+
+		records, rowErrors, err := importer.ParseCSV(buf, importer.Options{})
+		assert.NoError(t, err)
+		assert.Empty(t, rowErrors, "the totals row is skipped")
+		assert.Equal(t, []domain.Record{infos[0].RecordsInfo[0].Record, infos[0].RecordsInfo[1].Record}, records)
+	})
+}
+
+func TestWriteXLSX(t *testing.T) {
+	t.Run("should write a sheet per object after the portfolio", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, exporter.WriteXLSX(buf, newInfos()))
+
+		f, err := excelize.OpenReader(buf)
+		if !assert.NoError(t, err) {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		assert.Equal(t, []string{exporter.PortfolioSheet, "Склад_ корпус _1_", "Office"}, f.GetSheetList())
+
+		portfolio, _ := f.GetRows(exporter.PortfolioSheet, excelize.Options{RawCellValue: true})
+		assert.Equal(t, []string{"total", "150", "2500.5", "300", "2200.5", "16.67", "2", "14.67"}, portfolio[3])
+
+		rows, _ := f.GetRows("Office", excelize.Options{RawCellValue: true})
+		assert.Len(t, rows, 3)
+		assert.Equal(t, "total", rows[2][0])
+		assert.Equal(t, "500", rows[2][1])
+
+		date, _ := f.GetCellValue("Office", "A2")
+		assert.NotEmpty(t, date)
+	})
+
+	t.Run("should name sheets uniquely", func(t *testing.T) {
+		object := domain.NewRentObject("Sheet1", "", 1)
+		buf := &bytes.Buffer{}
+		assert.NoError(t, exporter.WriteXLSX(buf, []domain.RentObjectInfo{domain.NewRentObjectInfo(object)}))
+
+		f, _ := excelize.OpenReader(buf)
+		defer f.Close()
+		assert.Equal(t, []string{"Sheet1 (2)"}, f.GetSheetList())
+	})
+}
+
+func TestWriteLedger(t *testing.T) {
+	t.Run("should write a transaction for each record", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, exporter.WriteLedger(buf, newInfos(), exporter.DefaultAccounts()))
+
+		assert.Equal(t, `2025-01-01 * Office
+    Income:Rent:Office                       -500.00 RUB
+    Assets:Bank                               500.00 RUB
+
+2025-01-01 * Склад: корпус [1]
+    Income:Rent:Склад-корпус-1              -1000.00 RUB
+    Expenses:Utilities:Heat:Склад-корпус-1    100.00 RUB
+    Assets:Bank                               900.00 RUB
+
+2025-02-01 * Склад: корпус [1]
+    Income:Rent:Склад-корпус-1              -1000.50 RUB
+    Expenses:Security:Склад-корпус-1          200.00 RUB
+    Assets:Bank                               800.50 RUB
+`, buf.String())
+	})
+
+	t.Run("should use configured accounts", func(t *testing.T) {
+		accounts, err := exporter.ParseAccounts([]string{"rent=Income:Аренда:<object>", "balance=Assets:Bank:<object>"})
+		assert.NoError(t, err)
+
+		buf := &bytes.Buffer{}
+		assert.NoError(t, exporter.WriteLedger(buf, newInfos()[1:], accounts))
+		assert.Contains(t, buf.String(), "    Income:Аренда:Office  -500.00 RUB\n    Assets:Bank:Office     500.00 RUB\n")
+	})
+
+	t.Run("should reject unknown fields", func(t *testing.T) {
+		_, err := exporter.ParseAccounts([]string{"income=Income:Rent"})
+		assert.Equal(t, exporter.UnknownAccountError{Key: "income"}, err)
+
+		_, err = exporter.ParseAccounts([]string{"rent"})
+		assert.Error(t, err)
+	})
+}
+
+func TestWriteBeancount(t *testing.T) {
+	t.Run("should open accounts before transactions", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, exporter.WriteBeancount(buf, newInfos()[1:], exporter.DefaultAccounts()))
+
+		assert.Equal(t, `option "operating_currency" "RUB"
+
+2025-01-01 open Assets:Bank RUB
+2025-01-01 open Income:Rent:Office RUB
+
+2025-01-01 * "Office"
+  Income:Rent:Office  -500.00 RUB
+  Assets:Bank          500.00 RUB
+`, buf.String())
+	})
+
+	t.Run("should be deterministic", func(t *testing.T) {
+		infos := newInfos()
+		first, second := &bytes.Buffer{}, &bytes.Buffer{}
+		_ = exporter.WriteBeancount(first, infos, exporter.DefaultAccounts())
+		_ = exporter.WriteBeancount(second, []domain.RentObjectInfo{infos[1], infos[0]}, exporter.DefaultAccounts())
+		assert.Equal(t, first.String(), second.String())
+	})
+}
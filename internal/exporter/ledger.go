@@ -0,0 +1,254 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"rental-server/internal/domain"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ObjectPlaceholder in an account name is replaced with the object name.
+const ObjectPlaceholder = "<object>"
+
+// BalanceAccount is the key of Accounts for the account that takes the
+// profit of each record, e.g. a bank account.
+const BalanceAccount = "balance"
+
+const commodity = "RUB"
+
+// Accounts maps record fields, named as in the JSON of domain.Record, and
+// BalanceAccount to account names.
+type Accounts map[string]string
+
+// DefaultAccounts keeps the income and expenses of each object in its own
+// subaccounts, and the profit of all objects in one bank account.
+func DefaultAccounts() Accounts {
+	return Accounts{
+		"rent":         "Income:Rent:" + ObjectPlaceholder,
+		"heat":         "Expenses:Utilities:Heat:" + ObjectPlaceholder,
+		"exploitation": "Expenses:Maintenance:Exploitation:" + ObjectPlaceholder,
+		"mop":          "Expenses:Maintenance:CommonAreas:" + ObjectPlaceholder,
+		"renovation":   "Expenses:Renovation:" + ObjectPlaceholder,
+		"tbo":          "Expenses:Utilities:Waste:" + ObjectPlaceholder,
+		"electricity":  "Expenses:Utilities:Electricity:" + ObjectPlaceholder,
+		"earth_rent":   "Expenses:LandRent:" + ObjectPlaceholder,
+		"other":        "Expenses:Other:" + ObjectPlaceholder,
+		"security":     "Expenses:Security:" + ObjectPlaceholder,
+		BalanceAccount: "Assets:Bank",
+	}
+}
+
+type UnknownAccountError struct {
+	Key string
+}
+
+func (e UnknownAccountError) Error() string {
+	return fmt.Sprintf("Unknown record field %q for account", e.Key)
+}
+
+// ParseAccounts overrides the default accounts with "field=Account" pairs,
+// as they come from query parameters.
+func ParseAccounts(pairs []string) (Accounts, error) {
+	accounts := DefaultAccounts()
+	for _, pair := range pairs {
+		key, account, ok := strings.Cut(pair, "=")
+		key, account = strings.TrimSpace(key), strings.TrimSpace(account)
+		if !ok || account == "" || strings.ContainsFunc(account, unicode.IsSpace) {
+			return nil, fmt.Errorf("Invalid account %q, want field=Account", pair)
+		}
+		if _, ok := accounts[key]; !ok {
+			return nil, UnknownAccountError{key}
+		}
+		accounts[key] = account
+	}
+	return accounts, nil
+}
+
+func (a Accounts) name(key, objectName string) string {
+	account, ok := a[key]
+	if !ok {
+		account = DefaultAccounts()[key]
+	}
+	return strings.ReplaceAll(account, ObjectPlaceholder, accountComponent(objectName))
+}
+
+// accountComponent makes a valid account component of an object name for
+// both ledger and beancount: letters, digits and dashes, starting with a
+// capital letter or a digit.
+func accountComponent(name string) string {
+	component := strings.Join(strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), "-")
+	if component == "" {
+		return "Object"
+	}
+	first, size := utf8.DecodeRuneInString(component)
+	return string(unicode.ToUpper(first)) + component[size:]
+}
+
+// postedFields are the record fields that are posted, named as in the JSON
+// of domain.Record, in the order of their postings.
+var postedFields = []struct {
+	name   string
+	amount func(r *domain.Record) domain.RUB
+}{
+	{"rent", func(r *domain.Record) domain.RUB { return r.Rent }},
+	{"heat", func(r *domain.Record) domain.RUB { return r.Heat }},
+	{"exploitation", func(r *domain.Record) domain.RUB { return r.Exploitation }},
+	{"mop", func(r *domain.Record) domain.RUB { return r.MOP }},
+	{"renovation", func(r *domain.Record) domain.RUB { return r.Renovation }},
+	{"tbo", func(r *domain.Record) domain.RUB { return r.TBO }},
+	{"electricity", func(r *domain.Record) domain.RUB { return r.Electricity }},
+	{"earth_rent", func(r *domain.Record) domain.RUB { return r.EarthRent }},
+	{"other", func(r *domain.Record) domain.RUB { return r.Other }},
+	{"security", func(r *domain.Record) domain.RUB { return r.Security }},
+}
+
+type posting struct {
+	account string
+	// cents are kept whole, so that the postings balance exactly.
+	cents int64
+}
+
+type transaction struct {
+	date       time.Time
+	objectName string
+	postings   []posting
+}
+
+// transactions returns a transaction for each record of the objects, in the
+// order of object names and record dates, so that exports diff cleanly.
+// Income is credited and expenses are debited, and the profit goes to the
+// balance account.
+func transactions(infos []domain.RentObjectInfo, accounts Accounts) []transaction {
+	infos = append([]domain.RentObjectInfo(nil), infos...)
+	sort.SliceStable(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+
+	var result []transaction
+	for _, info := range infos {
+		records := append([]domain.RecordInfo(nil), info.RecordsInfo...)
+		sort.SliceStable(records, func(i, j int) bool { return records[i].Date.Before(records[j].Date) })
+
+		for i := range records {
+			t := transaction{date: records[i].Date, objectName: info.Name}
+			var profit int64
+			for _, field := range postedFields {
+				cents := toCents(field.amount(&records[i].Record))
+				if cents == 0 {
+					continue
+				}
+				// Rent is the income of records, as in domain.Record.Income.
+				if field.name == "rent" {
+					cents = -cents
+				}
+				profit -= cents
+				t.postings = append(t.postings, posting{accounts.name(field.name, info.Name), cents})
+			}
+			if len(t.postings) == 0 {
+				continue
+			}
+			if profit != 0 {
+				t.postings = append(t.postings, posting{accounts.name(BalanceAccount, info.Name), profit})
+			}
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+func toCents(amount domain.RUB) int64 {
+	return int64(math.Round(float64(amount) * 100))
+}
+
+func formatCents(cents int64) string {
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	return fmt.Sprintf("%s%d.%02d %s", sign, cents/100, cents%100, commodity)
+}
+
+// columnWidths are the widths of the longest account and amount, which
+// align the amounts by their decimal points.
+func columnWidths(transactions []transaction) (accountWidth, amountWidth int) {
+	for _, t := range transactions {
+		for _, p := range t.postings {
+			accountWidth = max(accountWidth, utf8.RuneCountInString(p.account))
+			amountWidth = max(amountWidth, len(formatCents(p.cents)))
+		}
+	}
+	return accountWidth, amountWidth
+}
+
+func writePostings(w *bufio.Writer, postings []posting, indent string, accountWidth, amountWidth int) {
+	for _, p := range postings {
+		amount := formatCents(p.cents)
+		padding := strings.Repeat(" ", accountWidth-utf8.RuneCountInString(p.account)+amountWidth-len(amount))
+		fmt.Fprintf(w, "%s%s  %s%s\n", indent, p.account, padding, amount)
+	}
+}
+
+// WriteLedger writes the records of objects as ledger and hledger journal
+// transactions, one for each record with an account for each field.
+func WriteLedger(w io.Writer, infos []domain.RentObjectInfo, accounts Accounts) error {
+	transactions := transactions(infos, accounts)
+	accountWidth, amountWidth := columnWidths(transactions)
+
+	writer := bufio.NewWriter(w)
+	for i, t := range transactions {
+		if i != 0 {
+			writer.WriteString("\n")
+		}
+		fmt.Fprintf(writer, "%s * %s\n", t.date.Format(time.DateOnly), strings.Join(strings.Fields(t.objectName), " "))
+		writePostings(writer, t.postings, "    ", accountWidth, amountWidth)
+	}
+	return writer.Flush()
+}
+
+// WriteBeancount writes the records of objects as beancount transactions,
+// after the open directives of the accounts dated with their first use.
+func WriteBeancount(w io.Writer, infos []domain.RentObjectInfo, accounts Accounts) error {
+	transactions := transactions(infos, accounts)
+	accountWidth, amountWidth := columnWidths(transactions)
+
+	opened := map[string]time.Time{}
+	for _, t := range transactions {
+		for _, p := range t.postings {
+			if date, ok := opened[p.account]; !ok || t.date.Before(date) {
+				opened[p.account] = t.date
+			}
+		}
+	}
+	names := make([]string, 0, len(opened))
+	for account := range opened {
+		names = append(names, account)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if !opened[names[i]].Equal(opened[names[j]]) {
+			return opened[names[i]].Before(opened[names[j]])
+		}
+		return names[i] < names[j]
+	})
+
+	writer := bufio.NewWriter(w)
+	fmt.Fprintf(writer, "option \"operating_currency\" \"%s\"\n\n", commodity)
+	for _, account := range names {
+		fmt.Fprintf(writer, "%s open %s %s\n", opened[account].Format(time.DateOnly), account, commodity)
+	}
+	for _, t := range transactions {
+		fmt.Fprintf(writer, "\n%s * %s\n", t.date.Format(time.DateOnly), beancountString(t.objectName))
+		writePostings(writer, t.postings, "  ", accountWidth, amountWidth)
+	}
+	return writer.Flush()
+}
+
+func beancountString(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,108 @@
+// Package exporter writes rent objects with their computed records to files
+// for spreadsheets and accounting software.
+package exporter
+
+import (
+	"rental-server/internal/domain"
+	"time"
+)
+
+// TotalLabel marks the totals rows in place of the date and, for the whole
+// portfolio, in place of the object name.
+const TotalLabel = "total"
+
+type column struct {
+	name   string
+	amount func(r *domain.RecordInfo) domain.RUB
+}
+
+// columns are named as the JSON fields of domain.RecordInfo, so that CSV
+// exports can be imported back.
+var columns = []column{
+	{"rent", func(r *domain.RecordInfo) domain.RUB { return r.Rent }},
+	{"heat", func(r *domain.RecordInfo) domain.RUB { return r.Heat }},
+	{"exploitation", func(r *domain.RecordInfo) domain.RUB { return r.Exploitation }},
+	{"mop", func(r *domain.RecordInfo) domain.RUB { return r.MOP }},
+	{"renovation", func(r *domain.RecordInfo) domain.RUB { return r.Renovation }},
+	{"tbo", func(r *domain.RecordInfo) domain.RUB { return r.TBO }},
+	{"electricity", func(r *domain.RecordInfo) domain.RUB { return r.Electricity }},
+	{"earth_rent", func(r *domain.RecordInfo) domain.RUB { return r.EarthRent }},
+	{"other", func(r *domain.RecordInfo) domain.RUB { return r.Other }},
+	{"security", func(r *domain.RecordInfo) domain.RUB { return r.Security }},
+	{"income", func(r *domain.RecordInfo) domain.RUB { return r.Income }},
+	{"expenses", func(r *domain.RecordInfo) domain.RUB { return r.Expenses }},
+	{"profit", func(r *domain.RecordInfo) domain.RUB { return r.Profit }},
+	{"income_by_area", func(r *domain.RecordInfo) domain.RUB { return r.IncomeByArea }},
+	{"expenses_by_area", func(r *domain.RecordInfo) domain.RUB { return r.ExpensesByArea }},
+	{"profit_by_area", func(r *domain.RecordInfo) domain.RUB { return r.ProfitByArea }},
+}
+
+// byAreaColumns are the last columns, which are not summed up in totals.
+const byAreaColumns = 3
+
+type row struct {
+	// date is nil for totals rows.
+	date    *time.Time
+	amounts []domain.RUB
+}
+
+func header() []string {
+	names := []string{"date"}
+	for _, column := range columns {
+		names = append(names, column.name)
+	}
+	return names
+}
+
+// objectRows returns a row for each record of the object and the totals row.
+func objectRows(info domain.RentObjectInfo) []row {
+	var rows []row
+	for i := range info.RecordsInfo {
+		recordInfo := &info.RecordsInfo[i]
+		r := row{date: &recordInfo.Date}
+		for _, column := range columns {
+			r.amounts = append(r.amounts, column.amount(recordInfo))
+		}
+		rows = append(rows, r)
+	}
+
+	total := sumRows(rows)
+	summed := len(columns) - byAreaColumns
+	total.amounts[summed] = info.Total.IncomeByArea
+	total.amounts[summed+1] = info.Total.ExpensesByArea
+	total.amounts[summed+2] = info.Total.ProfitByArea
+	return append(rows, total)
+}
+
+// portfolioTotal sums up the totals rows of objects, dividing by the area of
+// all the objects for the by-area columns.
+func portfolioTotal(infos []domain.RentObjectInfo, totals []row) row {
+	total := sumRows(totals)
+
+	var area float64
+	for _, info := range infos {
+		area += info.Area
+	}
+	summed := len(columns) - byAreaColumns
+	for i := 0; i < byAreaColumns; i++ {
+		total.amounts[summed+i] = byArea(total.amounts[summed-byAreaColumns+i], area)
+	}
+	return total
+}
+
+func sumRows(rows []row) row {
+	total := row{amounts: make([]domain.RUB, len(columns))}
+	for _, r := range rows {
+		for i := 0; i < len(columns)-byAreaColumns; i++ {
+			total.amounts[i] += r.amounts[i]
+		}
+	}
+	return total
+}
+
+func byArea(amount domain.RUB, area float64) domain.RUB {
+	if area == 0 {
+		return 0
+	}
+	return domain.RUB(float64(amount) / area)
+}
@@ -0,0 +1,206 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"rental-server/internal/domain"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// PortfolioSheet is the first sheet of a portfolio export with the totals of
+// every object.
+const PortfolioSheet = "Portfolio"
+
+var portfolioHeader = []string{"object", "area", "income", "expenses", "profit", "income_by_area", "expenses_by_area", "profit_by_area"}
+
+type xlsxStyles struct {
+	header, date, amount, total int
+}
+
+// WriteXLSX writes a workbook with a sheet of records for each object, which
+// ends with the totals row. A portfolio of several objects gets the
+// PortfolioSheet first.
+func WriteXLSX(w io.Writer, infos []domain.RentObjectInfo) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	styles, err := newXLSXStyles(f)
+	if err != nil {
+		return err
+	}
+
+	sheets := sheetNames(infos)
+	if len(infos) > 1 {
+		if err := writePortfolioSheet(f, styles, infos); err != nil {
+			return err
+		}
+	}
+	for i, info := range infos {
+		if err := writeObjectSheet(f, styles, sheets[i], info); err != nil {
+			return err
+		}
+	}
+
+	if len(infos) != 0 {
+		if err := f.DeleteSheet(defaultSheet); err != nil {
+			return err
+		}
+		f.SetActiveSheet(0)
+	}
+
+	_, err = f.WriteTo(w)
+	return err
+}
+
+func newXLSXStyles(f *excelize.File) (xlsxStyles, error) {
+	var styles xlsxStyles
+	var err error
+	if styles.header, err = f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		return styles, err
+	}
+	// Built-in formats: 14 is a short date and 4 is #,##0.00.
+	if styles.date, err = f.NewStyle(&excelize.Style{NumFmt: 14}); err != nil {
+		return styles, err
+	}
+	if styles.amount, err = f.NewStyle(&excelize.Style{NumFmt: 4}); err != nil {
+		return styles, err
+	}
+	if styles.total, err = f.NewStyle(&excelize.Style{NumFmt: 4, Font: &excelize.Font{Bold: true}}); err != nil {
+		return styles, err
+	}
+	return styles, nil
+}
+
+func writeObjectSheet(f *excelize.File, styles xlsxStyles, sheet string, info domain.RentObjectInfo) error {
+	if _, err := f.NewSheet(sheet); err != nil {
+		return err
+	}
+	if err := writeXLSXRow(f, sheet, 1, styles.header, header()); err != nil {
+		return err
+	}
+
+	rows := objectRows(info)
+	for i, r := range rows {
+		values := make([]any, 0, len(r.amounts)+1)
+		if r.date != nil {
+			values = append(values, *r.date)
+		} else {
+			values = append(values, TotalLabel)
+		}
+		for _, amount := range r.amounts {
+			values = append(values, float64(amount))
+		}
+
+		style := styles.amount
+		if r.date == nil {
+			style = styles.total
+		}
+		if err := writeXLSXRow(f, sheet, i+2, style, values); err != nil {
+			return err
+		}
+		if r.date != nil {
+			if err := f.SetCellStyle(sheet, cell(1, i+2), cell(1, i+2), styles.date); err != nil {
+				return err
+			}
+		}
+	}
+
+	return f.SetColWidth(sheet, "A", columnName(len(columns)+1), 14)
+}
+
+func writePortfolioSheet(f *excelize.File, styles xlsxStyles, infos []domain.RentObjectInfo) error {
+	if _, err := f.NewSheet(PortfolioSheet); err != nil {
+		return err
+	}
+	if err := writeXLSXRow(f, PortfolioSheet, 1, styles.header, portfolioHeader); err != nil {
+		return err
+	}
+
+	var totals []row
+	var area float64
+	for i, info := range infos {
+		rows := objectRows(info)
+		totals = append(totals, rows[len(rows)-1])
+		area += info.Area
+
+		values := append([]any{info.Name, info.Area}, summaryValues(rows[len(rows)-1])...)
+		if err := writeXLSXRow(f, PortfolioSheet, i+2, styles.amount, values); err != nil {
+			return err
+		}
+	}
+
+	values := append([]any{TotalLabel, area}, summaryValues(portfolioTotal(infos, totals))...)
+	if err := writeXLSXRow(f, PortfolioSheet, len(infos)+2, styles.total, values); err != nil {
+		return err
+	}
+
+	return f.SetColWidth(PortfolioSheet, "A", columnName(len(portfolioHeader)), 16)
+}
+
+// summaryValues returns the income, expenses and profit columns of a totals
+// row followed by the by-area ones.
+func summaryValues(total row) []any {
+	var values []any
+	for _, amount := range total.amounts[len(columns)-2*byAreaColumns:] {
+		values = append(values, float64(amount))
+	}
+	return values
+}
+
+func writeXLSXRow[T any](f *excelize.File, sheet string, row int, style int, values []T) error {
+	if err := f.SetSheetRow(sheet, cell(1, row), &values); err != nil {
+		return err
+	}
+	return f.SetCellStyle(sheet, cell(1, row), cell(len(values), row), style)
+}
+
+func cell(col, row int) string {
+	name, _ := excelize.CoordinatesToCellName(col, row)
+	return name
+}
+
+func columnName(col int) string {
+	name, _ := excelize.ColumnNumberToName(col)
+	return name
+}
+
+// defaultSheet is created with the workbook and deleted once the other
+// sheets are added.
+const defaultSheet = "Sheet1"
+
+// sheetNames makes a valid and unique sheet name of each object name.
+func sheetNames(infos []domain.RentObjectInfo) []string {
+	used := map[string]bool{strings.ToLower(PortfolioSheet): true, strings.ToLower(defaultSheet): true}
+	var names []string
+	for _, info := range infos {
+		name := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`:\/?*[]`, r) {
+				return '_'
+			}
+			return r
+		}, info.Name)
+		name = strings.Trim(truncate(name, excelize.MaxSheetNameLength), "'")
+		if name == "" {
+			name = "Object"
+		}
+
+		unique := name
+		for i := 2; used[strings.ToLower(unique)]; i++ {
+			suffix := fmt.Sprintf(" (%d)", i)
+			unique = truncate(name, excelize.MaxSheetNameLength-len(suffix)) + suffix
+		}
+		used[strings.ToLower(unique)] = true
+		names = append(names, unique)
+	}
+	return names
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
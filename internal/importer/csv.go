@@ -0,0 +1,257 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"rental-server/internal/domain"
+	"rental-server/internal/exporter"
+	"sort"
+	"strings"
+)
+
+var NoDateColumnError = errors.New("No column is mapped to date")
+
+// DateField is the field that the date column is mapped to.
+const DateField = "date"
+
+// AmountFields are the record fields that columns can be mapped to, named as
+// in the JSON of domain.Record.
+var AmountFields = map[string]func(r *domain.Record) *domain.RUB{
+	"rent":         func(r *domain.Record) *domain.RUB { return &r.Rent },
+	"heat":         func(r *domain.Record) *domain.RUB { return &r.Heat },
+	"exploitation": func(r *domain.Record) *domain.RUB { return &r.Exploitation },
+	"mop":          func(r *domain.Record) *domain.RUB { return &r.MOP },
+	"renovation":   func(r *domain.Record) *domain.RUB { return &r.Renovation },
+	"tbo":          func(r *domain.Record) *domain.RUB { return &r.TBO },
+	"electricity":  func(r *domain.Record) *domain.RUB { return &r.Electricity },
+	"earth_rent":   func(r *domain.Record) *domain.RUB { return &r.EarthRent },
+	"other":        func(r *domain.Record) *domain.RUB { return &r.Other },
+	"security":     func(r *domain.Record) *domain.RUB { return &r.Security },
+}
+
+// Mapping maps CSV column headers to record fields. Several columns may be
+// mapped to the same amount field, e.g. spreadsheet categories that the
+// record keeps together, and their values are summed up.
+type Mapping map[string]string
+
+type UnknownFieldError struct {
+	Field string
+}
+
+func (e UnknownFieldError) Error() string {
+	return fmt.Sprintf("Unknown record field %q", e.Field)
+}
+
+type MissingColumnError struct {
+	Column string
+}
+
+func (e MissingColumnError) Error() string {
+	return fmt.Sprintf("No column %q in CSV header", e.Column)
+}
+
+// ParseMapping parses "field=Column" pairs, as they come from query
+// parameters or command line flags.
+func ParseMapping(pairs []string) (Mapping, error) {
+	mapping := Mapping{}
+	for _, pair := range pairs {
+		field, column, ok := strings.Cut(pair, "=")
+		if !ok || column == "" {
+			return nil, fmt.Errorf("Invalid mapping %q, want field=Column", pair)
+		}
+		mapping[column] = strings.TrimSpace(field)
+	}
+	return mapping, nil
+}
+
+func (m Mapping) validate() error {
+	hasDate := false
+	for _, field := range m {
+		if field == DateField {
+			hasDate = true
+			continue
+		}
+		if _, ok := AmountFields[field]; !ok {
+			return UnknownFieldError{field}
+		}
+	}
+	if !hasDate {
+		return NoDateColumnError
+	}
+	return nil
+}
+
+type Options struct {
+	// Mapping of the columns. Without it the columns named as record fields
+	// are imported and the others are ignored.
+	Mapping Mapping
+	// Comma separates the fields. It is guessed from the header when zero,
+	// since Russian spreadsheets are exported with semicolons.
+	Comma rune
+	// DateLayout is the time.Parse layout of dates. ParseDate guesses it when
+	// empty.
+	DateLayout string
+}
+
+type RowError struct {
+	// Line of the row in the file, the header being line 1.
+	Line    int    `json:"line"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// ParseCSV reads records from CSV with a header row. Rows that fail
+// validation are reported as row errors and left out of the records, while
+// an error is returned only when the file as a whole can't be imported.
+// Totals rows of exporter.WriteCSV are skipped, so that exports can be
+// imported back.
+func ParseCSV(r io.Reader, options Options) ([]domain.Record, []RowError, error) {
+	reader := bufio.NewReader(r)
+	comma := options.Comma
+	if comma == 0 {
+		comma = guessComma(reader)
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.Comma = comma
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	mapping := options.Mapping
+	if mapping == nil {
+		mapping = defaultMapping(header)
+	}
+	if err := mapping.validate(); err != nil {
+		return nil, nil, err
+	}
+
+	columns := map[string]int{}
+	for i, column := range header {
+		columns[strings.TrimSpace(column)] = i
+	}
+	var mapped []string
+	var dates []int
+	for column, field := range mapping {
+		if _, ok := columns[column]; !ok {
+			return nil, nil, MissingColumnError{column}
+		}
+		mapped = append(mapped, column)
+		if field == DateField {
+			dates = append(dates, columns[column])
+		}
+	}
+	sort.Slice(mapped, func(i, j int) bool { return columns[mapped[i]] < columns[mapped[j]] })
+
+	var records []domain.Record
+	var rowErrors []RowError
+	for {
+		row, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, nil, err
+			}
+			rowErrors = append(rowErrors, RowError{Line: parseErr.Line, Message: parseErr.Err.Error()})
+			continue
+		}
+		if isEmpty(row) || isTotal(row, dates) {
+			continue
+		}
+
+		line, _ := csvReader.FieldPos(0)
+		record, errs := parseRow(row, line, mapped, columns, mapping, options.DateLayout)
+		if len(errs) != 0 {
+			rowErrors = append(rowErrors, errs...)
+			continue
+		}
+		records = append(records, record)
+	}
+	return records, rowErrors, nil
+}
+
+func parseRow(row []string, line int, mapped []string, columns map[string]int, mapping Mapping, dateLayout string) (domain.Record, []RowError) {
+	var record domain.Record
+	var rowErrors []RowError
+
+	for _, column := range mapped {
+		var value string
+		if i := columns[column]; i < len(row) {
+			value = row[i]
+		}
+
+		field := mapping[column]
+		if field == DateField {
+			date, err := ParseDate(value, dateLayout)
+			if err != nil {
+				rowErrors = append(rowErrors, RowError{Line: line, Column: column, Message: fmt.Sprintf("%s %q", err, value)})
+			}
+			record.Date = date
+			continue
+		}
+
+		amount, err := ParseRUB(value)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Column: column, Message: fmt.Sprintf("%s %q", err, value)})
+			continue
+		}
+		*AmountFields[field](&record) += amount
+	}
+	return record, rowErrors
+}
+
+func defaultMapping(header []string) Mapping {
+	mapping := Mapping{}
+	for _, column := range header {
+		field := strings.ToLower(strings.TrimSpace(column))
+		if _, ok := AmountFields[field]; ok || field == DateField {
+			mapping[strings.TrimSpace(column)] = field
+		}
+	}
+	return mapping
+}
+
+func guessComma(reader *bufio.Reader) rune {
+	line, _ := reader.Peek(reader.Size())
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	if bytes.Count(line, []byte(";")) > bytes.Count(line, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+// isTotal tells whether the row is a totals row, which has TotalLabel in
+// place of the date.
+func isTotal(row []string, dates []int) bool {
+	for _, i := range dates {
+		if i < len(row) && strings.TrimSpace(row[i]) == exporter.TotalLabel {
+			return true
+		}
+	}
+	return false
+}
+
+func isEmpty(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
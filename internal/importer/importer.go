@@ -0,0 +1,52 @@
+// Package importer adds records of rent objects from files exported by other
+// software.
+package importer
+
+import (
+	"context"
+	"io"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+)
+
+type Report struct {
+	// Records is the number of valid records in the file.
+	Records  int        `json:"records"`
+	Imported int        `json:"imported"`
+	DryRun   bool       `json:"dry_run"`
+	Errors   []RowError `json:"errors"`
+}
+
+// ImportCSV adds the records of the CSV to the object. Nothing is added on a
+// dry run or when any row is invalid, so that the file can be fixed and
+// imported again without duplicates.
+func ImportCSV(ctx context.Context, rep repository.RentObjectRepository, userID int64, objectName string, r io.Reader, options Options, dryRun bool) (Report, error) {
+	records, rowErrors, err := ParseCSV(r, options)
+	if err != nil {
+		return Report{}, err
+	}
+	return AddRecords(ctx, rep, userID, objectName, records, rowErrors, dryRun)
+}
+
+// AddRecords adds records parsed from a file unless it is a dry run or some
+// rows of the file are invalid. The object is checked to exist either way.
+func AddRecords(ctx context.Context, rep repository.RentObjectRepository, userID int64, objectName string, records []domain.Record, rowErrors []RowError, dryRun bool) (Report, error) {
+	report := Report{Records: len(records), DryRun: dryRun, Errors: rowErrors}
+	if report.Errors == nil {
+		report.Errors = []RowError{}
+	}
+
+	if _, err := rep.GetByName(ctx, userID, objectName); err != nil {
+		return report, err
+	}
+	if dryRun || len(rowErrors) != 0 {
+		return report, nil
+	}
+
+	// The records are added together, so that a failure adds none of them.
+	if err := rep.AddRecords(ctx, userID, objectName, records); err != nil {
+		return report, err
+	}
+	report.Imported = len(records)
+	return report, nil
+}
@@ -0,0 +1,191 @@
+package importer_test
+
+import (
+	"context"
+	"rental-server/internal/domain"
+	"rental-server/internal/importer"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/memory"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+var dummyUserID int64 = 1
+
+func TestParseRUB(t *testing.T) {
+	cases := map[string]domain.RUB{
+		"":             0,
+		"1234":         1234,
+		"1234.5":       1234.5,
+		"1 234,56":     1234.56,
+		"1\u00a0234,5": 1234.5,
+		"1.234,56 ₽":   1234.56,
+		"1,234.56":     1234.56,
+		"1.234.567":    1234567,
+		"-500,00":      -500,
+		"12 000 руб.":  12000,
+	}
+	for input, want := range cases {
+		got, err := importer.ParseRUB(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := importer.ParseRUB("десять")
+	assert.ErrorIs(t, err, importer.InvalidAmountError)
+}
+
+func TestParseDate(t *testing.T) {
+	march := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+	for _, input := range []string{"01.03.2025", "1.3.2025", "2025-03-01", "03.2025", "Март 2025", "мар. 2025", "марта 2025г."} {
+		got, err := importer.ParseDate(input, "")
+		assert.NoError(t, err, input)
+		assert.Equal(t, march, got, input)
+	}
+
+	got, err := importer.ParseDate("03/01/2025", "01/02/2006")
+	assert.NoError(t, err)
+	assert.Equal(t, march, got)
+
+	_, err = importer.ParseDate("2025", "")
+	assert.ErrorIs(t, err, importer.InvalidDateError)
+}
+
+func TestParseCSV(t *testing.T) {
+	t.Run("should map and sum columns of a Russian spreadsheet", func(t *testing.T) {
+		data := "\ufeffМесяц;Аренда;Охрана;Видеонаблюдение;Комментарий\n" +
+			"01.01.2025;\"100 000,50\";1 000;500;январь\n" +
+			"\n" +
+			"01.02.2025;100 000;1 000;;\n"
+		mapping := importer.Mapping{"Месяц": "date", "Аренда": "rent", "Охрана": "security", "Видеонаблюдение": "security"}
+
+		records, rowErrors, err := importer.ParseCSV(strings.NewReader(data), importer.Options{Mapping: mapping})
+		assert.NoError(t, err)
+		assert.Empty(t, rowErrors)
+		assert.Equal(t, []domain.Record{
+			{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 100000.5, Security: 1500},
+			{Date: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Rent: 100000, Security: 1000},
+		}, records)
+	})
+
+	t.Run("should import columns named as record fields without mapping", func(t *testing.T) {
+		data := "date,rent,earth_rent,note\n2025-01-01,100,20,x\n"
+
+		records, _, err := importer.ParseCSV(strings.NewReader(data), importer.Options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.Record{{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 100, EarthRent: 20}}, records)
+	})
+
+	t.Run("should skip totals rows of exports", func(t *testing.T) {
+		data := "object,date,rent\nOffice,2025-01-01,100\nOffice,total,100\ntotal,total,100\n"
+
+		records, rowErrors, err := importer.ParseCSV(strings.NewReader(data), importer.Options{})
+		assert.NoError(t, err)
+		assert.Empty(t, rowErrors)
+		assert.Equal(t, []domain.Record{{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 100}}, records)
+	})
+
+	t.Run("should report invalid rows with their lines", func(t *testing.T) {
+		data := "date,rent\n2025-01-01,100\nsomeday,100\n2025-03-01,lots\n"
+
+		records, rowErrors, err := importer.ParseCSV(strings.NewReader(data), importer.Options{})
+		assert.NoError(t, err)
+		assert.Len(t, records, 1)
+		assert.Equal(t, []importer.RowError{
+			{Line: 3, Column: "date", Message: `Invalid date "someday"`},
+			{Line: 4, Column: "rent", Message: `Invalid amount "lots"`},
+		}, rowErrors)
+	})
+
+	t.Run("should reject invalid mappings", func(t *testing.T) {
+		data := "date,rent\n"
+
+		_, _, err := importer.ParseCSV(strings.NewReader(data), importer.Options{Mapping: importer.Mapping{"rent": "rent"}})
+		assert.ErrorIs(t, err, importer.NoDateColumnError)
+
+		_, _, err = importer.ParseCSV(strings.NewReader(data), importer.Options{Mapping: importer.Mapping{"date": "date", "rent": "profit"}})
+		assert.Equal(t, importer.UnknownFieldError{Field: "profit"}, err)
+
+		_, _, err = importer.ParseCSV(strings.NewReader(data), importer.Options{Mapping: importer.Mapping{"Дата": "date"}})
+		assert.Equal(t, importer.MissingColumnError{Column: "Дата"}, err)
+	})
+}
+
+func TestParseMapping(t *testing.T) {
+	mapping, err := importer.ParseMapping([]string{"date=Месяц", "security=Охрана", "security=Видеонаблюдение"})
+	assert.NoError(t, err)
+	assert.Equal(t, importer.Mapping{"Месяц": "date", "Охрана": "security", "Видеонаблюдение": "security"}, mapping)
+
+	_, err = importer.ParseMapping([]string{"date"})
+	assert.Error(t, err)
+}
+
+func TestImportCSV(t *testing.T) {
+	newRepository := func() *memory.MemoryObjectRepository {
+		return memory.NewMemoryObjectRepository(memory.MemoryStore{
+			dummyUserID: {"Name": domain.NewRentObject("Name", "", 10)},
+		})
+	}
+	valid := "date,rent\n2025-01-01,100\n2025-02-01,200\n"
+
+	t.Run("should add records", func(t *testing.T) {
+		rep := newRepository()
+
+		report, err := importer.ImportCSV(ctx, rep, dummyUserID, "Name", strings.NewReader(valid), importer.Options{}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, importer.Report{Records: 2, Imported: 2, Errors: []importer.RowError{}}, report)
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, "Name")
+		assert.Len(t, records, 2)
+	})
+
+	t.Run("should add nothing on dry run", func(t *testing.T) {
+		rep := newRepository()
+
+		report, err := importer.ImportCSV(ctx, rep, dummyUserID, "Name", strings.NewReader(valid), importer.Options{}, true)
+		assert.NoError(t, err)
+		assert.Equal(t, importer.Report{Records: 2, DryRun: true, Errors: []importer.RowError{}}, report)
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, "Name")
+		assert.Empty(t, records)
+	})
+
+	t.Run("should add nothing when a row is invalid", func(t *testing.T) {
+		rep := newRepository()
+
+		report, err := importer.ImportCSV(ctx, rep, dummyUserID, "Name", strings.NewReader(valid+"2025-03-01,x\n"), importer.Options{}, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, report.Imported)
+		assert.Len(t, report.Errors, 1)
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, "Name")
+		assert.Empty(t, records)
+	})
+
+	t.Run("should return error for unknown object", func(t *testing.T) {
+		_, err := importer.ImportCSV(ctx, newRepository(), dummyUserID, "Unknown", strings.NewReader(valid), importer.Options{}, true)
+		assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	})
+
+	t.Run("should import nothing when storage fails", func(t *testing.T) {
+		rep := failingRepository{newRepository()}
+
+		report, err := importer.ImportCSV(ctx, rep, dummyUserID, "Name", strings.NewReader(valid), importer.Options{}, false)
+		assert.ErrorIs(t, err, repository.TimeoutError)
+		assert.Equal(t, 0, report.Imported)
+	})
+}
+
+// failingRepository fails to add records, as a storage that times out.
+type failingRepository struct {
+	*memory.MemoryObjectRepository
+}
+
+func (r failingRepository) AddRecords(ctx context.Context, userID int64, objectName string, records []domain.Record) error {
+	return repository.TimeoutError
+}
@@ -0,0 +1,99 @@
+package importer
+
+import (
+	"errors"
+	"rental-server/internal/domain"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var InvalidAmountError = errors.New("Invalid amount")
+var InvalidDateError = errors.New("Invalid date")
+
+// ParseRUB parses amounts as spreadsheets print them: "1 234,56", "1234.56",
+// "1.234,56 ₽" or "-500". An empty value is zero.
+func ParseRUB(s string) (domain.RUB, error) {
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f', '\t', '\'', '₽':
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(s), "."), "руб")
+	if s == "" {
+		return 0, nil
+	}
+
+	// The last separator is the decimal one and the others group thousands,
+	// unless it is repeated as in "1.234.567".
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 {
+		if strings.Count(s, s[i:i+1]) > 1 {
+			i = len(s)
+			s += "."
+		}
+		s = strings.NewReplacer(".", "", ",", "").Replace(s[:i]) + "." + s[i+1:]
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, InvalidAmountError
+	}
+	return domain.RUB(value), nil
+}
+
+var dateLayouts = []string{
+	"02.01.2006",
+	"2.1.2006",
+	"02.01.06",
+	time.DateOnly,
+	time.RFC3339,
+	"01.2006",
+	"2006-01",
+	"02/01/2006",
+}
+
+var months = map[string]time.Month{
+	"январь": time.January, "января": time.January, "янв": time.January,
+	"февраль": time.February, "февраля": time.February, "фев": time.February,
+	"март": time.March, "марта": time.March, "мар": time.March,
+	"апрель": time.April, "апреля": time.April, "апр": time.April,
+	"май": time.May, "мая": time.May,
+	"июнь": time.June, "июня": time.June, "июн": time.June,
+	"июль": time.July, "июля": time.July, "июл": time.July,
+	"август": time.August, "августа": time.August, "авг": time.August,
+	"сентябрь": time.September, "сентября": time.September, "сен": time.September,
+	"октябрь": time.October, "октября": time.October, "окт": time.October,
+	"ноябрь": time.November, "ноября": time.November, "ноя": time.November,
+	"декабрь": time.December, "декабря": time.December, "дек": time.December,
+}
+
+// ParseDate parses dates in the layout when it is set, or else in one of the
+// common Russian and ISO layouts, including month names like "Март 2025".
+// Dates without a day are the first day of the month.
+func ParseDate(s string, layout string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if layout != "" {
+		date, err := time.Parse(layout, s)
+		if err != nil {
+			return time.Time{}, InvalidDateError
+		}
+		return date, nil
+	}
+
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, s); err == nil {
+			return date, nil
+		}
+	}
+
+	if fields := strings.Fields(strings.ToLower(s)); len(fields) == 2 {
+		month, ok := months[strings.TrimSuffix(fields[0], ".")]
+		year, err := strconv.Atoi(strings.TrimSuffix(fields[1], "г."))
+		if ok && err == nil {
+			return time.Date(year, month, 1, 0, 0, 0, 0, time.UTC), nil
+		}
+	}
+	return time.Time{}, InvalidDateError
+}
@@ -0,0 +1,160 @@
+// Package metrics exports metrics of the service in the Prometheus format.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics keep metrics of HTTP requests and repository operations, and of
+// the Go runtime. It is a server.RequestObserver and a
+// repository.OperationObserver.
+type Metrics struct {
+	registry          *prometheus.Registry
+	requests          *prometheus.CounterVec
+	requestDuration   *prometheus.HistogramVec
+	operationDuration *prometheus.HistogramVec
+	operationErrors   *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rental_http_requests_total",
+			Help: "HTTP requests by route and status code.",
+		}, []string{"route", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "rental_http_request_duration_seconds",
+			Help:    "Duration of HTTP requests by route and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"route", "code"}),
+		operationDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "rental_repository_operation_duration_seconds",
+			Help:    "Duration of repository operations by method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"operation"}),
+		operationErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rental_repository_operation_errors_total",
+			Help: "Failed repository operations by method and kind of error.",
+		}, []string{"operation", "error"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests, m.requestDuration, m.operationDuration, m.operationErrors,
+	)
+	return m
+}
+
+// Handler serves the metrics to Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+func (m *Metrics) ObserveRequest(route string, code int, duration time.Duration) {
+	labels := prometheus.Labels{"route": route, "code": strconv.Itoa(code)}
+	m.requests.With(labels).Inc()
+	m.requestDuration.With(labels).Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveOperation(operation string, duration time.Duration, err error) {
+	m.operationDuration.WithLabelValues(operation).Observe(duration.Seconds())
+	if err != nil {
+		m.operationErrors.WithLabelValues(operation, errorKind(err)).Inc()
+	}
+}
+
+// errorKind names the kind of error, keeping the number of label values
+// small.
+func errorKind(err error) string {
+	switch {
+	case errors.Is(err, repository.TimeoutError):
+		return "timeout"
+	case errors.Is(err, repository.CanceledError):
+		return "canceled"
+	case errors.Is(err, repository.ObjectNotFoundError), errors.Is(err, domain.RecordNotFoundError),
+		errors.Is(err, domain.MatchRuleNotFoundError):
+		return "not_found"
+	case errors.Is(err, repository.ObjectAlreadyExists):
+		return "exists"
+	case errors.Is(err, repository.ConcurrentUpdateError):
+		return "conflict"
+	case errors.Is(err, repository.InvalidQueryError):
+		return "invalid"
+	}
+	return "other"
+}
+
+// CountStorage adds gauges of the objects and records of the storage, which
+// are counted on each scrape within the timeout, if it is not zero.
+func (m *Metrics) CountStorage(counter repository.Counter, timeout time.Duration) {
+	m.registry.MustRegister(&countCollector{
+		counter: counter,
+		timeout: timeout,
+		objects: prometheus.NewDesc("rental_objects", "Objects of all users.", nil, nil),
+		records: prometheus.NewDesc("rental_records", "Records of all objects.", nil, nil),
+	})
+}
+
+type countCollector struct {
+	counter          repository.Counter
+	timeout          time.Duration
+	objects, records *prometheus.Desc
+}
+
+func (c *countCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.objects
+	ch <- c.records
+}
+
+// Collect leaves the gauges out when counting fails, so that the other
+// metrics are still scraped.
+func (c *countCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	counts, err := c.counter.Count(ctx)
+	if err != nil {
+		slog.Error("Counting objects for metrics", "error", err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.objects, prometheus.GaugeValue, float64(counts.Objects))
+	ch <- prometheus.MustNewConstMetric(c.records, prometheus.GaugeValue, float64(counts.Records))
+}
+
+// ObserveCache adds the statistics of the cache.
+func (m *Metrics) ObserveCache(cache *repository.CachingRepository) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "rental_cache_hits_total",
+			Help: "Reads of objects served from the cache.",
+		}, func() float64 { return float64(cache.Stats().Hits) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "rental_cache_misses_total",
+			Help: "Reads of objects missing from the cache.",
+		}, func() float64 { return float64(cache.Stats().Misses) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: "rental_cache_evictions_total",
+			Help: "Objects evicted from the full cache.",
+		}, func() float64 { return float64(cache.Stats().Evictions) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: "rental_cache_entries",
+			Help: "Objects in the cache.",
+		}, func() float64 { return float64(cache.Stats().Entries) }),
+	)
+}
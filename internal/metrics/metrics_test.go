@@ -0,0 +1,78 @@
+package metrics_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"rental-server/internal/domain"
+	"rental-server/internal/metrics"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/memory"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func scrape(t *testing.T, m *metrics.Metrics) string {
+	t.Helper()
+	request, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+	responce := httptest.NewRecorder()
+	m.Handler().ServeHTTP(responce, request)
+	require.Equal(t, http.StatusOK, responce.Code)
+	return responce.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should export requests, operations and runtime metrics", func(t *testing.T) {
+		m := metrics.New()
+		rep := repository.WithObserver(memory.NewMemoryObjectRepository(nil), m)
+
+		m.ObserveRequest("/getObject", http.StatusNotFound, 10*time.Millisecond)
+		_, err := rep.GetByName(ctx, 1, "Unknown")
+		require.Equal(t, repository.ObjectNotFoundError, err)
+		_, err = rep.GetAll(ctx, 1)
+		require.NoError(t, err)
+
+		body := scrape(t, m)
+		for _, line := range []string{
+			`rental_http_requests_total{code="404",route="/getObject"} 1`,
+			`rental_http_request_duration_seconds_count{code="404",route="/getObject"} 1`,
+			`rental_repository_operation_duration_seconds_count{operation="GetByName"} 1`,
+			`rental_repository_operation_duration_seconds_count{operation="GetAll"} 1`,
+			`rental_repository_operation_errors_total{error="not_found",operation="GetByName"} 1`,
+		} {
+			assert.Contains(t, body, line)
+		}
+		assert.NotContains(t, body, `rental_repository_operation_errors_total{error="not_found",operation="GetAll"}`)
+		assert.Contains(t, body, "go_goroutines")
+	})
+
+	t.Run("Should export counts of the storage and statistics of the cache", func(t *testing.T) {
+		m := metrics.New()
+		storage := memory.NewMemoryObjectRepository(nil)
+		object := domain.NewRentObject("Name", "Description", 100)
+		object.AddRecord(domain.Record{Date: time.Now()})
+		require.NoError(t, storage.Add(ctx, 1, object))
+		cache := repository.NewCachingRepository(storage, repository.CacheOptions{Size: 10})
+		m.CountStorage(storage, time.Second)
+		m.ObserveCache(cache)
+
+		for range 2 {
+			_, err := cache.GetByName(ctx, 1, "Name")
+			require.NoError(t, err)
+		}
+
+		body := scrape(t, m)
+		for _, line := range []string{
+			"rental_objects 1", "rental_records 1",
+			"rental_cache_hits_total 1", "rental_cache_misses_total 1", "rental_cache_entries 1",
+		} {
+			assert.True(t, strings.Contains(body, "\n"+line+"\n"), "missing %q", line)
+		}
+	})
+}
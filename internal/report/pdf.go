@@ -0,0 +1,182 @@
+// Package report renders statements of rent objects for their owners.
+package report
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"rental-server/internal/domain"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// fontFamily is embedded so that Cyrillic is rendered without fonts
+// installed on the server.
+const fontFamily = "Go"
+
+const dateLayout = "02.01.2006"
+
+type category struct {
+	label  string
+	amount func(r *domain.Record) domain.RUB
+}
+
+var expenseCategories = []category{
+	{"Отопление", func(r *domain.Record) domain.RUB { return r.Heat }},
+	{"Эксплуатация", func(r *domain.Record) domain.RUB { return r.Exploitation }},
+	{"МОП", func(r *domain.Record) domain.RUB { return r.MOP }},
+	{"Ремонт", func(r *domain.Record) domain.RUB { return r.Renovation }},
+	{"ТБО", func(r *domain.Record) domain.RUB { return r.TBO }},
+	{"Электроэнергия", func(r *domain.Record) domain.RUB { return r.Electricity }},
+	{"Аренда земли", func(r *domain.Record) domain.RUB { return r.EarthRent }},
+	{"Прочее", func(r *domain.Record) domain.RUB { return r.Other }},
+	{"Охрана", func(r *domain.Record) domain.RUB { return r.Security }},
+}
+
+type table struct {
+	pdf    *gofpdf.Fpdf
+	widths []float64
+}
+
+func (t table) row(style string, fill bool, values ...string) {
+	t.pdf.SetFont(fontFamily, style, 9)
+	if fill {
+		t.pdf.SetFillColor(230, 230, 230)
+	}
+	for i, value := range values {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		t.pdf.CellFormat(t.widths[i], 6, value, "1", 0, align, fill, 0, "")
+	}
+	t.pdf.Ln(-1)
+}
+
+// WritePDF writes the statement of the object over the period of the info:
+// the object details, a table of records, the breakdown of expenses by
+// category and the totals.
+func WritePDF(w io.Writer, info domain.RentObjectInfo) error {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(fontFamily, "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes(fontFamily, "B", gobold.TTF)
+	pdf.SetTitle("Отчёт по объекту "+info.Name, true)
+	pdf.SetCreationDate(time.Now())
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont(fontFamily, "", 8)
+		pdf.CellFormat(0, 10, fmt.Sprintf("Страница %d из {nb}", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont(fontFamily, "B", 16)
+	pdf.MultiCell(0, 8, fmt.Sprintf("Отчёт по объекту «%s»", info.Name), "", "L", false)
+	pdf.SetFont(fontFamily, "", 10)
+	if info.Description != "" {
+		pdf.MultiCell(0, 5, info.Description, "", "L", false)
+	}
+	pdf.CellFormat(0, 6, fmt.Sprintf("Площадь: %s м²", formatNumber(info.Area)), "", 1, "L", false, 0, "")
+	pdf.CellFormat(0, 6, "Период: "+formatPeriod(info.Period), "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	heading(pdf, "Записи")
+	records := table{pdf, []float64{25, 27.5, 27.5, 27.5, 27.5, 27.5, 27.5}}
+	records.row("B", true, "Дата", "Доход", "Расходы", "Прибыль", "Доход/м²", "Расходы/м²", "Прибыль/м²")
+	for _, record := range info.RecordsInfo {
+		records.row("", false, record.Date.Format(dateLayout),
+			FormatRUB(record.Income), FormatRUB(record.Expenses), FormatRUB(record.Profit),
+			FormatRUB(record.IncomeByArea), FormatRUB(record.ExpensesByArea), FormatRUB(record.ProfitByArea))
+	}
+	if len(info.RecordsInfo) == 0 {
+		pdf.SetFont(fontFamily, "", 9)
+		pdf.CellFormat(0, 6, "Нет записей за период", "1", 1, "C", false, 0, "")
+	}
+	records.row("B", true, "Итого",
+		FormatRUB(info.Total.Income), FormatRUB(info.Total.Expenses), FormatRUB(info.Total.Profit),
+		FormatRUB(info.Total.IncomeByArea), FormatRUB(info.Total.ExpensesByArea), FormatRUB(info.Total.ProfitByArea))
+	pdf.Ln(6)
+
+	heading(pdf, "Расходы по статьям")
+	categories := table{pdf, []float64{60, 40, 40, 40}}
+	categories.row("B", true, "Статья", "Сумма", "Доля", "На м²")
+	for _, category := range expenseCategories {
+		var amount domain.RUB
+		for i := range info.RecordsInfo {
+			amount += category.amount(&info.RecordsInfo[i].Record)
+		}
+		categories.row("", false, category.label, FormatRUB(amount), formatShare(amount, info.Total.Expenses), FormatRUB(byArea(amount, info.Area)))
+	}
+	categories.row("B", true, "Всего", FormatRUB(info.Total.Expenses), formatShare(info.Total.Expenses, info.Total.Expenses), FormatRUB(info.Total.ExpensesByArea))
+	pdf.Ln(6)
+
+	heading(pdf, "Итоги")
+	totals := table{pdf, []float64{60, 40}}
+	totals.row("", false, "Доход", FormatRUB(info.Total.Income))
+	totals.row("", false, "Расходы", FormatRUB(info.Total.Expenses))
+	totals.row("B", false, "Прибыль", FormatRUB(info.Total.Profit))
+	totals.row("B", false, "Прибыль на м²", FormatRUB(info.Total.ProfitByArea))
+
+	return pdf.Output(w)
+}
+
+func heading(pdf *gofpdf.Fpdf, text string) {
+	pdf.SetFont(fontFamily, "B", 12)
+	pdf.CellFormat(0, 8, text, "", 1, "L", false, 0, "")
+}
+
+func formatPeriod(period domain.Period) string {
+	switch {
+	case period.From != nil && period.To != nil:
+		return fmt.Sprintf("с %s до %s", period.From.Format(dateLayout), period.To.Format(dateLayout))
+	case period.From != nil:
+		return "с " + period.From.Format(dateLayout)
+	case period.To != nil:
+		return "до " + period.To.Format(dateLayout)
+	default:
+		return "все записи"
+	}
+}
+
+// FormatRUB formats amounts the Russian way, e.g. "-1 234 567,89".
+func FormatRUB(amount domain.RUB) string {
+	cents := int64(math.Round(math.Abs(float64(amount)) * 100))
+	sign := ""
+	if amount < 0 && cents != 0 {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s%s,%02d", sign, groupThousands(cents/100), cents%100)
+}
+
+func formatNumber(value float64) string {
+	s := FormatRUB(domain.RUB(value))
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ",")
+}
+
+func formatShare(amount, total domain.RUB) string {
+	if total == 0 {
+		return "—"
+	}
+	return strings.Replace(fmt.Sprintf("%.1f %%", float64(amount/total)*100), ".", ",", 1)
+}
+
+func groupThousands(n int64) string {
+	s := fmt.Sprint(n)
+	var groups []string
+	for len(s) > 3 {
+		groups = append([]string{s[len(s)-3:]}, groups...)
+		s = s[:len(s)-3]
+	}
+	return strings.Join(append([]string{s}, groups...), " ")
+}
+
+func byArea(amount domain.RUB, area float64) domain.RUB {
+	if area == 0 {
+		return 0
+	}
+	return domain.RUB(float64(amount) / area)
+}
@@ -0,0 +1,53 @@
+package report_test
+
+import (
+	"bytes"
+	"rental-server/internal/domain"
+	"rental-server/internal/report"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatRUB(t *testing.T) {
+	cases := map[domain.RUB]string{
+		0:           "0,00",
+		5.5:         "5,50",
+		1234.567:    "1 234,57",
+		-1234567.89: "-1 234 567,89",
+		-0.001:      "0,00",
+	}
+	for amount, want := range cases {
+		assert.Equal(t, want, report.FormatRUB(amount))
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	object := domain.NewRentObject("Склад №1", "Ул. Ленина, 1", 120.5)
+	for month := 1; month <= 60; month++ {
+		object.AddRecord(domain.Record{Date: time.Date(2021, time.Month(month), 1, 0, 0, 0, 0, time.UTC), Rent: 100000, Heat: 5000.5, Security: 3000})
+	}
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("should write a page for a period", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		err := report.WritePDF(buf, domain.NewRentObjectInfoForPeriod(object, domain.Period{From: &from}))
+		assert.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+		assert.Contains(t, buf.String(), "/Count 1")
+	})
+
+	t.Run("should break long tables into pages", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		err := report.WritePDF(buf, domain.NewRentObjectInfo(object))
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "/Count 2")
+	})
+
+	t.Run("should write an object without records", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		err := report.WritePDF(buf, domain.NewRentObjectInfo(domain.NewRentObject("Пусто", "", 0)))
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"rental-server/internal/domain"
+	"sort"
+	"time"
+)
+
+// Granularity is the length of the periods that TotalsByPeriod sums up.
+type Granularity string
+
+const (
+	ByMonth   Granularity = "month"
+	ByQuarter Granularity = "quarter"
+	ByYear    Granularity = "year"
+)
+
+// TotalsQuery selects the records to sum up: those of Period, of the object
+// named ObjectName, or of all objects of the user when it is empty.
+type TotalsQuery struct {
+	domain.Period
+	ObjectName string
+	// Granularity is only used by TotalsByPeriod, months by default.
+	Granularity Granularity
+}
+
+// Normalize fills in defaults and validates the query.
+func (q *TotalsQuery) Normalize() error {
+	if q.Granularity == "" {
+		q.Granularity = ByMonth
+	}
+	if q.Granularity != ByMonth && q.Granularity != ByQuarter && q.Granularity != ByYear {
+		return InvalidQueryError
+	}
+	return nil
+}
+
+// Totals are sums of records, with Profit computed as domain.Record.Profit.
+type Totals struct {
+	Income   domain.RUB `json:"income"`
+	Expenses domain.RUB `json:"expenses"`
+	Profit   domain.RUB `json:"profit"`
+	Records  int        `json:"records"`
+}
+
+func (t *Totals) add(record domain.Record) {
+	t.Income += record.Income()
+	t.Expenses += record.Expenses()
+	t.Profit = t.Income - t.Expenses
+	t.Records++
+}
+
+// ObjectTotals are the totals of an object, which are zero when it has no
+// records in the period.
+type ObjectTotals struct {
+	Name string `json:"name"`
+	Totals
+}
+
+// PeriodTotals are the totals of the period that starts at Start, in UTC.
+type PeriodTotals struct {
+	Start time.Time `json:"start"`
+	Totals
+}
+
+// CategoryTotal is the sum of an amount field of records, named as in JSON.
+// Rent is income and the other categories are expenses.
+type CategoryTotal struct {
+	Category string     `json:"category"`
+	Amount   domain.RUB `json:"amount"`
+}
+
+// Categories are the amount fields of records in the order of
+// TotalsByCategory.
+var Categories = []string{"rent", "heat", "exploitation", "mop", "renovation", "tbo", "electricity", "earth_rent", "other", "security"}
+
+// CategoryAmounts returns the amounts of the record in the order of
+// Categories.
+func CategoryAmounts(record domain.Record) []domain.RUB {
+	return []domain.RUB{
+		record.Rent, record.Heat, record.Exploitation, record.MOP, record.Renovation,
+		record.TBO, record.Electricity, record.EarthRent, record.Other, record.Security,
+	}
+}
+
+// PeriodStart returns the start of the period of the granularity that the
+// date falls into.
+func PeriodStart(date time.Time, granularity Granularity) time.Time {
+	date = date.UTC()
+	month := date.Month()
+	switch granularity {
+	case ByQuarter:
+		month = (month-1)/3*3 + 1
+	case ByYear:
+		month = time.January
+	}
+	return time.Date(date.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// SumByObject sums up records of the objects in the period of the query.
+// The totals are sorted by object name.
+func SumByObject(objects []domain.RentObject, query TotalsQuery) ([]ObjectTotals, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	result := []ObjectTotals{}
+	for _, object := range objects {
+		totals := ObjectTotals{Name: object.Name}
+		for _, record := range object.Records {
+			if query.Contains(record.Date) {
+				totals.add(record)
+			}
+		}
+		result = append(result, totals)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// SumByPeriod sums up records of the objects in periods of the granularity
+// of the query. Periods without records are left out.
+func SumByPeriod(objects []domain.RentObject, query TotalsQuery) ([]PeriodTotals, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	totals := map[time.Time]*Totals{}
+	for _, object := range objects {
+		for _, record := range object.Records {
+			if !query.Contains(record.Date) {
+				continue
+			}
+			start := PeriodStart(record.Date, query.Granularity)
+			if totals[start] == nil {
+				totals[start] = &Totals{}
+			}
+			totals[start].add(record)
+		}
+	}
+
+	result := []PeriodTotals{}
+	for start, t := range totals {
+		result = append(result, PeriodTotals{Start: start, Totals: *t})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Start.Before(result[j].Start) })
+	return result, nil
+}
+
+// SumByCategory sums up each amount field of records of the objects in the
+// period of the query.
+func SumByCategory(objects []domain.RentObject, query TotalsQuery) ([]CategoryTotal, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	sums := make([]domain.RUB, len(Categories))
+	for _, object := range objects {
+		for _, record := range object.Records {
+			if !query.Contains(record.Date) {
+				continue
+			}
+			for i, amount := range CategoryAmounts(record) {
+				sums[i] += amount
+			}
+		}
+	}
+	return NewCategoryTotals(sums), nil
+}
+
+// NewCategoryTotals names sums given in the order of Categories.
+func NewCategoryTotals(sums []domain.RUB) []CategoryTotal {
+	result := make([]CategoryTotal, 0, len(Categories))
+	for i, category := range Categories {
+		result = append(result, CategoryTotal{Category: category, Amount: sums[i]})
+	}
+	return result
+}
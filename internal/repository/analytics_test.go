@@ -0,0 +1,73 @@
+package repository_test
+
+import (
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func totalsObjects() []domain.RentObject {
+	shop := domain.NewRentObject("shop", "", 10)
+	shop.AddRecord(domain.Record{Date: time.Date(2024, time.January, 10, 0, 0, 0, 0, time.UTC), Rent: 1000, Heat: 100})
+	shop.AddRecord(domain.Record{Date: time.Date(2024, time.May, 10, 0, 0, 0, 0, time.UTC), Rent: 1000, TBO: 50})
+	office := domain.NewRentObject("office", "", 20)
+	office.AddRecord(domain.Record{Date: time.Date(2024, time.February, 10, 0, 0, 0, 0, time.UTC), Rent: 500, Heat: 200})
+	return []domain.RentObject{shop, office, domain.NewRentObject("empty", "", 5)}
+}
+
+func TestSumByObject(t *testing.T) {
+	t.Run("Should sum records of each object sorted by name", func(t *testing.T) {
+		got, err := repository.SumByObject(totalsObjects(), repository.TotalsQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, []repository.ObjectTotals{
+			{Name: "empty"},
+			{Name: "office", Totals: repository.Totals{Income: 500, Expenses: 200, Profit: 300, Records: 1}},
+			{Name: "shop", Totals: repository.Totals{Income: 2000, Expenses: 150, Profit: 1850, Records: 2}},
+		}, got)
+	})
+
+	t.Run("Should only sum records of the period", func(t *testing.T) {
+		to := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+		got, err := repository.SumByObject(totalsObjects(), repository.TotalsQuery{Period: domain.Period{To: &to}})
+		assert.NoError(t, err)
+		assert.Equal(t, repository.Totals{Income: 1000, Expenses: 100, Profit: 900, Records: 1}, got[2].Totals)
+	})
+}
+
+func TestSumByPeriod(t *testing.T) {
+	t.Run("Should sum records of each month with records", func(t *testing.T) {
+		got, err := repository.SumByPeriod(totalsObjects(), repository.TotalsQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, []repository.PeriodTotals{
+			{Start: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), Totals: repository.Totals{Income: 1000, Expenses: 100, Profit: 900, Records: 1}},
+			{Start: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), Totals: repository.Totals{Income: 500, Expenses: 200, Profit: 300, Records: 1}},
+			{Start: time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC), Totals: repository.Totals{Income: 1000, Expenses: 50, Profit: 950, Records: 1}},
+		}, got)
+	})
+
+	t.Run("Should sum records of each quarter", func(t *testing.T) {
+		got, err := repository.SumByPeriod(totalsObjects(), repository.TotalsQuery{Granularity: repository.ByQuarter})
+		assert.NoError(t, err)
+		if assert.Len(t, got, 2) {
+			assert.Equal(t, time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC), got[1].Start)
+			assert.Equal(t, 2, got[0].Records)
+		}
+	})
+
+	t.Run("Should reject unknown granularity", func(t *testing.T) {
+		_, err := repository.SumByPeriod(totalsObjects(), repository.TotalsQuery{Granularity: "week"})
+		assert.ErrorIs(t, err, repository.InvalidQueryError)
+	})
+}
+
+func TestSumByCategory(t *testing.T) {
+	got, err := repository.SumByCategory(totalsObjects(), repository.TotalsQuery{})
+	assert.NoError(t, err)
+	assert.Len(t, got, len(repository.Categories))
+	assert.Equal(t, repository.CategoryTotal{Category: "rent", Amount: 2500}, got[0])
+	assert.Equal(t, repository.CategoryTotal{Category: "heat", Amount: 300}, got[1])
+	assert.Equal(t, repository.CategoryTotal{Category: "tbo", Amount: 50}, got[5])
+}
@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"rental-server/internal/domain"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CacheOptions configure CachingRepository.
+type CacheOptions struct {
+	// Size is the number of objects kept, which must be positive.
+	Size int
+	// TTL is how long an object is kept, zero for as long as it is not
+	// changed or evicted.
+	TTL time.Duration
+	// OnInvalidate, when set, is called after each change of an object made
+	// through the cache, e.g. to let other instances call Invalidate.
+	OnInvalidate func(userID int64, objectName string)
+}
+
+// CacheStats count reads of objects since the cache was made.
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+}
+
+// CachingRepository keeps recently read objects in an LRU cache, and reads
+// of an object and its records are served from it. Only reads of whole
+// objects fill the cache, so that reads of a period, a page or a record of
+// an object that is not cached read no more than they need. Other reads go
+// to the repository. Each change drops the object that it changes.
+type CachingRepository struct {
+	RentObjectRepository
+	options CacheOptions
+
+	mu      sync.Mutex
+	entries map[cacheKey]*list.Element
+	// lru has the most recently used entry at the front.
+	lru *list.List
+	// generation grows with each invalidation, so that objects read before
+	// a change are not cached after it.
+	generation uint64
+	stats      CacheStats
+}
+
+type cacheKey struct {
+	userID     int64
+	objectName string
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	object  domain.RentObject
+	expires time.Time
+}
+
+func NewCachingRepository(rep RentObjectRepository, options CacheOptions) *CachingRepository {
+	return &CachingRepository{
+		RentObjectRepository: rep,
+		options:              options,
+		entries:              map[cacheKey]*list.Element{},
+		lru:                  list.New(),
+	}
+}
+
+// Stats returns the statistics of the cache.
+func (c *CachingRepository) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	return stats
+}
+
+// Invalidate drops the object from the cache. Unlike changes, it does not
+// call OnInvalidate, so that invalidations from other instances are not
+// sent back.
+func (c *CachingRepository) Invalidate(userID int64, objectName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	if element, ok := c.entries[cacheKey{userID, objectName}]; ok {
+		c.remove(element)
+	}
+}
+
+func (c *CachingRepository) remove(element *list.Element) {
+	c.lru.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).key)
+}
+
+// changed invalidates the objects after a change, whether it failed or
+// not, since a failed change may still have been made.
+func (c *CachingRepository) changed(userID int64, objectNames ...string) {
+	for _, objectName := range objectNames {
+		c.Invalidate(userID, objectName)
+		if c.options.OnInvalidate != nil {
+			c.options.OnInvalidate(userID, objectName)
+		}
+	}
+}
+
+// lookup returns a copy of the object when it is cached, and otherwise the
+// generation that an object read from the repository is cached in.
+func (c *CachingRepository) lookup(userID int64, objectName string) (domain.RentObject, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[cacheKey{userID, objectName}]; ok {
+		entry := element.Value.(*cacheEntry)
+		if entry.expires.IsZero() || time.Now().Before(entry.expires) {
+			c.lru.MoveToFront(element)
+			c.stats.Hits++
+			return clone(entry.object), c.generation, true
+		}
+		c.remove(element)
+	}
+	c.stats.Misses++
+	return domain.RentObject{}, c.generation, false
+}
+
+// get returns a copy of the object, reading it from the repository on a
+// miss.
+func (c *CachingRepository) get(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	object, generation, ok := c.lookup(userID, objectName)
+	if ok {
+		return object, nil
+	}
+
+	object, err := c.RentObjectRepository.GetByName(ctx, userID, objectName)
+	if err != nil {
+		return object, err
+	}
+
+	key := cacheKey{userID, objectName}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return object, nil
+	}
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	entry := &cacheEntry{key: key, object: clone(object)}
+	if c.options.TTL > 0 {
+		entry.expires = time.Now().Add(c.options.TTL)
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.options.Size {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+	return object, nil
+}
+
+func clone(object domain.RentObject) domain.RentObject {
+	object.Records = slices.Clone(object.Records)
+	return object
+}
+
+func (c *CachingRepository) GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	return c.get(ctx, userID, objectName)
+}
+
+// GetByNameInPeriod reads a bounded period of an object that is not cached
+// from the repository, which reads only the records of the period, and
+// leaves the cache as it is.
+func (c *CachingRepository) GetByNameInPeriod(ctx context.Context, userID int64, objectName string, period domain.Period) (domain.RentObject, error) {
+	if period.From == nil && period.To == nil {
+		return c.get(ctx, userID, objectName)
+	}
+	if object, _, ok := c.lookup(userID, objectName); ok {
+		return object.InPeriod(period), nil
+	}
+	return c.RentObjectRepository.GetByNameInPeriod(ctx, userID, objectName, period)
+}
+
+// GetRecordByIndex reads a record of an object that is not cached from the
+// repository, as GetByNameInPeriod does.
+func (c *CachingRepository) GetRecordByIndex(ctx context.Context, userID int64, objectName string, recordIndex int) (domain.Record, error) {
+	if object, _, ok := c.lookup(userID, objectName); ok {
+		return object.GetRecordByIndex(recordIndex)
+	}
+	return c.RentObjectRepository.GetRecordByIndex(ctx, userID, objectName, recordIndex)
+}
+
+// GetAllRecords sorts records by date, keeping records of the same date in
+// the order of their indexes, as storages do.
+func (c *CachingRepository) GetAllRecords(ctx context.Context, userID int64, objectName string) ([]domain.Record, error) {
+	object, err := c.get(ctx, userID, objectName)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(object.Records, func(i, j int) bool {
+		return object.Records[i].Date.Before(object.Records[j].Date)
+	})
+	return object.Records, nil
+}
+
+// FindRecords pages records of an object that is not cached in the
+// repository, as GetByNameInPeriod does.
+func (c *CachingRepository) FindRecords(ctx context.Context, userID int64, objectName string, query RecordQuery) (Page[RecordEntry], error) {
+	if object, _, ok := c.lookup(userID, objectName); ok {
+		return PageRecords(object.Records, query)
+	}
+	return c.RentObjectRepository.FindRecords(ctx, userID, objectName, query)
+}
+
+func (c *CachingRepository) Add(ctx context.Context, userID int64, object domain.RentObject) error {
+	defer c.changed(userID, object.Name)
+	return c.RentObjectRepository.Add(ctx, userID, object)
+}
+
+func (c *CachingRepository) Delete(ctx context.Context, userID int64, objectName string) error {
+	defer c.changed(userID, objectName)
+	return c.RentObjectRepository.Delete(ctx, userID, objectName)
+}
+
+func (c *CachingRepository) Update(ctx context.Context, userID int64, objectName string, input domain.UpdateRentObjectInput) error {
+	if input.Name != nil && *input.Name != objectName {
+		defer c.changed(userID, objectName, *input.Name)
+	} else {
+		defer c.changed(userID, objectName)
+	}
+	return c.RentObjectRepository.Update(ctx, userID, objectName, input)
+}
+
+func (c *CachingRepository) AddRecord(ctx context.Context, userID int64, objectName string, record domain.Record) (int, error) {
+	defer c.changed(userID, objectName)
+	return c.RentObjectRepository.AddRecord(ctx, userID, objectName, record)
+}
+
+func (c *CachingRepository) AddRecords(ctx context.Context, userID int64, objectName string, records []domain.Record) error {
+	defer c.changed(userID, objectName)
+	return c.RentObjectRepository.AddRecords(ctx, userID, objectName, records)
+}
+
+func (c *CachingRepository) DeleteRecord(ctx context.Context, userID int64, objectName string, recordIndex int) error {
+	defer c.changed(userID, objectName)
+	return c.RentObjectRepository.DeleteRecord(ctx, userID, objectName, recordIndex)
+}
+
+func (c *CachingRepository) UpdateRecord(ctx context.Context, userID int64, objectName string, recordIndex int, input domain.UpdateRecordInput) error {
+	defer c.changed(userID, objectName)
+	return c.RentObjectRepository.UpdateRecord(ctx, userID, objectName, recordIndex, input)
+}
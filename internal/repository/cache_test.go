@@ -0,0 +1,180 @@
+package repository_test
+
+import (
+	"context"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/memory"
+	"rental-server/internal/repository/repositorytest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingRepository counts reads of objects by name.
+type countingRepository struct {
+	*memory.MemoryObjectRepository
+	reads int
+}
+
+func (r *countingRepository) GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	r.reads++
+	return r.MemoryObjectRepository.GetByName(ctx, userID, objectName)
+}
+
+func newCountingRepository(t *testing.T, names ...string) *countingRepository {
+	rep := &countingRepository{MemoryObjectRepository: memory.NewMemoryObjectRepository(nil)}
+	for _, name := range names {
+		require.NoError(t, rep.Add(context.Background(), 1, domain.NewRentObject(name, "Description", 100)))
+	}
+	return rep
+}
+
+func TestCachingRepository(t *testing.T) {
+	ctx := context.Background()
+	record := domain.Record{Date: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), Rent: 1000}
+
+	t.Run("Reads of an object are served from the cache", func(t *testing.T) {
+		storage := newCountingRepository(t, "Name")
+		rep := repository.NewCachingRepository(storage, repository.CacheOptions{Size: 10})
+
+		_, err := rep.GetByName(ctx, 1, "Name")
+		require.NoError(t, err)
+		_, err = rep.GetAllRecords(ctx, 1, "Name")
+		require.NoError(t, err)
+		_, err = rep.GetByNameInPeriod(ctx, 1, "Name", domain.Period{})
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, storage.reads)
+		assert.Equal(t, repository.CacheStats{Hits: 2, Misses: 1, Entries: 1}, rep.Stats())
+	})
+
+	t.Run("Changes invalidate the object and call the hook", func(t *testing.T) {
+		storage := newCountingRepository(t, "Name")
+		var invalidated []string
+		rep := repository.NewCachingRepository(storage, repository.CacheOptions{
+			Size:         10,
+			OnInvalidate: func(userID int64, objectName string) { invalidated = append(invalidated, objectName) },
+		})
+		_, err := rep.GetByName(ctx, 1, "Name")
+		require.NoError(t, err)
+
+		_, err = rep.AddRecord(ctx, 1, "Name", record)
+		require.NoError(t, err)
+		records, err := rep.GetAllRecords(ctx, 1, "Name")
+
+		require.NoError(t, err)
+		assert.Equal(t, []domain.Record{record}, records)
+		assert.Equal(t, 2, storage.reads)
+		assert.Equal(t, []string{"Name"}, invalidated)
+	})
+
+	t.Run("Renaming invalidates both names", func(t *testing.T) {
+		storage := newCountingRepository(t, "Name")
+		rep := repository.NewCachingRepository(storage, repository.CacheOptions{Size: 10})
+		_, err := rep.GetByName(ctx, 1, "Name")
+		require.NoError(t, err)
+		_, err = rep.GetByName(ctx, 1, "New")
+		require.Equal(t, repository.ObjectNotFoundError, err)
+
+		newName := "New"
+		require.NoError(t, rep.Update(ctx, 1, "Name", domain.UpdateRentObjectInput{Name: &newName}))
+
+		_, err = rep.GetByName(ctx, 1, "Name")
+		assert.Equal(t, repository.ObjectNotFoundError, err)
+		object, err := rep.GetByName(ctx, 1, "New")
+		assert.NoError(t, err)
+		assert.Equal(t, "New", object.Name)
+	})
+
+	t.Run("Invalidate drops the object without calling the hook", func(t *testing.T) {
+		storage := newCountingRepository(t, "Name")
+		rep := repository.NewCachingRepository(storage, repository.CacheOptions{
+			Size:         10,
+			OnInvalidate: func(int64, string) { t.Error("hook called") },
+		})
+		_, err := rep.GetByName(ctx, 1, "Name")
+		require.NoError(t, err)
+
+		rep.Invalidate(1, "Name")
+		_, err = rep.GetByName(ctx, 1, "Name")
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, storage.reads)
+	})
+
+	t.Run("Objects expire after TTL", func(t *testing.T) {
+		storage := newCountingRepository(t, "Name")
+		rep := repository.NewCachingRepository(storage, repository.CacheOptions{Size: 10, TTL: 10 * time.Millisecond})
+		_, err := rep.GetByName(ctx, 1, "Name")
+		require.NoError(t, err)
+
+		time.Sleep(20 * time.Millisecond)
+		_, err = rep.GetByName(ctx, 1, "Name")
+
+		require.NoError(t, err)
+		assert.Equal(t, 2, storage.reads)
+	})
+
+	t.Run("Least recently used object is evicted", func(t *testing.T) {
+		storage := newCountingRepository(t, "A", "B", "C")
+		rep := repository.NewCachingRepository(storage, repository.CacheOptions{Size: 2})
+
+		for _, name := range []string{"A", "B", "A", "C", "A", "B"} {
+			_, err := rep.GetByName(ctx, 1, name)
+			require.NoError(t, err)
+		}
+
+		assert.Equal(t, repository.CacheStats{Hits: 2, Misses: 4, Evictions: 2, Entries: 2}, rep.Stats())
+	})
+
+	t.Run("Cached objects are not changed by callers", func(t *testing.T) {
+		storage := newCountingRepository(t, "Name")
+		_, err := storage.AddRecord(ctx, 1, "Name", record)
+		require.NoError(t, err)
+		rep := repository.NewCachingRepository(storage, repository.CacheOptions{Size: 10})
+
+		object, err := rep.GetByName(ctx, 1, "Name")
+		require.NoError(t, err)
+		object.Records[0].Rent = 0
+		got, err := rep.GetRecordByIndex(ctx, 1, "Name", 0)
+
+		require.NoError(t, err)
+		assert.Equal(t, record, got)
+	})
+
+	t.Run("Bounded reads of objects that are not cached go to the storage", func(t *testing.T) {
+		storage := newCountingRepository(t, "Name")
+		_, err := storage.AddRecord(ctx, 1, "Name", record)
+		require.NoError(t, err)
+		rep := repository.NewCachingRepository(storage, repository.CacheOptions{Size: 10})
+
+		from := record.Date.AddDate(0, 1, 0)
+		object, err := rep.GetByNameInPeriod(ctx, 1, "Name", domain.Period{From: &from})
+		require.NoError(t, err)
+		assert.Empty(t, object.Records)
+		_, err = rep.GetRecordByIndex(ctx, 1, "Name", 0)
+		require.NoError(t, err)
+		page, err := rep.FindRecords(ctx, 1, "Name", repository.RecordQuery{Limit: 1})
+		require.NoError(t, err)
+		assert.Len(t, page.Items, 1)
+
+		assert.Zero(t, storage.reads, "the whole object is not read")
+		assert.Zero(t, rep.Stats().Entries)
+
+		_, err = rep.GetByName(ctx, 1, "Name")
+		require.NoError(t, err)
+		object, err = rep.GetByNameInPeriod(ctx, 1, "Name", domain.Period{From: &from})
+		require.NoError(t, err)
+		assert.Empty(t, object.Records)
+		assert.Equal(t, 1, storage.reads, "cached objects serve bounded reads")
+	})
+}
+
+func TestCachingRepositoryConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.RentObjectRepository {
+		return repository.NewCachingRepository(memory.NewMemoryObjectRepository(nil), repository.CacheOptions{Size: 10})
+	})
+}
@@ -1,15 +1,26 @@
 package memory
 
 import (
+	"context"
 	"rental-server/internal/domain"
 	"rental-server/internal/repository"
+	"slices"
 	"sort"
+	"sync"
 )
 
 type MemoryStore map[int64]map[string]domain.RentObject
 
+// MemoryObjectRepository is safe for concurrent use. Objects are copied in
+// and out, so that callers never share records with the store.
 type MemoryObjectRepository struct {
+	mu    sync.RWMutex
 	store MemoryStore
+	rules map[int64][]domain.MatchRule
+
+	// wal is set when the repository is persistent, see
+	// OpenMemoryObjectRepository.
+	wal *wal
 }
 
 func NewMemoryObjectRepository(store MemoryStore) *MemoryObjectRepository {
@@ -19,19 +30,49 @@ func NewMemoryObjectRepository(store MemoryStore) *MemoryObjectRepository {
 
 	return &MemoryObjectRepository{
 		store: store,
+		rules: map[int64][]domain.MatchRule{},
 	}
 }
 
-func (m *MemoryObjectRepository) Add(userID int64, object domain.RentObject) error {
+func clone(object domain.RentObject) domain.RentObject {
+	object.Records = slices.Clone(object.Records)
+	return object
+}
+
+// write applies a change under the write lock, see change. Changes are
+// quick, so the context is only checked before.
+func (m *MemoryObjectRepository) write(ctx context.Context, e entry) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := m.change(e)
+	return err
+}
+
+func (m *MemoryObjectRepository) Add(ctx context.Context, userID int64, object domain.RentObject) error {
+	return m.write(ctx, entry{Op: opAdd, UserID: userID, Object: &object})
+}
+
+func (m *MemoryObjectRepository) add(userID int64, object domain.RentObject) error {
+	if _, ok := m.store[userID][object.Name]; ok {
+		return repository.ObjectAlreadyExists
+	}
 	if m.store[userID] == nil {
 		objectStore := make(map[string]domain.RentObject)
 		m.store[userID] = objectStore
 	}
-	m.store[userID][object.Name] = object
+	m.store[userID][object.Name] = clone(object)
 	return nil
 }
 
-func (m *MemoryObjectRepository) Delete(userID int64, objectName string) error {
+func (m *MemoryObjectRepository) Delete(ctx context.Context, userID int64, objectName string) error {
+	return m.write(ctx, entry{Op: opDelete, UserID: userID, ObjectName: objectName})
+}
+
+func (m *MemoryObjectRepository) delete(userID int64, objectName string) error {
 	if _, ok := m.store[userID][objectName]; !ok {
 		return repository.ObjectNotFoundError
 	}
@@ -39,18 +80,48 @@ func (m *MemoryObjectRepository) Delete(userID int64, objectName string) error {
 	return nil
 }
 
-func (m *MemoryObjectRepository) Update(userID int64, objectName string, input domain.UpdateRentObjectInput) error {
-	object, err := m.GetByName(userID, objectName)
+func (m *MemoryObjectRepository) Update(ctx context.Context, userID int64, objectName string, input domain.UpdateRentObjectInput) error {
+	return m.write(ctx, entry{Op: opUpdate, UserID: userID, ObjectName: objectName, ObjectInput: &input})
+}
+
+func (m *MemoryObjectRepository) update(userID int64, objectName string, input domain.UpdateRentObjectInput) error {
+	object, err := m.get(userID, objectName)
 	if err != nil {
 		return err
 	}
 
 	newObject := object.Update(input)
-	m.store[userID][objectName] = newObject
+	if newObject.Name != objectName {
+		if _, ok := m.store[userID][newObject.Name]; ok {
+			return repository.ObjectAlreadyExists
+		}
+		delete(m.store[userID], objectName)
+	}
+	m.store[userID][newObject.Name] = newObject
 	return nil
 }
 
-func (m *MemoryObjectRepository) GetByName(userID int64, objectName string) (domain.RentObject, error) {
+func (m *MemoryObjectRepository) GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.RentObject{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	object, err := m.get(userID, objectName)
+	return clone(object), err
+}
+
+func (m *MemoryObjectRepository) GetByNameInPeriod(ctx context.Context, userID int64, objectName string, period domain.Period) (domain.RentObject, error) {
+	object, err := m.GetByName(ctx, userID, objectName)
+	if err != nil {
+		return object, err
+	}
+	return object.InPeriod(period), nil
+}
+
+// get returns the stored object, whose records must not be changed in place.
+func (m *MemoryObjectRepository) get(userID int64, objectName string) (domain.RentObject, error) {
 	var obj domain.RentObject
 	obj, ok := m.store[userID][objectName]
 
@@ -61,10 +132,16 @@ func (m *MemoryObjectRepository) GetByName(userID int64, objectName string) (dom
 	return obj, nil
 }
 
-func (m *MemoryObjectRepository) GetAll(userID int64) ([]domain.RentObject, error) {
+func (m *MemoryObjectRepository) GetAll(ctx context.Context, userID int64) ([]domain.RentObject, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	var objects []domain.RentObject
 	for _, object := range m.store[userID] {
-		objects = append(objects, object)
+		objects = append(objects, clone(object))
 	}
 
 	sort.Slice(objects, func(i, j int) bool {
@@ -73,22 +150,111 @@ func (m *MemoryObjectRepository) GetAll(userID int64) ([]domain.RentObject, erro
 	return objects, nil
 }
 
-func (m *MemoryObjectRepository) AddRecord(userID int64, objectName string, record domain.Record) (int, error) {
-	object, err := m.GetByName(userID, objectName)
+func (m *MemoryObjectRepository) GetAllInPeriod(ctx context.Context, userID int64, period domain.Period) ([]domain.RentObject, error) {
+	objects, err := m.GetAll(ctx, userID)
 	if err != nil {
+		return nil, err
+	}
+	for i, object := range objects {
+		objects[i] = object.InPeriod(period)
+	}
+	return objects, nil
+}
+
+func (m *MemoryObjectRepository) FindObjects(ctx context.Context, userID int64, query repository.ObjectQuery) (repository.Page[domain.RentObject], error) {
+	objects, err := m.GetAll(ctx, userID)
+	if err != nil {
+		return repository.Page[domain.RentObject]{}, err
+	}
+	return repository.PageObjects(objects, query)
+}
+
+// sum calls fn with the stored objects of the query under the read lock, so
+// that records are summed up without copying them.
+func sum[T any](ctx context.Context, m *MemoryObjectRepository, userID int64, query repository.TotalsQuery, fn func([]domain.RentObject, repository.TotalsQuery) (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if query.ObjectName != "" {
+		object, err := m.get(userID, query.ObjectName)
+		if err != nil {
+			return zero, err
+		}
+		return fn([]domain.RentObject{object}, query)
+	}
+
+	objects := make([]domain.RentObject, 0, len(m.store[userID]))
+	for _, object := range m.store[userID] {
+		objects = append(objects, object)
+	}
+	return fn(objects, query)
+}
+
+func (m *MemoryObjectRepository) TotalsByObject(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.ObjectTotals, error) {
+	return sum(ctx, m, userID, query, repository.SumByObject)
+}
+
+func (m *MemoryObjectRepository) TotalsByPeriod(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.PeriodTotals, error) {
+	return sum(ctx, m, userID, query, repository.SumByPeriod)
+}
+
+func (m *MemoryObjectRepository) TotalsByCategory(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.CategoryTotal, error) {
+	return sum(ctx, m, userID, query, repository.SumByCategory)
+}
+
+func (m *MemoryObjectRepository) AddRecord(ctx context.Context, userID int64, objectName string, record domain.Record) (int, error) {
+	if err := ctx.Err(); err != nil {
 		return 0, err
 	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.change(entry{Op: opAddRecord, UserID: userID, ObjectName: objectName, Record: &record})
+}
+
+func (m *MemoryObjectRepository) addRecord(userID int64, objectName string, record domain.Record) (int, error) {
+	object, err := m.get(userID, objectName)
+	if err != nil {
+		return 0, err
+	}
+	object = clone(object)
 	index := object.AddRecord(record)
 	m.store[userID][objectName] = object
 	return index, nil
 }
 
-func (m *MemoryObjectRepository) DeleteRecord(userID int64, objectName string, recordIndex int) error {
-	object, err := m.GetByName(userID, objectName)
+func (m *MemoryObjectRepository) AddRecords(ctx context.Context, userID int64, objectName string, records []domain.Record) error {
+	return m.write(ctx, entry{Op: opAddRecords, UserID: userID, ObjectName: objectName, Records: records})
+}
+
+func (m *MemoryObjectRepository) addRecords(userID int64, objectName string, records []domain.Record) error {
+	object, err := m.get(userID, objectName)
+	if err != nil {
+		return err
+	}
+	object = clone(object)
+	for _, record := range records {
+		object.AddRecord(record)
+	}
+	m.store[userID][objectName] = object
+	return nil
+}
+
+func (m *MemoryObjectRepository) DeleteRecord(ctx context.Context, userID int64, objectName string, recordIndex int) error {
+	return m.write(ctx, entry{Op: opDeleteRecord, UserID: userID, ObjectName: objectName, Index: recordIndex})
+}
+
+func (m *MemoryObjectRepository) deleteRecord(userID int64, objectName string, recordIndex int) error {
+	object, err := m.get(userID, objectName)
 	if err != nil {
 		return err
 	}
 
+	object = clone(object)
 	err = object.DeleteRecord(recordIndex)
 	if err != nil {
 		return err
@@ -97,11 +263,16 @@ func (m *MemoryObjectRepository) DeleteRecord(userID int64, objectName string, r
 	return nil
 }
 
-func (m *MemoryObjectRepository) UpdateRecord(userID int64, objectName string, recordIndex int, input domain.UpdateRecordInput) error {
-	object, err := m.GetByName(userID, objectName)
+func (m *MemoryObjectRepository) UpdateRecord(ctx context.Context, userID int64, objectName string, recordIndex int, input domain.UpdateRecordInput) error {
+	return m.write(ctx, entry{Op: opUpdateRecord, UserID: userID, ObjectName: objectName, Index: recordIndex, RecordInput: &input})
+}
+
+func (m *MemoryObjectRepository) updateRecord(userID int64, objectName string, recordIndex int, input domain.UpdateRecordInput) error {
+	object, err := m.get(userID, objectName)
 	if err != nil {
 		return err
 	}
+	object = clone(object)
 	err = object.UpdateRecord(recordIndex, input)
 	if err != nil {
 		return err
@@ -110,9 +281,9 @@ func (m *MemoryObjectRepository) UpdateRecord(userID int64, objectName string, r
 	return nil
 }
 
-func (m *MemoryObjectRepository) GetRecordByIndex(userID int64, objectName string, recordIndex int) (domain.Record, error) {
+func (m *MemoryObjectRepository) GetRecordByIndex(ctx context.Context, userID int64, objectName string, recordIndex int) (domain.Record, error) {
 	var zero domain.Record
-	object, err := m.GetByName(userID, objectName)
+	object, err := m.GetByName(ctx, userID, objectName)
 	if err != nil {
 		return zero, err
 	}
@@ -121,10 +292,73 @@ func (m *MemoryObjectRepository) GetRecordByIndex(userID int64, objectName strin
 
 }
 
-func (m *MemoryObjectRepository) GetAllRecords(userID int64, objectName string) ([]domain.Record, error) {
-	object, err := m.GetByName(userID, objectName)
+func (m *MemoryObjectRepository) GetAllRecords(ctx context.Context, userID int64, objectName string) ([]domain.Record, error) {
+	object, err := m.GetByName(ctx, userID, objectName)
 	if err != nil {
 		return nil, err
 	}
 	return object.GetAllRecords(), nil
 }
+
+func (m *MemoryObjectRepository) FindRecords(ctx context.Context, userID int64, objectName string, query repository.RecordQuery) (repository.Page[repository.RecordEntry], error) {
+	object, err := m.GetByName(ctx, userID, objectName)
+	if err != nil {
+		return repository.Page[repository.RecordEntry]{}, err
+	}
+	return repository.PageRecords(object.Records, query)
+}
+
+func (m *MemoryObjectRepository) AddMatchRule(ctx context.Context, userID int64, rule domain.MatchRule) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.change(entry{Op: opAddMatchRule, UserID: userID, Rule: &rule})
+}
+
+func (m *MemoryObjectRepository) addMatchRule(userID int64, rule domain.MatchRule) int {
+	m.rules[userID] = append(m.rules[userID], rule)
+	return len(m.rules[userID]) - 1
+}
+
+func (m *MemoryObjectRepository) DeleteMatchRule(ctx context.Context, userID int64, ruleIndex int) error {
+	return m.write(ctx, entry{Op: opDeleteMatchRule, UserID: userID, Index: ruleIndex})
+}
+
+func (m *MemoryObjectRepository) deleteMatchRule(userID int64, ruleIndex int) error {
+	rules := m.rules[userID]
+	if ruleIndex < 0 || ruleIndex >= len(rules) {
+		return domain.MatchRuleNotFoundError
+	}
+	m.rules[userID] = slices.Delete(slices.Clone(rules), ruleIndex, ruleIndex+1)
+	return nil
+}
+
+func (m *MemoryObjectRepository) GetMatchRules(ctx context.Context, userID int64) ([]domain.MatchRule, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return append([]domain.MatchRule{}, m.rules[userID]...), nil
+}
+
+func (m *MemoryObjectRepository) Count(ctx context.Context) (repository.Counts, error) {
+	if err := ctx.Err(); err != nil {
+		return repository.Counts{}, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var counts repository.Counts
+	for _, objects := range m.store {
+		for _, object := range objects {
+			counts.Objects++
+			counts.Records += int64(len(object.Records))
+		}
+	}
+	return counts, nil
+}
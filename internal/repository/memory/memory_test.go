@@ -1,14 +1,20 @@
 package memory_test
 
 import (
+	"context"
 	"rental-server/internal/domain"
 	"rental-server/internal/repository"
 	"rental-server/internal/repository/memory"
+	"rental-server/internal/repository/repositorytest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+var ctx = context.Background()
+
 var dummyRecord = domain.Record{}
 var dummyObject = domain.RentObject{}
 var dummyUserID int64 = 1
@@ -19,9 +25,9 @@ func TestAdd(t *testing.T) {
 
 		object := domain.NewRentObject("Name", "Description", 1000)
 
-		_ = rep.Add(dummyUserID, object)
+		_ = rep.Add(ctx, dummyUserID, object)
 
-		objects, _ := rep.GetAll(dummyUserID)
+		objects, _ := rep.GetAll(ctx, dummyUserID)
 
 		assert.Contains(t, objects, object)
 	})
@@ -36,18 +42,18 @@ func TestDelete(t *testing.T) {
 			},
 		}
 		rep := memory.NewMemoryObjectRepository(store)
-		objects, _ := rep.GetAll(dummyUserID)
+		objects, _ := rep.GetAll(ctx, dummyUserID)
 		assert.NotEmpty(t, objects)
 
-		rep.Delete(dummyUserID, dummyObject.Name)
+		rep.Delete(ctx, dummyUserID, dummyObject.Name)
 
-		objects, _ = rep.GetAll(dummyUserID)
+		objects, _ = rep.GetAll(ctx, dummyUserID)
 		assert.Empty(t, objects)
 	})
 
 	t.Run("If object doesnt exist should return error", func(t *testing.T) {
 		rep := memory.NewMemoryObjectRepository(nil)
-		err := rep.Delete(dummyUserID, "")
+		err := rep.Delete(ctx, dummyUserID, "")
 		assert.ErrorIs(t, err, repository.ObjectNotFoundError)
 	})
 }
@@ -67,9 +73,9 @@ func TestUpdate(t *testing.T) {
 
 		update := domain.NewUpdateRentObjectInput(newName, newDescription, newArea)
 
-		rep.Update(dummyUserID, dummyObject.Name, update)
+		rep.Update(ctx, dummyUserID, dummyObject.Name, update)
 
-		got, _ := rep.GetByName(dummyUserID, dummyObject.Name)
+		got, _ := rep.GetByName(ctx, dummyUserID, newName)
 
 		want := domain.RentObject{
 			Name:        newName,
@@ -83,7 +89,7 @@ func TestUpdate(t *testing.T) {
 	t.Run("Should return an error if object doesnt exist", func(t *testing.T) {
 		rep := memory.NewMemoryObjectRepository(nil)
 		updateInput := domain.UpdateRentObjectInput{}
-		err := rep.Update(dummyUserID, "", updateInput)
+		err := rep.Update(ctx, dummyUserID, "", updateInput)
 
 		assert.ErrorIs(t, err, repository.ObjectNotFoundError)
 	})
@@ -104,7 +110,7 @@ func TestMemoryRepositoryRecords(t *testing.T) {
 
 			rep := memory.NewMemoryObjectRepository(store)
 
-			records, _ := rep.GetAllRecords(dummyUserID, dummyObject.Name)
+			records, _ := rep.GetAllRecords(ctx, dummyUserID, dummyObject.Name)
 
 			assert.Contains(t, records, record)
 		})
@@ -113,7 +119,7 @@ func TestMemoryRepositoryRecords(t *testing.T) {
 
 			rep := memory.NewMemoryObjectRepository(nil)
 			record := domain.Record{}
-			_, err := rep.AddRecord(dummyUserID, "", record)
+			_, err := rep.AddRecord(ctx, dummyUserID, "", record)
 
 			assert.ErrorIs(t, err, repository.ObjectNotFoundError)
 		})
@@ -129,25 +135,25 @@ func TestMemoryRepositoryRecords(t *testing.T) {
 			}
 			rep := memory.NewMemoryObjectRepository(store)
 
-			rep.DeleteRecord(dummyUserID, dummyObject.Name, recordIndex)
+			rep.DeleteRecord(ctx, dummyUserID, dummyObject.Name, recordIndex)
 
-			records, _ := rep.GetAllRecords(dummyUserID, dummyObject.Name)
+			records, _ := rep.GetAllRecords(ctx, dummyUserID, dummyObject.Name)
 			assert.Empty(t, records)
 		})
 
 		t.Run("If object doesnt exists should return ObjectNotFoundError", func(t *testing.T) {
 			rep := memory.NewMemoryObjectRepository(nil)
 			recordIndex := 0
-			err := rep.DeleteRecord(dummyUserID, dummyObject.Name, recordIndex)
+			err := rep.DeleteRecord(ctx, dummyUserID, dummyObject.Name, recordIndex)
 
 			assert.ErrorIs(t, err, repository.ObjectNotFoundError)
 		})
 		t.Run("If record doesnt exists should return RecordNotFoundError", func(t *testing.T) {
 			rep := memory.NewMemoryObjectRepository(nil)
-			_ = rep.Add(dummyUserID, dummyObject)
+			_ = rep.Add(ctx, dummyUserID, dummyObject)
 			recordIndex := 0
 
-			err := rep.DeleteRecord(dummyUserID, dummyObject.Name, recordIndex)
+			err := rep.DeleteRecord(ctx, dummyUserID, dummyObject.Name, recordIndex)
 
 			assert.ErrorIs(t, err, domain.RecordNotFoundError)
 		})
@@ -168,9 +174,9 @@ func TestMemoryRepositoryRecords(t *testing.T) {
 				Rent: &newRent,
 			}
 
-			rep.UpdateRecord(dummyUserID, dummyObject.Name, recordIndex, update)
+			rep.UpdateRecord(ctx, dummyUserID, dummyObject.Name, recordIndex, update)
 
-			got, _ := rep.GetRecordByIndex(dummyUserID, dummyObject.Name, recordIndex)
+			got, _ := rep.GetRecordByIndex(ctx, dummyUserID, dummyObject.Name, recordIndex)
 
 			want := domain.Record{
 				Rent: newRent,
@@ -183,20 +189,121 @@ func TestMemoryRepositoryRecords(t *testing.T) {
 			objectID, recordIndex := "", 0
 			updateInput := domain.UpdateRecordInput{}
 
-			err := rep.UpdateRecord(dummyUserID, objectID, recordIndex, updateInput)
+			err := rep.UpdateRecord(ctx, dummyUserID, objectID, recordIndex, updateInput)
 
 			assert.ErrorIs(t, err, repository.ObjectNotFoundError)
 		})
 
 		t.Run("If record doesnt exists should return RecordNotFoundError", func(t *testing.T) {
 			rep := memory.NewMemoryObjectRepository(nil)
-			_ = rep.Add(dummyUserID, dummyObject)
+			_ = rep.Add(ctx, dummyUserID, dummyObject)
 			recordIndex := 0
 			updateInput := domain.UpdateRecordInput{}
 
-			err := rep.UpdateRecord(dummyUserID, dummyObject.Name, recordIndex, updateInput)
+			err := rep.UpdateRecord(ctx, dummyUserID, dummyObject.Name, recordIndex, updateInput)
 
 			assert.ErrorIs(t, err, domain.RecordNotFoundError)
 		})
 	})
 }
+
+func TestGetByNameInPeriod(t *testing.T) {
+	january := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	february := time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+	store := memory.MemoryStore{
+		dummyUserID: {
+			dummyObject.Name: {
+				Records: []domain.Record{{Date: january}, {Date: february}},
+			},
+		},
+	}
+	rep := memory.NewMemoryObjectRepository(store)
+
+	object, err := rep.GetByNameInPeriod(ctx, dummyUserID, dummyObject.Name, domain.Period{From: &february})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.Record{{Date: february}}, object.Records)
+}
+
+func TestMatchRules(t *testing.T) {
+	first := domain.MatchRule{ObjectName: "first", Field: "rent", Counterparty: "7701234567"}
+	second := domain.MatchRule{ObjectName: "second", Field: "security", Purpose: "охрана"}
+
+	t.Run("Happy path. Rules are kept in order", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_, _ = rep.AddMatchRule(ctx, dummyUserID, first)
+		index, err := rep.AddMatchRule(ctx, dummyUserID, second)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, index)
+
+		rules, _ := rep.GetMatchRules(ctx, dummyUserID)
+		assert.Equal(t, []domain.MatchRule{first, second}, rules)
+
+		otherRules, _ := rep.GetMatchRules(ctx, dummyUserID+1)
+		assert.Empty(t, otherRules)
+	})
+
+	t.Run("Happy path. Delete rule", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_, _ = rep.AddMatchRule(ctx, dummyUserID, first)
+		_, _ = rep.AddMatchRule(ctx, dummyUserID, second)
+
+		assert.NoError(t, rep.DeleteMatchRule(ctx, dummyUserID, 0))
+
+		rules, _ := rep.GetMatchRules(ctx, dummyUserID)
+		assert.Equal(t, []domain.MatchRule{second}, rules)
+	})
+
+	t.Run("If rule doesnt exist should return MatchRuleNotFoundError", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+
+		err := rep.DeleteMatchRule(ctx, dummyUserID, 0)
+
+		assert.ErrorIs(t, err, domain.MatchRuleNotFoundError)
+	})
+}
+
+func TestConcurrentUse(t *testing.T) {
+	t.Run("Happy path. Concurrent record changes are all kept", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_ = rep.Add(ctx, dummyUserID, domain.NewRentObject("Name", "Description", 1000))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, _ = rep.AddRecord(ctx, dummyUserID, "Name", domain.Record{Date: time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC)})
+				_, _ = rep.GetAll(ctx, dummyUserID)
+			}(i)
+		}
+		wg.Wait()
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, "Name")
+		assert.Len(t, records, 50)
+	})
+
+	t.Run("Objects that are read dont share records with the store", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		object := domain.NewRentObject("Name", "Description", 1000)
+		object.Records = []domain.Record{{Rent: 100}}
+		_ = rep.Add(ctx, dummyUserID, object)
+		object.Records[0].Rent = 200
+
+		read, _ := rep.GetByName(ctx, dummyUserID, "Name")
+		assert.Equal(t, domain.RUB(100), read.Records[0].Rent)
+		read.Records[0].Rent = 300
+
+		objects, _ := rep.GetAll(ctx, dummyUserID)
+		objects[0].Records[0].Rent = 400
+
+		record, _ := rep.GetRecordByIndex(ctx, dummyUserID, "Name", 0)
+		assert.Equal(t, domain.RUB(100), record.Rent)
+	})
+}
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.RentObjectRepository {
+		return memory.NewMemoryObjectRepository(nil)
+	})
+}
@@ -0,0 +1,403 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"rental-server/internal/domain"
+	"sync"
+	"time"
+)
+
+const (
+	snapshotFile = "snapshot.json"
+	walFile      = "wal.jsonl"
+
+	snapshotVersion = 1
+)
+
+type Options struct {
+	// SnapshotInterval is how often the store is written to the snapshot
+	// file, which empties the write-ahead log. Zero disables periodic
+	// snapshots, and the log then grows until Snapshot or Close.
+	SnapshotInterval time.Duration
+}
+
+const (
+	opAdd             = "add"
+	opDelete          = "delete"
+	opUpdate          = "update"
+	opAddRecord       = "add_record"
+	opAddRecords      = "add_records"
+	opDeleteRecord    = "delete_record"
+	opUpdateRecord    = "update_record"
+	opAddMatchRule    = "add_match_rule"
+	opDeleteMatchRule = "delete_match_rule"
+)
+
+// entry is a change of the store, as it is kept in the write-ahead log.
+// Index is the record or match rule index of the change.
+type entry struct {
+	Seq         int64                         `json:"seq"`
+	Op          string                        `json:"op"`
+	UserID      int64                         `json:"user_id"`
+	ObjectName  string                        `json:"object_name,omitempty"`
+	Object      *domain.RentObject            `json:"object,omitempty"`
+	ObjectInput *domain.UpdateRentObjectInput `json:"object_input,omitempty"`
+	Record      *domain.Record                `json:"record,omitempty"`
+	Records     []domain.Record               `json:"records,omitempty"`
+	RecordInput *domain.UpdateRecordInput     `json:"record_input,omitempty"`
+	Rule        *domain.MatchRule             `json:"rule,omitempty"`
+	Index       int                           `json:"index,omitempty"`
+}
+
+// apply changes the store by the entry. It returns the index of an added
+// record or match rule.
+func (m *MemoryObjectRepository) apply(e entry) (int, error) {
+	switch e.Op {
+	case opAdd:
+		return 0, m.add(e.UserID, *e.Object)
+	case opDelete:
+		return 0, m.delete(e.UserID, e.ObjectName)
+	case opUpdate:
+		return 0, m.update(e.UserID, e.ObjectName, *e.ObjectInput)
+	case opAddRecord:
+		return m.addRecord(e.UserID, e.ObjectName, *e.Record)
+	case opAddRecords:
+		return 0, m.addRecords(e.UserID, e.ObjectName, e.Records)
+	case opDeleteRecord:
+		return 0, m.deleteRecord(e.UserID, e.ObjectName, e.Index)
+	case opUpdateRecord:
+		return 0, m.updateRecord(e.UserID, e.ObjectName, e.Index, *e.RecordInput)
+	case opAddMatchRule:
+		return m.addMatchRule(e.UserID, *e.Rule), nil
+	case opDeleteMatchRule:
+		return 0, m.deleteMatchRule(e.UserID, e.Index)
+	default:
+		return 0, fmt.Errorf("Unknown write-ahead log operation %q", e.Op)
+	}
+}
+
+// change applies the change and logs it once it succeeds. A change that
+// cannot be logged is taken back, so that it is neither seen nor written to
+// the next snapshot. It must be called under the write lock.
+func (m *MemoryObjectRepository) change(e entry) (int, error) {
+	var before undo
+	if m.wal != nil {
+		before = m.saveUndo(e)
+	}
+	index, err := m.apply(e)
+	if err != nil {
+		return 0, err
+	}
+	if err := m.log(e); err != nil {
+		m.restore(before)
+		return 0, err
+	}
+	return index, nil
+}
+
+// undo keeps what a change may replace. Changes replace objects and rules
+// instead of changing them in place, so keeping them needs no copies.
+type undo struct {
+	userID  int64
+	hadUser bool
+	// objects are the objects of the names the change touches, nil for
+	// missing ones.
+	objects  map[string]*domain.RentObject
+	rules    []domain.MatchRule
+	hadRules bool
+}
+
+func (m *MemoryObjectRepository) saveUndo(e entry) undo {
+	u := undo{userID: e.UserID, objects: map[string]*domain.RentObject{}}
+	_, u.hadUser = m.store[e.UserID]
+	u.rules, u.hadRules = m.rules[e.UserID]
+
+	names := []string{e.ObjectName}
+	if e.Object != nil {
+		names = append(names, e.Object.Name)
+	}
+	if e.ObjectInput != nil && e.ObjectInput.Name != nil {
+		names = append(names, *e.ObjectInput.Name)
+	}
+	for _, name := range names {
+		if object, ok := m.store[e.UserID][name]; ok {
+			u.objects[name] = &object
+		} else {
+			u.objects[name] = nil
+		}
+	}
+	return u
+}
+
+func (m *MemoryObjectRepository) restore(u undo) {
+	if !u.hadUser {
+		delete(m.store, u.userID)
+	} else {
+		for name, object := range u.objects {
+			if object == nil {
+				delete(m.store[u.userID], name)
+			} else {
+				m.store[u.userID][name] = *object
+			}
+		}
+	}
+	if u.hadRules {
+		m.rules[u.userID] = u.rules
+	} else {
+		delete(m.rules, u.userID)
+	}
+}
+
+// wal keeps the changes made since the last snapshot of a persistent
+// repository.
+type wal struct {
+	dir  string
+	file *os.File
+	// seq is the sequence number of the last change.
+	seq int64
+
+	// snapshotMu serializes snapshots, which only hold the read lock of
+	// the repository.
+	snapshotMu sync.Mutex
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+type snapshot struct {
+	Version int                          `json:"version"`
+	Seq     int64                        `json:"seq"`
+	Objects MemoryStore                  `json:"objects"`
+	Rules   map[int64][]domain.MatchRule `json:"rules"`
+}
+
+// OpenMemoryObjectRepository keeps the store in the directory, creating it
+// when missing. The store is loaded from the last snapshot and the changes
+// logged after it. Each change is logged and synced to disk before it is
+// reported done, so that the store survives a crash. Close takes a final
+// snapshot.
+func OpenMemoryObjectRepository(dir string, options Options) (*MemoryObjectRepository, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	m := NewMemoryObjectRepository(nil)
+	seq, err := m.load(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, walFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	m.wal = &wal{dir: dir, file: file, seq: seq}
+
+	// The replayed log, with a torn last line of a crash, is not needed
+	// any more.
+	if err := m.Snapshot(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if options.SnapshotInterval > 0 {
+		m.wal.stop = make(chan struct{})
+		m.wal.done = make(chan struct{})
+		go m.snapshotEvery(options.SnapshotInterval)
+	}
+	return m, nil
+}
+
+// load reads the snapshot and replays the log of the directory, and returns
+// the sequence number of the last change.
+func (m *MemoryObjectRepository) load(dir string) (int64, error) {
+	var seq int64
+	data, err := os.ReadFile(filepath.Join(dir, snapshotFile))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return 0, err
+	}
+	if err == nil {
+		var s snapshot
+		if err := json.Unmarshal(data, &s); err != nil {
+			return 0, fmt.Errorf("Invalid snapshot %s: %w", snapshotFile, err)
+		}
+		if s.Version != snapshotVersion {
+			return 0, fmt.Errorf("Unsupported snapshot version %d, want %d", s.Version, snapshotVersion)
+		}
+		if s.Objects != nil {
+			m.store = s.Objects
+		}
+		if s.Rules != nil {
+			m.rules = s.Rules
+		}
+		seq = s.Seq
+	}
+
+	file, err := os.Open(filepath.Join(dir, walFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return seq, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20)
+	for line := 1; scanner.Scan(); line++ {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// Only the last line may be torn by a crash while it was
+			// written, and that change was never reported done.
+			if scanner.Scan() {
+				return 0, fmt.Errorf("Invalid write-ahead log %s at line %d: %w", walFile, line, err)
+			}
+			break
+		}
+		// Changes before the snapshot are left when a crash happens
+		// between writing the snapshot and emptying the log.
+		if e.Seq <= seq {
+			continue
+		}
+		if _, err := m.apply(e); err != nil {
+			return 0, fmt.Errorf("Invalid write-ahead log %s at line %d: %w", walFile, line, err)
+		}
+		seq = e.Seq
+	}
+	return seq, scanner.Err()
+}
+
+// log appends a change that has been applied to the write-ahead log. It is
+// called with the write lock held.
+func (m *MemoryObjectRepository) log(e entry) error {
+	if m.wal == nil {
+		return nil
+	}
+
+	e.Seq = m.wal.seq + 1
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	info, err := m.wal.file.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = m.wal.file.Write(append(data, '\n'))
+	if err == nil {
+		err = m.wal.file.Sync()
+	}
+	if err != nil {
+		// The change is taken back, so a part of its entry that was written
+		// must not be replayed.
+		m.wal.file.Truncate(info.Size())
+		return err
+	}
+	m.wal.seq = e.Seq
+	return nil
+}
+
+// Snapshot writes the store to the snapshot file and empties the
+// write-ahead log. It does nothing when the repository is not persistent.
+func (m *MemoryObjectRepository) Snapshot() error {
+	if m.wal == nil {
+		return nil
+	}
+	m.wal.snapshotMu.Lock()
+	defer m.wal.snapshotMu.Unlock()
+
+	// The read lock keeps changes, and so the log, still while readers go
+	// on.
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	data, err := json.Marshal(snapshot{
+		Version: snapshotVersion,
+		Seq:     m.wal.seq,
+		Objects: m.store,
+		Rules:   m.rules,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The snapshot is replaced at once by renaming, so that a crash leaves
+	// either the old or the new one.
+	path := filepath.Join(m.wal.dir, snapshotFile)
+	if err := writeFileSync(path+".tmp", data); err != nil {
+		return err
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return err
+	}
+	if err := syncDir(m.wal.dir); err != nil {
+		return err
+	}
+
+	return m.wal.file.Truncate(0)
+}
+
+func writeFileSync(path string, data []byte) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (m *MemoryObjectRepository) snapshotEvery(interval time.Duration) {
+	defer close(m.wal.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := m.Snapshot(); err != nil {
+				// The log still has the changes, so the next snapshot
+				// can catch up.
+				slog.Error("memory repository snapshot", "error", err)
+			}
+		case <-m.wal.stop:
+			return
+		}
+	}
+}
+
+// Close stops periodic snapshots and takes a final one. The repository must
+// not be used after Close.
+func (m *MemoryObjectRepository) Close() error {
+	if m.wal == nil {
+		return nil
+	}
+	if m.wal.stop != nil {
+		close(m.wal.stop)
+		<-m.wal.done
+	}
+
+	err := m.Snapshot()
+	return errors.Join(err, m.wal.file.Close())
+}
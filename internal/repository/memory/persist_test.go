@@ -0,0 +1,137 @@
+package memory_test
+
+import (
+	"os"
+	"path/filepath"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/memory"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPersistence(t *testing.T) {
+	record := domain.Record{Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 1000}
+	rule := domain.MatchRule{ObjectName: "Name", Field: "rent", Counterparty: "7701234567"}
+
+	fill := func(t *testing.T, rep *memory.MemoryObjectRepository) {
+		require.NoError(t, rep.Add(ctx, dummyUserID, domain.NewRentObject("Name", "Description", 1000)))
+		require.NoError(t, rep.Add(ctx, dummyUserID, domain.NewRentObject("Deleted", "", 0)))
+		_, err := rep.AddRecord(ctx, dummyUserID, "Name", record)
+		require.NoError(t, err)
+		require.NoError(t, rep.Delete(ctx, dummyUserID, "Deleted"))
+		_, err = rep.AddMatchRule(ctx, dummyUserID, rule)
+		require.NoError(t, err)
+	}
+	check := func(t *testing.T, rep *memory.MemoryObjectRepository) {
+		objects, _ := rep.GetAll(ctx, dummyUserID)
+		require.Len(t, objects, 1)
+		assert.Equal(t, "Name", objects[0].Name)
+		assert.Equal(t, []domain.Record{record}, objects[0].Records)
+
+		rules, _ := rep.GetMatchRules(ctx, dummyUserID)
+		assert.Equal(t, []domain.MatchRule{rule}, rules)
+	}
+
+	t.Run("Happy path. Store is restored after Close", func(t *testing.T) {
+		dir := t.TempDir()
+		rep, err := memory.OpenMemoryObjectRepository(dir, memory.Options{})
+		require.NoError(t, err)
+		fill(t, rep)
+		require.NoError(t, rep.Close())
+
+		rep, err = memory.OpenMemoryObjectRepository(dir, memory.Options{})
+		require.NoError(t, err)
+		defer rep.Close()
+		check(t, rep)
+	})
+
+	t.Run("Happy path. Changes are replayed from the log without Close", func(t *testing.T) {
+		dir := t.TempDir()
+		rep, err := memory.OpenMemoryObjectRepository(dir, memory.Options{})
+		require.NoError(t, err)
+		fill(t, rep)
+
+		reopened, err := memory.OpenMemoryObjectRepository(dir, memory.Options{})
+		require.NoError(t, err)
+		defer reopened.Close()
+		check(t, reopened)
+	})
+
+	t.Run("Torn last line of the log is ignored", func(t *testing.T) {
+		dir := t.TempDir()
+		rep, err := memory.OpenMemoryObjectRepository(dir, memory.Options{})
+		require.NoError(t, err)
+		fill(t, rep)
+
+		file, err := os.OpenFile(filepath.Join(dir, "wal.jsonl"), os.O_WRONLY|os.O_APPEND, 0)
+		require.NoError(t, err)
+		_, err = file.WriteString(`{"seq":100,"op":"del`)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+
+		reopened, err := memory.OpenMemoryObjectRepository(dir, memory.Options{})
+		require.NoError(t, err)
+		defer reopened.Close()
+		check(t, reopened)
+	})
+
+	t.Run("Happy path. Periodic snapshots empty the log", func(t *testing.T) {
+		dir := t.TempDir()
+		rep, err := memory.OpenMemoryObjectRepository(dir, memory.Options{SnapshotInterval: 10 * time.Millisecond})
+		require.NoError(t, err)
+		defer rep.Close()
+		fill(t, rep)
+
+		assert.Eventually(t, func() bool {
+			info, err := os.Stat(filepath.Join(dir, "wal.jsonl"))
+			return err == nil && info.Size() == 0
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Failed changes are not logged", func(t *testing.T) {
+		dir := t.TempDir()
+		rep, err := memory.OpenMemoryObjectRepository(dir, memory.Options{})
+		require.NoError(t, err)
+
+		err = rep.Delete(ctx, dummyUserID, "Name")
+		assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+
+		info, err := os.Stat(filepath.Join(dir, "wal.jsonl"))
+		require.NoError(t, err)
+		assert.Zero(t, info.Size())
+		require.NoError(t, rep.Close())
+	})
+
+	t.Run("Changes that could not be logged are taken back", func(t *testing.T) {
+		rep, err := memory.OpenMemoryObjectRepository(t.TempDir(), memory.Options{})
+		require.NoError(t, err)
+		require.NoError(t, rep.Add(ctx, dummyUserID, domain.NewRentObject("Name", "Description", 1000)))
+		// Close closes the log, so that writing to it fails.
+		require.NoError(t, rep.Close())
+
+		assert.Error(t, rep.Add(ctx, dummyUserID+1, domain.NewRentObject("Other", "", 0)))
+		_, err = rep.AddRecord(ctx, dummyUserID, "Name", record)
+		assert.Error(t, err)
+		newName := "Renamed"
+		assert.Error(t, rep.Update(ctx, dummyUserID, "Name", domain.UpdateRentObjectInput{Name: &newName}))
+		assert.Error(t, rep.Delete(ctx, dummyUserID, "Name"))
+		_, err = rep.AddMatchRule(ctx, dummyUserID, rule)
+		assert.Error(t, err)
+
+		_, err = rep.GetByName(ctx, dummyUserID+1, "Other")
+		assert.Equal(t, repository.ObjectNotFoundError, err)
+		_, err = rep.GetByName(ctx, dummyUserID, "Renamed")
+		assert.Equal(t, repository.ObjectNotFoundError, err)
+		object, err := rep.GetByName(ctx, dummyUserID, "Name")
+		require.NoError(t, err)
+		assert.Empty(t, object.Records)
+		rules, _ := rep.GetMatchRules(ctx, dummyUserID)
+		assert.Empty(t, rules)
+		counts, _ := rep.Count(ctx)
+		assert.Equal(t, repository.Counts{Objects: 1}, counts)
+	})
+}
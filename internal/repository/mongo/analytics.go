@@ -0,0 +1,161 @@
+package mongorep
+
+import (
+	"context"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// totals sums up records into the fields of repository.Totals.
+var totals = bson.D{
+	{Key: "income", Value: bson.D{{Key: "$sum", Value: "$rent"}}},
+	{Key: "expenses", Value: bson.D{{Key: "$sum", Value: expensesExpression("$")}}},
+	{Key: "records", Value: bson.D{{Key: "$sum", Value: 1}}},
+}
+
+type totalsDocument struct {
+	Income   float64 `bson:"income"`
+	Expenses float64 `bson:"expenses"`
+	Records  int     `bson:"records"`
+}
+
+func (d totalsDocument) totals() repository.Totals {
+	return repository.Totals{
+		Income:   domain.RUB(d.Income),
+		Expenses: domain.RUB(d.Expenses),
+		Profit:   domain.RUB(d.Income - d.Expenses),
+		Records:  d.Records,
+	}
+}
+
+// recordsMatch matches the records of the query, by the indexes of user and
+// of object and date.
+func (r *MongoDBRepository) recordsMatch(ctx context.Context, userID int64, query repository.TotalsQuery) (bson.D, error) {
+	match := bson.D{{Key: "user_id", Value: userID}}
+	if query.ObjectName != "" {
+		objectID, err := r.objectID(ctx, userID, query.ObjectName)
+		if err != nil {
+			return nil, err
+		}
+		match = bson.D{{Key: "object_id", Value: objectID}}
+	}
+	return append(match, rangeFilter("date", dateBounds(query.From, query.To))...), nil
+}
+
+func (r *MongoDBRepository) TotalsByObject(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.ObjectTotals, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	match := bson.D{{Key: "user_id", Value: userID}}
+	if query.ObjectName != "" {
+		match = objectFilter(userID, query.ObjectName)
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "records"},
+			{Key: "let", Value: bson.D{{Key: "id", Value: "$_id"}}},
+			{Key: "pipeline", Value: mongo.Pipeline{
+				{{Key: "$match", Value: append(
+					bson.D{{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$object_id", "$$id"}}}}},
+					rangeFilter("date", dateBounds(query.From, query.To))...,
+				)}},
+				{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: nil}}, totals...)}},
+			}},
+			{Key: "as", Value: "totals"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "rent_object.name", Value: 1}}}},
+	}
+
+	var results []struct {
+		Object objectFields     `bson:"rent_object"`
+		Totals []totalsDocument `bson:"totals"`
+	}
+	if err := r.aggregate(ctx, r.objects(), pipeline, &results); err != nil {
+		return nil, err
+	}
+	if query.ObjectName != "" && len(results) == 0 {
+		return nil, repository.ObjectNotFoundError
+	}
+
+	objects := []repository.ObjectTotals{}
+	for _, res := range results {
+		object := repository.ObjectTotals{Name: res.Object.Name}
+		if len(res.Totals) > 0 {
+			object.Totals = res.Totals[0].totals()
+		}
+		objects = append(objects, object)
+	}
+	return objects, nil
+}
+
+func (r *MongoDBRepository) TotalsByPeriod(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.PeriodTotals, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+	match, err := r.recordsMatch(ctx, userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	start := bson.D{{Key: "$dateTrunc", Value: bson.D{
+		{Key: "date", Value: "$date"},
+		{Key: "unit", Value: string(query.Granularity)},
+		{Key: "timezone", Value: "UTC"},
+	}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: append(bson.D{{Key: "_id", Value: start}}, totals...)}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	var results []struct {
+		Start          time.Time `bson:"_id"`
+		totalsDocument `bson:",inline"`
+	}
+	if err := r.aggregate(ctx, r.records(), pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	periods := []repository.PeriodTotals{}
+	for _, res := range results {
+		periods = append(periods, repository.PeriodTotals{Start: res.Start.UTC(), Totals: res.totals()})
+	}
+	return periods, nil
+}
+
+func (r *MongoDBRepository) TotalsByCategory(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.CategoryTotal, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+	match, err := r.recordsMatch(ctx, userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := bson.D{{Key: "_id", Value: nil}, {Key: "rent", Value: bson.D{{Key: "$sum", Value: "$rent"}}}}
+	for _, field := range expenseFields {
+		sums = append(sums, bson.E{Key: field, Value: bson.D{{Key: "$sum", Value: "$" + field}}})
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: sums}},
+	}
+
+	// The sums are decoded as a record, whose fields they are named after.
+	var results []domain.Record
+	if err := r.aggregate(ctx, r.records(), pipeline, &results); err != nil {
+		return nil, err
+	}
+
+	var sum domain.Record
+	if len(results) > 0 {
+		sum = results[0]
+	}
+	return repository.NewCategoryTotals(repository.CategoryAmounts(sum)), nil
+}
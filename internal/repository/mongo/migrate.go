@@ -0,0 +1,292 @@
+package mongorep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rental-server/internal/domain"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Migrations are applied in the order of their versions, and the versions
+// applied are kept in the schema_migrations collection. MongoDB changes
+// several documents without a transaction, so a migration may stop halfway
+// and must be safe to run again.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, db *mongo.Database) error
+}
+
+var migrations = []migration{
+	{1, "records_collection", moveEmbeddedRecords},
+	{2, "unique_object_names", uniqueObjectNames},
+	{3, "unique_match_rules", uniqueMatchRules},
+	{4, "delete_orphan_records", deleteOrphanRecords},
+	{5, "unique_record_positions", uniqueRecordPositions},
+}
+
+// Codes of server errors.
+const (
+	namespaceNotFound = 26
+	indexNotFound     = 27
+)
+
+// migrationLease is how long a server may migrate before others take over,
+// in case it died.
+const migrationLease = 10 * time.Minute
+
+type appliedMigration struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+// Migrate applies the migrations that the database lacks and returns the
+// schema version. Servers started together wait for each other.
+func Migrate(ctx context.Context, db *mongo.Database) (int, error) {
+	token, err := lockMigrations(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	defer unlockMigrations(ctx, db, token)
+
+	coll := db.Collection("schema_migrations")
+	var last appliedMigration
+	err = coll.FindOne(ctx, bson.D{}, options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}})).Decode(&last)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return 0, err
+	}
+
+	current := last.Version
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := m.up(ctx, db); err != nil {
+			return current, fmt.Errorf("migration %d_%s: %w", m.version, m.name, err)
+		}
+		if _, err := coll.InsertOne(ctx, appliedMigration{m.version, m.name, time.Now()}); err != nil {
+			return current, err
+		}
+		current = m.version
+	}
+	return current, nil
+}
+
+// Migrate applies the migrations that the database of the repository lacks,
+// see Migrate.
+func (r *MongoDBRepository) Migrate(ctx context.Context) (int, error) {
+	return Migrate(ctx, r.client.Database(r.Database))
+}
+
+// lockMigrations takes the lease of the migration lock, waiting while
+// another server holds it.
+func lockMigrations(ctx context.Context, db *mongo.Database) (primitive.ObjectID, error) {
+	coll := db.Collection("schema_migrations_lock")
+	token := primitive.NewObjectID()
+
+	for attempt := 0; ; attempt++ {
+		now := time.Now()
+		filter := bson.D{{Key: "_id", Value: "lock"}, {Key: "until", Value: bson.D{{Key: "$lt", Value: now}}}}
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "token", Value: token}, {Key: "until", Value: now.Add(migrationLease)}}}}
+		_, err := coll.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+		if err == nil {
+			return token, nil
+		}
+		// The upsert of a held lock collides with it.
+		if !mongo.IsDuplicateKeyError(err) {
+			return token, err
+		}
+		if err := backoff(ctx, attempt); err != nil {
+			return token, err
+		}
+	}
+}
+
+func unlockMigrations(ctx context.Context, db *mongo.Database, token primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	_, err := db.Collection("schema_migrations_lock").DeleteOne(ctx, bson.D{{Key: "_id", Value: "lock"}, {Key: "token", Value: token}})
+	return err
+}
+
+// moveEmbeddedRecords creates the records collection and moves records that
+// are still embedded in object documents into it.
+func moveEmbeddedRecords(ctx context.Context, db *mongo.Database) error {
+	records := db.Collection("records")
+	_, err := records.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "object_id", Value: 1}, {Key: "position", Value: 1}}},
+		{Keys: bson.D{{Key: "object_id", Value: 1}, {Key: "date", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	objects := db.Collection("objects")
+	cursor, err := objects.Find(ctx, bson.D{{Key: "rent_object.records", Value: bson.D{{Key: "$exists", Value: true}}}})
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var object struct {
+			ID     primitive.ObjectID `bson:"_id"`
+			UserID int64              `bson:"user_id"`
+			Object struct {
+				Records []domain.Record `bson:"records"`
+			} `bson:"rent_object"`
+		}
+		if err := cursor.Decode(&object); err != nil {
+			return err
+		}
+
+		var writes []mongo.WriteModel
+		for position, record := range object.Object.Records {
+			writes = append(writes, mongo.NewReplaceOneModel().
+				SetFilter(bson.D{{Key: "object_id", Value: object.ID}, {Key: "position", Value: position}}).
+				SetReplacement(recordDocument{UserID: object.UserID, ObjectID: object.ID, Position: position, Record: record}).
+				SetUpsert(true))
+		}
+		if len(writes) > 0 {
+			if _, err := records.BulkWrite(ctx, writes); err != nil {
+				return err
+			}
+		}
+
+		update := bson.D{{Key: "$unset", Value: bson.D{{Key: "rent_object.records", Value: ""}, {Key: "version", Value: ""}}}}
+		if _, err := objects.UpdateByID(ctx, object.ID, update); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
+
+// uniqueObjectNames makes names of objects unique for each user, which Add
+// and Update rely on. Duplicates are left to be resolved by hand, since
+// either of them may be the one to keep.
+func uniqueObjectNames(ctx context.Context, db *mongo.Database) error {
+	objects := db.Collection("objects")
+	cursor, err := objects.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "user_id", Value: "$user_id"}, {Key: "name", Value: "$rent_object.name"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$gt", Value: 1}}}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var duplicates []struct {
+		ID struct {
+			UserID int64  `bson:"user_id"`
+			Name   string `bson:"name"`
+		} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &duplicates); err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		var names []string
+		for _, d := range duplicates {
+			names = append(names, fmt.Sprintf("%q of user %d", d.ID.Name, d.ID.UserID))
+		}
+		return fmt.Errorf("objects share names, rename or delete them: %s", strings.Join(names, ", "))
+	}
+
+	// An index of the same keys, which is not unique, may be left from
+	// before migrations.
+	_, err = objects.Indexes().DropOne(ctx, "user_id_1_rent_object.name_1")
+	var commandErr mongo.CommandError
+	if err != nil && !(errors.As(err, &commandErr) && (commandErr.Code == indexNotFound || commandErr.Code == namespaceNotFound)) {
+		return err
+	}
+
+	_, err = objects.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "rent_object.name", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("user_id_name_unique"),
+	})
+	return err
+}
+
+// uniqueMatchRules keeps the match rules of a user in one document.
+func uniqueMatchRules(ctx context.Context, db *mongo.Database) error {
+	_, err := db.Collection("match_rules").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// deleteOrphanRecords deletes records left by deletions of objects that
+// deleted the object before its records, which totals of the user counted.
+func deleteOrphanRecords(ctx context.Context, db *mongo.Database) error {
+	records := db.Collection("records")
+	objectIDs, err := records.Distinct(ctx, "object_id", bson.D{})
+	if err != nil {
+		return err
+	}
+	existing, err := db.Collection("objects").Distinct(ctx, "_id", bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: objectIDs}}}})
+	if err != nil {
+		return err
+	}
+
+	_, err = records.DeleteMany(ctx, bson.D{{Key: "object_id", Value: bson.D{{Key: "$nin", Value: existing}}}})
+	return err
+}
+
+// uniqueRecordPositions makes positions of records unique in their object,
+// so that a change of records that went wrong fails rather than leaves two
+// records at one index. Duplicates are left to be resolved by hand, as in
+// uniqueObjectNames.
+func uniqueRecordPositions(ctx context.Context, db *mongo.Database) error {
+	records := db.Collection("records")
+	cursor, err := records.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "object_id", Value: "$object_id"}, {Key: "position", Value: "$position"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$gt", Value: 1}}}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var duplicates []struct {
+		ID struct {
+			ObjectID primitive.ObjectID `bson:"object_id"`
+			Position int                `bson:"position"`
+		} `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &duplicates); err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		var positions []string
+		for _, d := range duplicates {
+			positions = append(positions, fmt.Sprintf("%d of object %s", d.ID.Position, d.ID.ObjectID.Hex()))
+		}
+		return fmt.Errorf("records share positions, delete or move them: %s", strings.Join(positions, ", "))
+	}
+
+	// The index of migration 1 has the same keys.
+	_, err = records.Indexes().DropOne(ctx, "object_id_1_position_1")
+	var commandErr mongo.CommandError
+	if err != nil && !(errors.As(err, &commandErr) && (commandErr.Code == indexNotFound || commandErr.Code == namespaceNotFound)) {
+		return err
+	}
+
+	_, err = records.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "object_id", Value: 1}, {Key: "position", Value: 1}},
+		Options: options.Index().SetUnique(true).SetName("object_id_position_unique"),
+	})
+	return err
+}
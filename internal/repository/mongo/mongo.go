@@ -6,8 +6,10 @@ import (
 	"rental-server/internal/repository"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
 )
 
 type MongoDBRepository struct {
@@ -15,8 +17,25 @@ type MongoDBRepository struct {
 	Database string
 }
 
+// NewMongoDBRepository connects to the database and migrates its schema.
 func NewMongoDBRepository(uri string, database string) (*MongoDBRepository, error) {
-	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(uri))
+	r, err := ConnectMongoDBRepository(uri, database)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Migrate(context.TODO()); err != nil {
+		r.client.Disconnect(context.TODO())
+		return nil, err
+	}
+	return r, nil
+}
+
+// ConnectMongoDBRepository connects to the database without migrating it,
+// for when migrations are run apart from servers.
+func ConnectMongoDBRepository(uri string, database string) (*MongoDBRepository, error) {
+	// Commands are traced with the global tracer provider, which does
+	// nothing unless tracing is set up.
+	client, err := mongo.Connect(context.TODO(), options.Client().ApplyURI(uri).SetMonitor(otelmongo.NewMonitor()))
 	if err != nil {
 		return nil, err
 	}
@@ -29,202 +48,287 @@ func NewMongoDBRepository(uri string, database string) (*MongoDBRepository, erro
 	return &MongoDBRepository{
 		client:   client,
 		Database: database,
-	}, err
+	}, nil
 }
 
-func (r *MongoDBRepository) Add(userId int64, object domain.RentObject) error {
-	coll := r.client.Database(r.Database).Collection("objects")
-
-	_, err := r.GetByName(userId, object.Name)
-	if err == nil {
-		return repository.ObjectAlreadyExists
-	}
+// Objects are kept in the objects collection, and their records in the
+// records collection, one document for each record, so that objects are not
+// limited by the size of a document.
 
-	if object.Records == nil {
-		object.Records = []domain.Record{}
-	}
+// objectDocument is an object without its records.
+type objectDocument struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty"`
+	UserID int64              `bson:"user_id"`
+	Object objectFields       `bson:"rent_object"`
+}
 
-	var data = struct {
-		UserID            int64 `bson:"user_id"`
-		domain.RentObject `bson:"rent_object"`
-	}{
-		userId,
-		object,
-	}
+type objectFields struct {
+	Name        string  `bson:"name"`
+	Description string  `bson:"description"`
+	Area        float64 `bson:"area"`
+}
 
-	_, err = coll.InsertOne(context.TODO(), data)
-	return err
+// recordDocument is a record at its index in the records of an object.
+type recordDocument struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	UserID        int64              `bson:"user_id"`
+	ObjectID      primitive.ObjectID `bson:"object_id"`
+	Position      int                `bson:"position"`
+	domain.Record `bson:",inline"`
 }
 
-func (r *MongoDBRepository) Delete(userId int64, objectName string) error {
-	_, err := r.GetByName(userId, objectName)
-	if err == repository.ObjectNotFoundError {
-		return repository.ObjectNotFoundError
-	}
+func (r *MongoDBRepository) objects() *mongo.Collection {
+	return r.client.Database(r.Database).Collection("objects")
+}
 
-	coll := r.client.Database(r.Database).Collection("objects")
+func (r *MongoDBRepository) records() *mongo.Collection {
+	return r.client.Database(r.Database).Collection("records")
+}
 
-	filter := bson.D{
-		{Key: "user_id", Value: userId},
+func objectFilter(userID int64, objectName string) bson.D {
+	return bson.D{
+		{Key: "user_id", Value: userID},
 		{Key: "rent_object.name", Value: objectName},
 	}
-	_, err = coll.DeleteOne(context.TODO(), filter)
-	return err
-
 }
 
-func (r *MongoDBRepository) Update(userId int64, objectName string, input domain.UpdateRentObjectInput) error {
-	obj, err := r.GetByName(userId, objectName)
-
-	if err == repository.ObjectNotFoundError {
-		return repository.ObjectNotFoundError
+// inTransaction runs fn in a transaction, so that changes of several
+// documents are kept and seen whole. Transactions need a replica set, which
+// may have a single member.
+func (r *MongoDBRepository) inTransaction(ctx context.Context, fn func(ctx mongo.SessionContext) error) error {
+	session, err := r.client.StartSession()
+	if err != nil {
+		return err
 	}
+	defer session.EndSession(ctx)
 
-	updated := obj.Update(input)
-	coll := r.client.Database(r.Database).Collection("objects")
-
-	filter := bson.D{
-		{Key: "user_id", Value: userId},
-		{Key: "rent_object.name", Value: objectName},
-	}
-	_, err = coll.UpdateOne(context.TODO(), filter, bson.D{{Key: "$set", Value: bson.D{{Key: "rent_object", Value: updated}}}})
+	_, err = session.WithTransaction(ctx, func(ctx mongo.SessionContext) (any, error) {
+		return nil, fn(ctx)
+	})
 	return err
 }
 
-func (r *MongoDBRepository) GetByName(userId int64, objectName string) (domain.RentObject, error) {
-	coll := r.client.Database(r.Database).Collection("objects")
+// Names of objects are unique by the index of migration 2, so Add and Update
+// may leave the check to the database. The object and its records are
+// inserted in a transaction, so that a failed Add leaves no object without
+// its records.
+func (r *MongoDBRepository) Add(ctx context.Context, userId int64, object domain.RentObject) error {
+	return r.inTransaction(ctx, func(ctx mongo.SessionContext) error {
+		result, err := r.objects().InsertOne(ctx, objectDocument{
+			UserID: userId,
+			Object: objectFields{Name: object.Name, Description: object.Description, Area: object.Area},
+		})
+		if mongo.IsDuplicateKeyError(err) {
+			return repository.ObjectAlreadyExists
+		}
+		if err != nil {
+			return err
+		}
+		if len(object.Records) == 0 {
+			return nil
+		}
+
+		objectID := result.InsertedID.(primitive.ObjectID)
+		documents := make([]any, 0, len(object.Records))
+		for position, record := range object.Records {
+			documents = append(documents, recordDocument{UserID: userId, ObjectID: objectID, Position: position, Record: record})
+		}
+		_, err = r.records().InsertMany(ctx, documents)
+		return err
+	})
+}
+
+// Delete takes the lock of the object, so that no change of records adds
+// one after the records are deleted. Records are deleted before the object,
+// so that a failed deletion leaves no records without an object, and can be
+// tried again.
+func (r *MongoDBRepository) Delete(ctx context.Context, userId int64, objectName string) error {
+	return r.withLockedObject(ctx, userId, objectName, func(objectID primitive.ObjectID) error {
+		if _, err := r.records().DeleteMany(ctx, bson.D{{Key: "object_id", Value: objectID}}); err != nil {
+			return err
+		}
+		_, err := r.objects().DeleteOne(ctx, bson.D{{Key: "_id", Value: objectID}})
+		return err
+	})
+}
 
-	var data struct {
-		UserID            int64 `bson:"user_id"`
-		domain.RentObject `bson:"rent_object"`
+// Update sets only the changed fields, which leaves records alone.
+func (r *MongoDBRepository) Update(ctx context.Context, userId int64, objectName string, input domain.UpdateRentObjectInput) error {
+	set := bson.D{}
+	if input.Name != nil {
+		set = append(set, bson.E{Key: "rent_object.name", Value: *input.Name})
 	}
-	filter := bson.D{
-		{Key: "user_id", Value: userId},
-		{Key: "rent_object.name", Value: objectName},
+	if input.Description != nil {
+		set = append(set, bson.E{Key: "rent_object.description", Value: *input.Description})
+	}
+	if input.Area != nil {
+		set = append(set, bson.E{Key: "rent_object.area", Value: *input.Area})
 	}
-	err := coll.FindOne(context.TODO(), filter).Decode(&data)
+	if len(set) == 0 {
+		_, err := r.objectID(ctx, userId, objectName)
+		return err
+	}
+
+	result, err := r.objects().UpdateOne(ctx, objectFilter(userId, objectName), bson.D{{Key: "$set", Value: set}})
+	if mongo.IsDuplicateKeyError(err) {
+		return repository.ObjectAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return repository.ObjectNotFoundError
+	}
+	return nil
+}
+
+// objectID returns the id of the object document.
+func (r *MongoDBRepository) objectID(ctx context.Context, userID int64, objectName string) (primitive.ObjectID, error) {
+	var object objectDocument
+	err := r.objects().FindOne(ctx, objectFilter(userID, objectName), options.FindOne().SetProjection(bson.D{{Key: "_id", Value: 1}})).Decode(&object)
 	if err == mongo.ErrNoDocuments {
-		return data.RentObject, repository.ObjectNotFoundError
+		return object.ID, repository.ObjectNotFoundError
 	}
+	return object.ID, err
+}
 
-	return data.RentObject, err
+func (r *MongoDBRepository) GetByName(ctx context.Context, userId int64, objectName string) (domain.RentObject, error) {
+	return r.GetByNameInPeriod(ctx, userId, objectName, domain.Period{})
 }
 
-func (r *MongoDBRepository) GetAll(userId int64) ([]domain.RentObject, error) {
-	coll := r.client.Database(r.Database).Collection("objects")
+// GetByNameInPeriod reads only the records of the period, by the index of
+// record dates.
+func (r *MongoDBRepository) GetByNameInPeriod(ctx context.Context, userId int64, objectName string, period domain.Period) (domain.RentObject, error) {
+	var object objectDocument
+	err := r.objects().FindOne(ctx, objectFilter(userId, objectName)).Decode(&object)
+	if err == mongo.ErrNoDocuments {
+		return domain.RentObject{}, repository.ObjectNotFoundError
+	}
+	if err != nil {
+		return domain.RentObject{}, err
+	}
 
-	filter := bson.D{
-		{Key: "user_id", Value: userId},
+	objects, err := r.withRecords(ctx, []objectDocument{object}, period)
+	if err != nil {
+		return domain.RentObject{}, err
 	}
+	return objects[0], nil
+}
+
+func (r *MongoDBRepository) GetAll(ctx context.Context, userId int64) ([]domain.RentObject, error) {
+	return r.GetAllInPeriod(ctx, userId, domain.Period{})
+}
 
-	cursor, err := coll.Find(context.TODO(), filter)
+// GetAllInPeriod reads only the records of the period, as GetByNameInPeriod
+// does.
+func (r *MongoDBRepository) GetAllInPeriod(ctx context.Context, userId int64, period domain.Period) ([]domain.RentObject, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "rent_object.name", Value: 1}})
+	cursor, err := r.objects().Find(ctx, bson.D{{Key: "user_id", Value: userId}}, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	var results []struct {
-		UserID            int64 `bson:"user_id"`
-		domain.RentObject `bson:"rent_object"`
-	}
-	if err = cursor.All(context.TODO(), &results); err != nil {
+	var documents []objectDocument
+	if err = cursor.All(ctx, &documents); err != nil {
 		return nil, err
 	}
-	var objects = []domain.RentObject{}
-	for _, res := range results {
-		objects = append(objects, res.RentObject)
-	}
-	return objects, nil
-
+	return r.withRecords(ctx, documents, period)
 }
 
-func (r *MongoDBRepository) AddRecord(userID int64, objectName string, record domain.Record) (int, error) {
-	obj, err := r.GetByName(userID, objectName)
-
-	if err == repository.ObjectNotFoundError {
-		return 0, repository.ObjectNotFoundError
+// withRecords reads the records of the period of the objects, in their
+// order, with one query.
+func (r *MongoDBRepository) withRecords(ctx context.Context, documents []objectDocument, period domain.Period) ([]domain.RentObject, error) {
+	objects := make([]domain.RentObject, 0, len(documents))
+	indexes := map[primitive.ObjectID]int{}
+	ids := bson.A{}
+	for i, document := range documents {
+		objects = append(objects, domain.NewRentObject(document.Object.Name, document.Object.Description, document.Object.Area))
+		indexes[document.ID] = i
+		ids = append(ids, document.ID)
 	}
-
-	coll := r.client.Database(r.Database).Collection("objects")
-	filter := bson.D{
-		{Key: "user_id", Value: userID},
-		{Key: "rent_object.name", Value: objectName},
+	if len(documents) == 0 {
+		return objects, nil
 	}
 
-	obj.AddRecord(record)
-
-	update := bson.D{
-		{Key: "$set", Value: bson.D{
-			{Key: "rent_object", Value: obj},
-		},
-		}}
-
-	_, err = coll.UpdateOne(context.TODO(), filter, update)
+	filter := bson.D{{Key: "object_id", Value: bson.D{{Key: "$in", Value: ids}}}}
+	filter = append(filter, rangeFilter("date", dateBounds(period.From, period.To))...)
+	opts := options.Find().SetSort(bson.D{{Key: "object_id", Value: 1}, {Key: "position", Value: 1}})
+	cursor, err := r.records().Find(ctx, filter, opts)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
-	return len(obj.Records) - 1, nil
-}
-
-func (r *MongoDBRepository) DeleteRecord(userID int64, objectName string, recordIndex int) error {
-	obj, err := r.GetByName(userID, objectName)
-	if err == repository.ObjectNotFoundError {
-		return repository.ObjectNotFoundError
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var record recordDocument
+		if err := cursor.Decode(&record); err != nil {
+			return nil, err
+		}
+		i := indexes[record.ObjectID]
+		objects[i].Records = append(objects[i].Records, record.Record)
 	}
+	return objects, cursor.Err()
+}
 
-	err = obj.DeleteRecord(recordIndex)
+func (r *MongoDBRepository) GetRecordByIndex(ctx context.Context, userID int64, objectName string, recordIndex int) (domain.Record, error) {
+	objectID, err := r.objectID(ctx, userID, objectName)
 	if err != nil {
-		return err
+		return domain.Record{}, err
 	}
 
-	coll := r.client.Database(r.Database).Collection("objects")
-
-	filter := bson.D{
-		{Key: "user_id", Value: userID},
-		{Key: "rent_object.name", Value: objectName},
+	var record recordDocument
+	err = r.records().FindOne(ctx, bson.D{{Key: "object_id", Value: objectID}, {Key: "position", Value: recordIndex}}).Decode(&record)
+	if err == mongo.ErrNoDocuments {
+		return domain.Record{}, domain.RecordNotFoundError
 	}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "rent_object", Value: obj}}}}
-	_, err = coll.UpdateOne(context.TODO(), filter, update)
-	return err
+	return record.Record, err
 }
 
-func (r *MongoDBRepository) UpdateRecord(userID int64, objectName string, recordIndex int, input domain.UpdateRecordInput) error {
-	obj, err := r.GetByName(userID, objectName)
-	if err == repository.ObjectNotFoundError {
-		return repository.ObjectNotFoundError
+// GetAllRecords returns the records sorted by date, as domain.RentObject
+// does, by the index of record dates.
+func (r *MongoDBRepository) GetAllRecords(ctx context.Context, userID int64, objectName string) ([]domain.Record, error) {
+	objectID, err := r.objectID(ctx, userID, objectName)
+	if err != nil {
+		return nil, err
 	}
 
-	err = obj.UpdateRecord(recordIndex, input)
+	opts := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "position", Value: 1}})
+	cursor, err := r.records().Find(ctx, bson.D{{Key: "object_id", Value: objectID}}, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	coll := r.client.Database(r.Database).Collection("objects")
-
-	filter := bson.D{
-		{Key: "user_id", Value: userID},
-		{Key: "rent_object.name", Value: objectName},
+	var documents []recordDocument
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
 	}
-	update := bson.D{{Key: "$set", Value: bson.D{{Key: "rent_object", Value: obj}}}}
-	_, err = coll.UpdateOne(context.TODO(), filter, update)
-	return err
+	records := make([]domain.Record, 0, len(documents))
+	for _, document := range documents {
+		records = append(records, document.Record)
+	}
+	return records, nil
 }
 
-func (r *MongoDBRepository) GetRecordByIndex(userID int64, objectName string, recordIndex int) (domain.Record, error) {
-	obj, err := r.GetByName(userID, objectName)
-	if err == repository.ObjectNotFoundError {
-		return domain.Record{}, repository.ObjectNotFoundError
-	}
-	return obj.GetRecordByIndex(recordIndex)
+func (r *MongoDBRepository) Clear() {
+	r.client.Database(r.Database).Drop(context.TODO())
 }
 
-func (r *MongoDBRepository) GetAllRecords(userID int64, objectName string) ([]domain.Record, error) {
-	obj, err := r.GetByName(userID, objectName)
-	if err == repository.ObjectNotFoundError {
-		return nil, repository.ObjectNotFoundError
+func (r *MongoDBRepository) Count(ctx context.Context) (repository.Counts, error) {
+	var counts repository.Counts
+	var err error
+	if counts.Objects, err = r.objects().EstimatedDocumentCount(ctx); err != nil {
+		return counts, err
 	}
-	return obj.GetAllRecords(), nil
+	counts.Records, err = r.records().EstimatedDocumentCount(ctx)
+	return counts, err
 }
 
-func (r *MongoDBRepository) Clear() {
-	r.client.Database(r.Database).Drop(context.TODO())
+func (r *MongoDBRepository) Ping(ctx context.Context) error {
+	return r.client.Ping(ctx, nil)
+}
+
+// Close disconnects the client, waiting for operations in progress until the
+// context is done.
+func (r *MongoDBRepository) Close(ctx context.Context) error {
+	return r.client.Disconnect(ctx)
 }
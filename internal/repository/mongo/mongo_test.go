@@ -1,43 +1,86 @@
 package mongorep_test
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"os"
 	"rental-server/internal/domain"
+	"rental-server/internal/repository"
 	mongorep "rental-server/internal/repository/mongo"
+	"rental-server/internal/repository/repositorytest"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-var testURI = "mongodb://localhost:27017/test"
+var ctx = context.Background()
+
 var testDatabase = "test"
 
+// testURI is MONGODB_TEST_URI or a local mongod started as a replica set,
+// which is given up on quickly so that tests are skipped without it.
+func testURI() string {
+	if uri := os.Getenv("MONGODB_TEST_URI"); uri != "" {
+		return uri
+	}
+	return "mongodb://localhost:27017/test?directConnection=true&serverSelectionTimeoutMS=2000"
+}
+
+var (
+	connectOnce sync.Once
+	connectErr  error
+)
+
+// newRepository connects to an empty test database, and skips the test
+// without mongod. Only the first test waits for it.
+func newRepository(t *testing.T) *mongorep.MongoDBRepository {
+	connectOnce.Do(func() {
+		var rep *mongorep.MongoDBRepository
+		if rep, connectErr = mongorep.NewMongoDBRepository(testURI(), testDatabase); connectErr == nil {
+			rep.Clear()
+		}
+	})
+	if connectErr != nil {
+		t.Skipf("no MongoDB at %s: %v", testURI(), connectErr)
+	}
+
+	rep, err := mongorep.NewMongoDBRepository(testURI(), testDatabase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rep.Clear)
+	return rep
+}
+
 var dummyUserId int64 = 1
 var dummyObject = domain.NewRentObject("", "", 0)
 var dummyRecord = domain.Record{}
 
-func TestCreateMongoRepository(t *testing.T) {
-	rep, err := mongorep.NewMongoDBRepository(testURI, testDatabase)
-	assert.NoError(t, err)
-	if rep == nil {
-		t.Fatal(err)
-	}
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.RentObjectRepository {
+		return newRepository(t)
+	})
 }
 
 func TestAdd(t *testing.T) {
-	rep, _ := mongorep.NewMongoDBRepository(testURI, testDatabase)
+	rep := newRepository(t)
 	object := dummyObject
 
 	t.Run("Should be able to add object", func(t *testing.T) {
-		err := rep.Add(dummyUserId, object)
+		err := rep.Add(ctx, dummyUserId, object)
 
 		if !assert.NoError(t, err) {
 			t.Fatal(err)
 		}
 
-		got, err := rep.GetByName(dummyUserId, object.Name)
+		got, err := rep.GetByName(ctx, dummyUserId, object.Name)
 		if !assert.NoError(t, err) {
 			t.Fatal(err)
 		}
@@ -45,142 +88,157 @@ func TestAdd(t *testing.T) {
 	})
 
 	t.Run("Should return an error if object already exists", func(t *testing.T) {
-		err := rep.Add(dummyUserId, object)
+		err := rep.Add(ctx, dummyUserId, object)
 		assert.Error(t, err)
 	})
-	rep.Clear()
 }
 
 func TestDelete(t *testing.T) {
-	rep, _ := mongorep.NewMongoDBRepository(testURI, testDatabase)
+	rep := newRepository(t)
 	object := dummyObject
-	rep.Add(dummyUserId, object)
+	rep.Add(ctx, dummyUserId, object)
 
 	t.Run("shoud delete object", func(t *testing.T) {
-		err := rep.Delete(dummyUserId, object.Name)
+		err := rep.Delete(ctx, dummyUserId, object.Name)
 		assert.NoError(t, err)
 	})
 	t.Run("should return object not found if object does not exists", func(t *testing.T) {
-		err := rep.Delete(dummyUserId, object.Name)
+		err := rep.Delete(ctx, dummyUserId, object.Name)
 		assert.Error(t, err)
 	})
 
-	rep.Clear()
+	t.Run("should leave no records when records are added concurrently", func(t *testing.T) {
+		object := domain.NewRentObject("Deleted", "", 0)
+		rep.Add(ctx, dummyUserId, object)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				rep.AddRecord(ctx, dummyUserId, object.Name, domain.Record{Date: time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC), Rent: 100})
+			}(i)
+		}
+		assert.NoError(t, rep.Delete(ctx, dummyUserId, object.Name))
+		wg.Wait()
+
+		totals, err := rep.TotalsByCategory(ctx, dummyUserId, repository.TotalsQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, repository.NewCategoryTotals(make([]domain.RUB, len(repository.Categories))), totals)
+		counts, err := rep.Count(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, repository.Counts{}, counts)
+	})
 }
 
 func TestUpdate(t *testing.T) {
-	rep, _ := mongorep.NewMongoDBRepository(testURI, testDatabase)
+	rep := newRepository(t)
 	object := dummyObject
-	rep.Add(dummyUserId, object)
+	rep.Add(ctx, dummyUserId, object)
 
 	newName := "test"
 	input := domain.UpdateRentObjectInput{Name: &newName}
 	updated := object.Update(input)
 
 	t.Run("should update object", func(t *testing.T) {
-		err := rep.Update(dummyUserId, object.Name, input)
+		err := rep.Update(ctx, dummyUserId, object.Name, input)
 		assert.NoError(t, err)
 
-		got, err := rep.GetByName(dummyUserId, updated.Name)
+		got, err := rep.GetByName(ctx, dummyUserId, updated.Name)
 		assert.NoError(t, err)
 
 		assert.Equal(t, updated, got)
 	})
 
 	t.Run("should return object not found if object does not exists", func(t *testing.T) {
-		err := rep.Update(dummyUserId, "failed", input)
+		err := rep.Update(ctx, dummyUserId, "failed", input)
 		assert.Error(t, err)
 	})
-	rep.Clear()
 }
 
 func TestGetAll(t *testing.T) {
-	rep, _ := mongorep.NewMongoDBRepository(testURI, testDatabase)
+	rep := newRepository(t)
 	var objects []domain.RentObject
 	for i := 0; i < 10; i++ {
 		object := domain.NewRentObject(fmt.Sprintf("%d", i), "", 0)
 		objects = append(objects, object)
-		rep.Add(dummyUserId, object)
+		rep.Add(ctx, dummyUserId, object)
 	}
 
-	got, err := rep.GetAll(dummyUserId)
+	got, err := rep.GetAll(ctx, dummyUserId)
 	assert.NoError(t, err)
 
 	assert.Equal(t, objects, got)
 
-	rep.Clear()
 }
 
 func TestAddRecord(t *testing.T) {
-	rep, _ := mongorep.NewMongoDBRepository(testURI, "testing")
-	rep.Add(dummyUserId, dummyObject)
+	rep := newRepository(t)
+	rep.Add(ctx, dummyUserId, dummyObject)
 	t.Run("should add record to object", func(t *testing.T) {
-		index, err := rep.AddRecord(dummyUserId, dummyObject.Name, dummyRecord)
+		index, err := rep.AddRecord(ctx, dummyUserId, dummyObject.Name, dummyRecord)
 		assert.NoError(t, err)
-		got, err := rep.GetRecordByIndex(dummyUserId, dummyObject.Name, index)
+		got, err := rep.GetRecordByIndex(ctx, dummyUserId, dummyObject.Name, index)
 		assert.NoError(t, err)
 
 		dummyRecord.Heat = 1
-		index, err = rep.AddRecord(dummyUserId, dummyObject.Name, dummyRecord)
+		index, err = rep.AddRecord(ctx, dummyUserId, dummyObject.Name, dummyRecord)
 		assert.NoError(t, err)
 
-		got, err = rep.GetRecordByIndex(dummyUserId, dummyObject.Name, index)
+		got, err = rep.GetRecordByIndex(ctx, dummyUserId, dummyObject.Name, index)
 		assert.NoError(t, err)
 		assert.Equal(t, dummyRecord, got)
 	})
 	t.Run("Shoudl return an error if object does not exists", func(t *testing.T) {
-		_, err := rep.AddRecord(dummyUserId, "WTH", dummyRecord)
+		_, err := rep.AddRecord(ctx, dummyUserId, "WTH", dummyRecord)
 		assert.Error(t, err)
 	})
-	rep.Clear()
 }
 
 func TestDeleteRecord(t *testing.T) {
-	rep, _ := mongorep.NewMongoDBRepository(testURI, testDatabase)
-	rep.Add(dummyUserId, dummyObject)
-	index, _ := rep.AddRecord(dummyUserId, dummyObject.Name, dummyRecord)
+	rep := newRepository(t)
+	rep.Add(ctx, dummyUserId, dummyObject)
+	index, _ := rep.AddRecord(ctx, dummyUserId, dummyObject.Name, dummyRecord)
 
 	t.Run("should delete record", func(t *testing.T) {
-		err := rep.DeleteRecord(dummyUserId, dummyObject.Name, index)
+		err := rep.DeleteRecord(ctx, dummyUserId, dummyObject.Name, index)
 		assert.NoError(t, err)
 
-		_, err = rep.GetRecordByIndex(dummyUserId, dummyObject.Name, index)
+		_, err = rep.GetRecordByIndex(ctx, dummyUserId, dummyObject.Name, index)
 		assert.Error(t, err)
 	})
-	rep.Clear()
 }
 
 func TestUpdateRecord(t *testing.T) {
-	rep, _ := mongorep.NewMongoDBRepository(testURI, testDatabase)
-	rep.Add(dummyUserId, dummyObject)
-	index, _ := rep.AddRecord(dummyUserId, dummyObject.Name, dummyRecord)
+	rep := newRepository(t)
+	rep.Add(ctx, dummyUserId, dummyObject)
+	index, _ := rep.AddRecord(ctx, dummyUserId, dummyObject.Name, dummyRecord)
 	newRent := domain.RUB(1000)
 	input := domain.UpdateRecordInput{Rent: &newRent}
 	newRecord := dummyRecord.Update(input)
 
 	t.Run("should update record", func(t *testing.T) {
-		err := rep.UpdateRecord(dummyUserId, dummyObject.Name, index, input)
+		err := rep.UpdateRecord(ctx, dummyUserId, dummyObject.Name, index, input)
 		assert.NoError(t, err)
 
-		got, err := rep.GetRecordByIndex(dummyUserId, dummyObject.Name, index)
+		got, err := rep.GetRecordByIndex(ctx, dummyUserId, dummyObject.Name, index)
 		assert.Equal(t, newRecord, got)
 	})
-	rep.Clear()
 }
 
 func TestGetAllRecords(t *testing.T) {
-	rep, _ := mongorep.NewMongoDBRepository(testURI, testDatabase)
-	rep.Add(dummyUserId, dummyObject)
+	rep := newRepository(t)
+	rep.Add(ctx, dummyUserId, dummyObject)
 	var records []domain.Record
 	for i := 0; i < 10; i++ {
 		randomTime := rand.Int63n(time.Now().Unix()-94608000) + 94608000
 		record := domain.Record{Date: time.Unix(randomTime, 0)}
 		records = append(records, record)
-		rep.AddRecord(dummyUserId, dummyObject.Name, record)
+		rep.AddRecord(ctx, dummyUserId, dummyObject.Name, record)
 	}
 
 	t.Run("should return all records", func(t *testing.T) {
-		got, err := rep.GetAllRecords(dummyUserId, dummyObject.Name)
+		got, err := rep.GetAllRecords(ctx, dummyUserId, dummyObject.Name)
 		assert.NoError(t, err)
 		assert.Len(t, got, 10)
 
@@ -190,5 +248,419 @@ func TestGetAllRecords(t *testing.T) {
 			}
 		}
 	})
-	rep.Clear()
+}
+
+func TestFindObjects(t *testing.T) {
+	rep := newRepository(t)
+	for i := 0; i < 5; i++ {
+		object := domain.NewRentObject(fmt.Sprintf("%d", i), "", float64(5-i))
+		object.AddRecord(domain.Record{Rent: domain.RUB(i % 2 * 100), Heat: 10})
+		rep.Add(ctx, dummyUserId, object)
+	}
+
+	t.Run("should walk through pages sorted by profit", func(t *testing.T) {
+		query := repository.ObjectQuery{SortBy: repository.SortByProfit, Descending: true, Limit: 2}
+		var got []string
+		for {
+			page, err := rep.FindObjects(ctx, dummyUserId, query)
+			if !assert.NoError(t, err) {
+				t.Fatal(err)
+			}
+			for _, object := range page.Items {
+				got = append(got, object.Name)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+		assert.Equal(t, []string{"3", "1", "4", "2", "0"}, got)
+	})
+
+	t.Run("should filter by name prefix and profit", func(t *testing.T) {
+		minProfit := domain.RUB(0)
+		page, err := rep.FindObjects(ctx, dummyUserId, repository.ObjectQuery{NamePrefix: "3", MinProfit: &minProfit})
+		assert.NoError(t, err)
+		if assert.Len(t, page.Items, 1) {
+			assert.Equal(t, "3", page.Items[0].Name)
+		}
+	})
+}
+
+func TestFindRecords(t *testing.T) {
+	rep := newRepository(t)
+	rep.Add(ctx, dummyUserId, dummyObject)
+	for month := 1; month <= 6; month++ {
+		rep.AddRecord(ctx, dummyUserId, dummyObject.Name, domain.Record{
+			Date: time.Date(2025, time.Month(month), 1, 0, 0, 0, 0, time.UTC),
+			Rent: domain.RUB(month * 100),
+		})
+	}
+
+	t.Run("should filter by date range and amount", func(t *testing.T) {
+		from := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+		minAmount := domain.RUB(300)
+
+		page, err := rep.FindRecords(ctx, dummyUserId, dummyObject.Name, repository.RecordQuery{
+			Period: domain.Period{From: &from, To: &to}, MinAmount: &minAmount, SortBy: repository.SortByAmount, Descending: true, Limit: 2,
+		})
+		assert.NoError(t, err)
+
+		var indexes []int
+		for _, entry := range page.Items {
+			indexes = append(indexes, entry.Index)
+		}
+		assert.Equal(t, []int{4, 3}, indexes)
+		assert.NotEmpty(t, page.NextCursor)
+	})
+
+	t.Run("should return object not found if object does not exists", func(t *testing.T) {
+		_, err := rep.FindRecords(ctx, dummyUserId, "failed", repository.RecordQuery{})
+		assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	})
+}
+
+func TestMatchRules(t *testing.T) {
+	rep := newRepository(t)
+	first := domain.MatchRule{ObjectName: "first", Field: "rent", Counterparty: "7701234567"}
+	second := domain.MatchRule{ObjectName: "second", Field: "security", Purpose: "охрана"}
+
+	t.Run("should keep rules in order", func(t *testing.T) {
+		rep.AddMatchRule(ctx, dummyUserId, first)
+		index, err := rep.AddMatchRule(ctx, dummyUserId, second)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, index)
+
+		rules, _ := rep.GetMatchRules(ctx, dummyUserId)
+		assert.Equal(t, []domain.MatchRule{first, second}, rules)
+	})
+
+	t.Run("should delete rule", func(t *testing.T) {
+		assert.NoError(t, rep.DeleteMatchRule(ctx, dummyUserId, 0))
+		assert.ErrorIs(t, rep.DeleteMatchRule(ctx, dummyUserId, 1), domain.MatchRuleNotFoundError)
+
+		rules, _ := rep.GetMatchRules(ctx, dummyUserId)
+		assert.Equal(t, []domain.MatchRule{second}, rules)
+	})
+}
+
+func TestConcurrentRecordOperations(t *testing.T) {
+	rep := newRepository(t)
+	const n = 20
+
+	t.Run("Concurrent additions should all be kept", func(t *testing.T) {
+		object := domain.NewRentObject("Added", "", 0)
+		rep.Add(ctx, dummyUserId, object)
+
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, err := rep.AddRecord(ctx, dummyUserId, object.Name, domain.Record{Date: time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC), Rent: domain.RUB(i)})
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserId, object.Name)
+		assert.Len(t, records, n)
+		for i, record := range records {
+			assert.Equal(t, domain.RUB(i), record.Rent)
+		}
+	})
+
+	t.Run("Concurrent updates of different fields should all be kept", func(t *testing.T) {
+		object := domain.NewRentObject("Updated", "", 0)
+		rep.Add(ctx, dummyUserId, object)
+		rep.AddRecord(ctx, dummyUserId, object.Name, domain.Record{})
+		rent, heat := domain.RUB(1000), domain.RUB(100)
+		description := "Description"
+
+		var wg sync.WaitGroup
+		for _, update := range []func() error{
+			func() error {
+				return rep.UpdateRecord(ctx, dummyUserId, object.Name, 0, domain.UpdateRecordInput{Rent: &rent})
+			},
+			func() error {
+				return rep.UpdateRecord(ctx, dummyUserId, object.Name, 0, domain.UpdateRecordInput{Heat: &heat})
+			},
+			func() error {
+				_, err := rep.AddRecord(ctx, dummyUserId, object.Name, domain.Record{Date: time.Now()})
+				return err
+			},
+			func() error {
+				return rep.Update(ctx, dummyUserId, object.Name, domain.UpdateRentObjectInput{Description: &description})
+			},
+		} {
+			wg.Add(1)
+			go func(update func() error) {
+				defer wg.Done()
+				assert.NoError(t, update())
+			}(update)
+		}
+		wg.Wait()
+
+		got, _ := rep.GetByName(ctx, dummyUserId, object.Name)
+		assert.Equal(t, description, got.Description)
+		assert.Len(t, got.Records, 2)
+		assert.Equal(t, rent, got.Records[0].Rent)
+		assert.Equal(t, heat, got.Records[0].Heat)
+	})
+
+	t.Run("Concurrent deletions and additions should all be kept", func(t *testing.T) {
+		object := domain.NewRentObject("Deleted", "", 0)
+		rep.Add(ctx, dummyUserId, object)
+		for i := 0; i < n; i++ {
+			rep.AddRecord(ctx, dummyUserId, object.Name, domain.Record{Date: time.Date(2024, 1, i+1, 0, 0, 0, 0, time.UTC)})
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < n/2; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, rep.DeleteRecord(ctx, dummyUserId, object.Name, 0))
+			}()
+			go func(i int) {
+				defer wg.Done()
+				_, err := rep.AddRecord(ctx, dummyUserId, object.Name, domain.Record{Date: time.Date(2025, 1, i+1, 0, 0, 0, 0, time.UTC)})
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserId, object.Name)
+		assert.Len(t, records, n)
+	})
+
+	t.Run("Record of a missing index should return RecordNotFoundError", func(t *testing.T) {
+		rent := domain.RUB(1)
+		err := rep.UpdateRecord(ctx, dummyUserId, "Added", n, domain.UpdateRecordInput{Rent: &rent})
+		assert.ErrorIs(t, err, domain.RecordNotFoundError)
+
+		err = rep.UpdateRecord(ctx, dummyUserId, "Unknown", 0, domain.UpdateRecordInput{Rent: &rent})
+		assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	})
+}
+
+func TestConcurrentMatchRuleOperations(t *testing.T) {
+	rep := newRepository(t)
+	const n = 20
+
+	t.Run("Concurrent additions should all be kept at the returned indexes", func(t *testing.T) {
+		indexes := make([]int, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				index, err := rep.AddMatchRule(ctx, dummyUserId, domain.MatchRule{ObjectName: fmt.Sprint(i), Field: "rent"})
+				assert.NoError(t, err)
+				indexes[i] = index
+			}(i)
+		}
+		wg.Wait()
+
+		rules, _ := rep.GetMatchRules(ctx, dummyUserId)
+		assert.Len(t, rules, n)
+		for i, index := range indexes {
+			if assert.Less(t, index, len(rules)) {
+				assert.Equal(t, fmt.Sprint(i), rules[index].ObjectName)
+			}
+		}
+	})
+
+	t.Run("Concurrent deletions and additions should all be kept", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < n/2; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, rep.DeleteMatchRule(ctx, dummyUserId, 0))
+			}()
+			go func(i int) {
+				defer wg.Done()
+				_, err := rep.AddMatchRule(ctx, dummyUserId, domain.MatchRule{ObjectName: fmt.Sprint("added", i), Field: "rent"})
+				assert.NoError(t, err)
+			}(i)
+		}
+		wg.Wait()
+
+		rules, _ := rep.GetMatchRules(ctx, dummyUserId)
+		assert.Len(t, rules, n)
+	})
+}
+
+func TestGetByNameInPeriod(t *testing.T) {
+	rep := newRepository(t)
+	rep.Add(ctx, dummyUserId, dummyObject)
+	for month := time.January; month <= time.April; month++ {
+		rep.AddRecord(ctx, dummyUserId, dummyObject.Name, domain.Record{Date: time.Date(2024, month, 1, 0, 0, 0, 0, time.UTC)})
+	}
+
+	t.Run("should return only the records of the period", func(t *testing.T) {
+		from := time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+		got, err := rep.GetByNameInPeriod(ctx, dummyUserId, dummyObject.Name, domain.Period{From: &from, To: &to})
+		assert.NoError(t, err)
+		if assert.Len(t, got.Records, 2) {
+			assert.True(t, got.Records[0].Date.Equal(from))
+		}
+	})
+
+	t.Run("should return an error if object doesnt exist", func(t *testing.T) {
+		_, err := rep.GetByNameInPeriod(ctx, dummyUserId, "missing", domain.Period{})
+		assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	})
+}
+
+func TestMigrateEmbeddedRecords(t *testing.T) {
+	newRepository(t)
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(testURI()))
+	if !assert.NoError(t, err) {
+		t.Fatal(err)
+	}
+	defer client.Disconnect(ctx)
+
+	records := []domain.Record{
+		{Date: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), Rent: 100},
+		{Date: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), Rent: 200},
+	}
+	_, err = client.Database(testDatabase).Collection("objects").InsertOne(ctx, bson.D{
+		{Key: "user_id", Value: dummyUserId},
+		{Key: "rent_object", Value: bson.D{
+			{Key: "name", Value: "old"},
+			{Key: "description", Value: ""},
+			{Key: "area", Value: 10.0},
+			{Key: "records", Value: records},
+		}},
+	})
+	if !assert.NoError(t, err) {
+		t.Fatal(err)
+	}
+
+	t.Run("should move records into their own collection", func(t *testing.T) {
+		// Opening twice checks that the migration may run again.
+		for i := 0; i < 2; i++ {
+			rep, err := mongorep.NewMongoDBRepository(testURI(), testDatabase)
+			if !assert.NoError(t, err) {
+				t.Fatal(err)
+			}
+
+			got, err := rep.GetAllRecords(ctx, dummyUserId, "old")
+			assert.NoError(t, err)
+			if assert.Len(t, got, 2) {
+				assert.Equal(t, records[1].Rent, got[1].Rent)
+			}
+		}
+
+		n, err := client.Database(testDatabase).Collection("objects").CountDocuments(ctx, bson.D{{Key: "rent_object.records", Value: bson.D{{Key: "$exists", Value: true}}}})
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+	})
+	client.Database(testDatabase).Drop(ctx)
+}
+
+func TestMigrate(t *testing.T) {
+	rep := newRepository(t)
+
+	t.Run("should keep the version when there is nothing to migrate", func(t *testing.T) {
+		first, err := rep.Migrate(ctx)
+		assert.NoError(t, err)
+		second, err := rep.Migrate(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.NotZero(t, first)
+	})
+
+	t.Run("should keep names unique under concurrent additions", func(t *testing.T) {
+		object := domain.NewRentObject("Unique", "", 0)
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- rep.Add(ctx, dummyUserId, object)
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		added := 0
+		for err := range errs {
+			if err == nil {
+				added++
+			} else {
+				assert.ErrorIs(t, err, repository.ObjectAlreadyExists)
+			}
+		}
+		assert.Equal(t, 1, added)
+	})
+
+	t.Run("should not rename an object to the name of another", func(t *testing.T) {
+		rep.Add(ctx, dummyUserId, domain.NewRentObject("Other", "", 0))
+		name := "Unique"
+		err := rep.Update(ctx, dummyUserId, "Other", domain.UpdateRentObjectInput{Name: &name})
+		assert.ErrorIs(t, err, repository.ObjectAlreadyExists)
+	})
+
+	t.Run("should keep record positions unique", func(t *testing.T) {
+		object := domain.NewRentObject("Positions", "", 0)
+		object.AddRecord(dummyRecord)
+		rep.Add(ctx, dummyUserId, object)
+
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(testURI()))
+		if !assert.NoError(t, err) {
+			t.Fatal(err)
+		}
+		defer client.Disconnect(ctx)
+		db := client.Database(testDatabase)
+
+		var document struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		err = db.Collection("objects").FindOne(ctx, bson.D{{Key: "rent_object.name", Value: "Positions"}}).Decode(&document)
+		assert.NoError(t, err)
+		_, err = db.Collection("records").InsertOne(ctx, bson.D{{Key: "object_id", Value: document.ID}, {Key: "position", Value: 0}})
+		assert.True(t, mongo.IsDuplicateKeyError(err))
+	})
+}
+
+func TestTotals(t *testing.T) {
+	rep := newRepository(t)
+	shop := domain.NewRentObject("shop", "", 10)
+	shop.AddRecord(domain.Record{Date: time.Date(2024, time.January, 10, 0, 0, 0, 0, time.UTC), Rent: 1000, Heat: 100})
+	shop.AddRecord(domain.Record{Date: time.Date(2024, time.May, 10, 0, 0, 0, 0, time.UTC), Rent: 1000, TBO: 50})
+	empty := domain.NewRentObject("empty", "", 5)
+	objects := []domain.RentObject{shop, empty}
+	for _, object := range objects {
+		rep.Add(ctx, dummyUserId, object)
+	}
+
+	t.Run("should sum up as the repository helpers do", func(t *testing.T) {
+		query := repository.TotalsQuery{Granularity: repository.ByQuarter}
+
+		byObject, err := rep.TotalsByObject(ctx, dummyUserId, query)
+		assert.NoError(t, err)
+		want, _ := repository.SumByObject(objects, query)
+		assert.Equal(t, want, byObject)
+
+		byPeriod, err := rep.TotalsByPeriod(ctx, dummyUserId, query)
+		assert.NoError(t, err)
+		wantPeriods, _ := repository.SumByPeriod(objects, query)
+		assert.Equal(t, wantPeriods, byPeriod)
+
+		byCategory, err := rep.TotalsByCategory(ctx, dummyUserId, query)
+		assert.NoError(t, err)
+		wantCategories, _ := repository.SumByCategory(objects, query)
+		assert.Equal(t, wantCategories, byCategory)
+	})
+
+	t.Run("should return an error if object doesnt exist", func(t *testing.T) {
+		_, err := rep.TotalsByPeriod(ctx, dummyUserId, repository.TotalsQuery{ObjectName: "missing"})
+		assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	})
 }
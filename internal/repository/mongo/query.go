@@ -0,0 +1,233 @@
+package mongorep
+
+import (
+	"context"
+	"regexp"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var expenseFields = []string{"heat", "exploitation", "mop", "renovation", "tbo", "electricity", "earthrent", "other", "security"}
+
+// profitExpression computes the profit of a record stored under prefix, the
+// same way as domain.Record.Profit does.
+func profitExpression(prefix string) bson.D {
+	return bson.D{{Key: "$subtract", Value: bson.A{prefix + "rent", expensesExpression(prefix)}}}
+}
+
+func expensesExpression(prefix string) bson.D {
+	var expenses bson.A
+	for _, field := range expenseFields {
+		expenses = append(expenses, prefix+field)
+	}
+	return bson.D{{Key: "$add", Value: expenses}}
+}
+
+func rangeFilter(field string, bounds bson.D) bson.D {
+	if len(bounds) == 0 {
+		return bson.D{}
+	}
+	return bson.D{{Key: field, Value: bounds}}
+}
+
+func amountBounds(min, max *domain.RUB) bson.D {
+	var bounds bson.D
+	if min != nil {
+		bounds = append(bounds, bson.E{Key: "$gte", Value: float64(*min)})
+	}
+	if max != nil {
+		bounds = append(bounds, bson.E{Key: "$lte", Value: float64(*max)})
+	}
+	return bounds
+}
+
+// afterCursor matches documents that come after the cursor in the order of
+// field and then tieField.
+func afterCursor(field string, value any, tieField string, tieValue any, descending bool) bson.D {
+	op := "$gt"
+	if descending {
+		op = "$lt"
+	}
+	return bson.D{{Key: "$or", Value: bson.A{
+		bson.D{{Key: field, Value: bson.D{{Key: op, Value: value}}}},
+		bson.D{{Key: field, Value: value}, {Key: tieField, Value: bson.D{{Key: op, Value: tieValue}}}},
+	}}}
+}
+
+// dateBounds matches dates of the half-open interval [from, to).
+func dateBounds(from, to *time.Time) bson.D {
+	var bounds bson.D
+	if from != nil {
+		bounds = append(bounds, bson.E{Key: "$gte", Value: *from})
+	}
+	if to != nil {
+		bounds = append(bounds, bson.E{Key: "$lt", Value: *to})
+	}
+	return bounds
+}
+
+func sortDirection(descending bool) int {
+	if descending {
+		return -1
+	}
+	return 1
+}
+
+func (r *MongoDBRepository) FindObjects(ctx context.Context, userID int64, query repository.ObjectQuery) (repository.Page[domain.RentObject], error) {
+	var page repository.Page[domain.RentObject]
+	cursor, err := query.Normalize()
+	if err != nil {
+		return page, err
+	}
+
+	match := bson.D{{Key: "user_id", Value: userID}}
+	if query.NamePrefix != "" {
+		match = append(match, bson.E{Key: "rent_object.name", Value: bson.D{{Key: "$regex", Value: "^" + regexp.QuoteMeta(query.NamePrefix)}}})
+	}
+
+	sortField := map[repository.SortField]string{
+		repository.SortByName:   "rent_object.name",
+		repository.SortByArea:   "rent_object.area",
+		repository.SortByProfit: "profit",
+	}[query.SortBy]
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "records"},
+			{Key: "let", Value: bson.D{{Key: "id", Value: "$_id"}}},
+			{Key: "pipeline", Value: mongo.Pipeline{
+				{{Key: "$match", Value: append(
+					bson.D{{Key: "$expr", Value: bson.D{{Key: "$eq", Value: bson.A{"$object_id", "$$id"}}}}},
+					rangeFilter("date", dateBounds(query.Period.From, query.Period.To))...,
+				)}},
+				{{Key: "$group", Value: bson.D{
+					{Key: "_id", Value: nil},
+					{Key: "profit", Value: bson.D{{Key: "$sum", Value: profitExpression("$")}}},
+				}}},
+			}},
+			{Key: "as", Value: "totals"},
+		}}},
+		{{Key: "$addFields", Value: bson.D{{Key: "profit", Value: bson.D{{Key: "$sum", Value: "$totals.profit"}}}}}},
+		{{Key: "$match", Value: rangeFilter("profit", amountBounds(query.MinProfit, query.MaxProfit))}},
+	}
+
+	if cursor != nil {
+		value := map[repository.SortField]any{
+			repository.SortByName:   cursor.Name,
+			repository.SortByArea:   cursor.Area,
+			repository.SortByProfit: float64(cursor.Profit),
+		}[query.SortBy]
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: afterCursor(sortField, value, "rent_object.name", cursor.Name, query.Descending)}})
+	}
+
+	direction := sortDirection(query.Descending)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: sortField, Value: direction}, {Key: "rent_object.name", Value: direction}}}},
+		bson.D{{Key: "$limit", Value: query.Limit + 1}},
+	)
+
+	var results []struct {
+		Profit         float64 `bson:"profit"`
+		objectDocument `bson:",inline"`
+	}
+	if err := r.aggregate(ctx, r.objects(), pipeline, &results); err != nil {
+		return page, err
+	}
+
+	var documents []objectDocument
+	for i, res := range results {
+		if i == query.Limit {
+			last := results[i-1]
+			page.NextCursor = repository.EncodeCursor(repository.ObjectCursor{
+				SortBy: query.SortBy,
+				Name:   last.Object.Name,
+				Area:   last.Object.Area,
+				Profit: domain.RUB(last.Profit),
+			})
+			break
+		}
+		documents = append(documents, res.objectDocument)
+	}
+
+	page.Items, err = r.withRecords(ctx, documents, domain.Period{})
+	return page, err
+}
+
+func (r *MongoDBRepository) FindRecords(ctx context.Context, userID int64, objectName string, query repository.RecordQuery) (repository.Page[repository.RecordEntry], error) {
+	var page repository.Page[repository.RecordEntry]
+	cursor, err := query.Normalize()
+	if err != nil {
+		return page, err
+	}
+
+	objectID, err := r.objectID(ctx, userID, objectName)
+	if err != nil {
+		return page, err
+	}
+
+	sortField := map[repository.SortField]string{
+		repository.SortByDate:   "date",
+		repository.SortByAmount: "amount",
+	}[query.SortBy]
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: append(
+			bson.D{{Key: "object_id", Value: objectID}},
+			rangeFilter("date", dateBounds(query.From, query.To))...,
+		)}},
+		{{Key: "$addFields", Value: bson.D{{Key: "amount", Value: profitExpression("$")}}}},
+		{{Key: "$match", Value: rangeFilter("amount", amountBounds(query.MinAmount, query.MaxAmount))}},
+	}
+
+	if cursor != nil {
+		value := map[repository.SortField]any{
+			repository.SortByDate:   cursor.Date,
+			repository.SortByAmount: float64(cursor.Amount),
+		}[query.SortBy]
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: afterCursor(sortField, value, "position", cursor.Index, query.Descending)}})
+	}
+
+	direction := sortDirection(query.Descending)
+	pipeline = append(pipeline,
+		bson.D{{Key: "$sort", Value: bson.D{{Key: sortField, Value: direction}, {Key: "position", Value: direction}}}},
+		bson.D{{Key: "$limit", Value: query.Limit + 1}},
+	)
+
+	var results []struct {
+		Index         int     `bson:"position"`
+		Amount        float64 `bson:"amount"`
+		domain.Record `bson:",inline"`
+	}
+	if err := r.aggregate(ctx, r.records(), pipeline, &results); err != nil {
+		return page, err
+	}
+
+	page.Items = []repository.RecordEntry{}
+	for i, res := range results {
+		if i == query.Limit {
+			last := results[i-1]
+			page.NextCursor = repository.EncodeCursor(repository.RecordCursor{
+				SortBy: query.SortBy,
+				Index:  last.Index,
+				Date:   last.Date,
+				Amount: domain.RUB(last.Amount),
+			})
+			break
+		}
+		page.Items = append(page.Items, repository.RecordEntry{Index: res.Index, Record: res.Record})
+	}
+	return page, nil
+}
+
+func (r *MongoDBRepository) aggregate(ctx context.Context, coll *mongo.Collection, pipeline mongo.Pipeline, results any) error {
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	return cursor.All(ctx, results)
+}
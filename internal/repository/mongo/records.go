@@ -0,0 +1,266 @@
+package mongorep
+
+import (
+	"context"
+	"math/rand"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Changes of records move other records of the object, so they lock the
+// object for each other. The lock is a lease in the object document, which
+// expires if its holder dies.
+const lockLease = 30 * time.Second
+
+// lockObject takes the lock of the object, waiting while others hold it,
+// and returns the id of the object with the token of the lock.
+func (r *MongoDBRepository) lockObject(ctx context.Context, userID int64, objectName string) (primitive.ObjectID, primitive.ObjectID, error) {
+	token := primitive.NewObjectID()
+	deadline := time.Now().Add(lockLease)
+
+	for attempt := 0; ; attempt++ {
+		now := time.Now()
+		filter := append(objectFilter(userID, objectName), bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "lock", Value: nil}},
+			bson.D{{Key: "lock.until", Value: bson.D{{Key: "$lt", Value: now}}}},
+		}})
+		update := bson.D{{Key: "$set", Value: bson.D{{Key: "lock", Value: bson.D{
+			{Key: "token", Value: token},
+			{Key: "until", Value: now.Add(lockLease)},
+		}}}}}
+		opts := options.FindOneAndUpdate().SetProjection(bson.D{{Key: "_id", Value: 1}})
+
+		var object objectDocument
+		err := r.objects().FindOneAndUpdate(ctx, filter, update, opts).Decode(&object)
+		if err == nil {
+			return object.ID, token, nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return object.ID, token, err
+		}
+
+		// The object is missing or locked.
+		if _, err := r.objectID(ctx, userID, objectName); err != nil {
+			return object.ID, token, err
+		}
+		if now.After(deadline) {
+			return object.ID, token, repository.ConcurrentUpdateError
+		}
+		if err := backoff(ctx, attempt); err != nil {
+			return object.ID, token, err
+		}
+	}
+}
+
+// unlockObject releases the lock even when the context of the change is
+// done, so that others need not wait for it to expire.
+func (r *MongoDBRepository) unlockObject(ctx context.Context, objectID, token primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "_id", Value: objectID}, {Key: "lock.token", Value: token}}
+	_, err := r.objects().UpdateOne(ctx, filter, bson.D{{Key: "$unset", Value: bson.D{{Key: "lock", Value: ""}}}})
+	return err
+}
+
+// withLockedObject runs fn with the object locked.
+func (r *MongoDBRepository) withLockedObject(ctx context.Context, userID int64, objectName string, fn func(objectID primitive.ObjectID) error) error {
+	objectID, token, err := r.lockObject(ctx, userID, objectName)
+	if err != nil {
+		return err
+	}
+	err = fn(objectID)
+	if unlockErr := r.unlockObject(ctx, objectID, token); err == nil {
+		err = unlockErr
+	}
+	return err
+}
+
+// backoff waits a random time that grows with the attempt, so that waiting
+// changes spread out.
+func backoff(ctx context.Context, attempt int) error {
+	timer := time.NewTimer(time.Duration(rand.Int63n(int64(min(attempt, 10)+1) * int64(5*time.Millisecond))))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// positionedRecord is the id, date and position of a record document.
+type positionedRecord struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Date     time.Time          `bson:"date"`
+	Position int                `bson:"position"`
+}
+
+func (r *MongoDBRepository) positions(ctx context.Context, objectID primitive.ObjectID) ([]positionedRecord, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "position", Value: 1}}).
+		SetProjection(bson.D{{Key: "date", Value: 1}, {Key: "position", Value: 1}})
+	cursor, err := r.records().Find(ctx, bson.D{{Key: "object_id", Value: objectID}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	var records []positionedRecord
+	err = cursor.All(ctx, &records)
+	return records, err
+}
+
+// AddRecord keeps the records sorted by date, as domain.RentObject does, and
+// returns the position of the new record.
+func (r *MongoDBRepository) AddRecord(ctx context.Context, userID int64, objectName string, record domain.Record) (int, error) {
+	positions, err := r.insertRecords(ctx, userID, objectName, []domain.Record{record})
+	if err != nil {
+		return 0, err
+	}
+	return positions[0], nil
+}
+
+func (r *MongoDBRepository) AddRecords(ctx context.Context, userID int64, objectName string, records []domain.Record) error {
+	_, err := r.insertRecords(ctx, userID, objectName, records)
+	return err
+}
+
+// insertRecords adds the records in one transaction, and returns the positions
+// of the new records in ascending order. Positions are unique, so the new
+// records and the records that move are given negative positions first, and
+// then their own.
+func (r *MongoDBRepository) insertRecords(ctx context.Context, userID int64, objectName string, records []domain.Record) ([]int, error) {
+	var positions []int
+	err := r.withLockedObject(ctx, userID, objectName, func(objectID primitive.ObjectID) error {
+		return r.inTransaction(ctx, func(ctx mongo.SessionContext) error {
+			// The transaction may be run again.
+			positions = nil
+			existing, err := r.positions(ctx, objectID)
+			if err != nil {
+				return err
+			}
+
+			added := map[primitive.ObjectID]domain.Record{}
+			for _, record := range records {
+				id := primitive.NewObjectID()
+				added[id] = record
+				existing = append(existing, positionedRecord{ID: id, Date: record.Date, Position: -1})
+			}
+			sort.SliceStable(existing, func(i, j int) bool { return existing[i].Date.Before(existing[j].Date) })
+
+			var writes []mongo.WriteModel
+			for position, p := range existing {
+				record, isNew := added[p.ID]
+				switch {
+				case isNew:
+					positions = append(positions, position)
+					writes = append(writes, mongo.NewInsertOneModel().SetDocument(recordDocument{
+						ID: p.ID, UserID: userID, ObjectID: objectID, Position: -1 - position, Record: record,
+					}))
+				case p.Position != position:
+					writes = append(writes, mongo.NewUpdateOneModel().
+						SetFilter(bson.D{{Key: "_id", Value: p.ID}}).
+						SetUpdate(bson.D{{Key: "$set", Value: bson.D{{Key: "position", Value: -1 - position}}}}))
+				}
+			}
+			if _, err := r.records().BulkWrite(ctx, writes); err != nil {
+				return err
+			}
+
+			_, err = r.records().UpdateMany(ctx,
+				bson.D{{Key: "object_id", Value: objectID}, {Key: "position", Value: bson.D{{Key: "$lt", Value: 0}}}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.D{{Key: "position", Value: bson.D{{Key: "$subtract", Value: bson.A{-1, "$position"}}}}}}}},
+			)
+			return err
+		})
+	})
+	return positions, err
+}
+
+// DeleteRecord moves the last record to the place of the deleted one, as
+// domain.RentObject does.
+func (r *MongoDBRepository) DeleteRecord(ctx context.Context, userID int64, objectName string, recordIndex int) error {
+	return r.withLockedObject(ctx, userID, objectName, func(objectID primitive.ObjectID) error {
+		return r.inTransaction(ctx, func(ctx mongo.SessionContext) error {
+			count, err := r.records().CountDocuments(ctx, bson.D{{Key: "object_id", Value: objectID}})
+			if err != nil {
+				return err
+			}
+			last := int(count) - 1
+			if recordIndex < 0 || recordIndex > last {
+				return domain.RecordNotFoundError
+			}
+
+			if _, err := r.records().DeleteOne(ctx, bson.D{{Key: "object_id", Value: objectID}, {Key: "position", Value: recordIndex}}); err != nil {
+				return err
+			}
+			if recordIndex == last {
+				return nil
+			}
+			_, err = r.records().UpdateOne(ctx,
+				bson.D{{Key: "object_id", Value: objectID}, {Key: "position", Value: last}},
+				bson.D{{Key: "$set", Value: bson.D{{Key: "position", Value: recordIndex}}}},
+			)
+			return err
+		})
+	})
+}
+
+// UpdateRecord sets the changed fields of the record in place.
+func (r *MongoDBRepository) UpdateRecord(ctx context.Context, userID int64, objectName string, recordIndex int, input domain.UpdateRecordInput) error {
+	return r.withLockedObject(ctx, userID, objectName, func(objectID primitive.ObjectID) error {
+		filter := bson.D{{Key: "object_id", Value: objectID}, {Key: "position", Value: recordIndex}}
+		set := recordChanges(input)
+		if len(set) == 0 {
+			n, err := r.records().CountDocuments(ctx, filter)
+			if err == nil && n == 0 {
+				err = domain.RecordNotFoundError
+			}
+			return err
+		}
+
+		result, err := r.records().UpdateOne(ctx, filter, bson.D{{Key: "$set", Value: set}})
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 0 {
+			return domain.RecordNotFoundError
+		}
+		return nil
+	})
+}
+
+// recordChanges are the fields that domain.Record.Update changes.
+func recordChanges(input domain.UpdateRecordInput) bson.D {
+	var set bson.D
+	if input.Date != nil {
+		set = append(set, bson.E{Key: "date", Value: *input.Date})
+	}
+	amounts := []struct {
+		field  string
+		amount *domain.RUB
+	}{
+		{"rent", input.Rent},
+		{"heat", input.Heat},
+		{"exploitation", input.Exploitation},
+		{"mop", input.MOP},
+		{"renovation", input.Renovation},
+		{"tbo", input.TBO},
+		{"electricity", input.Electricity},
+		{"earthrent", input.EarthRent},
+		{"other", input.Other},
+		{"security", input.Security},
+	}
+	for _, a := range amounts {
+		if a.amount != nil {
+			set = append(set, bson.E{Key: a.field, Value: *a.amount})
+		}
+	}
+	return set
+}
@@ -0,0 +1,111 @@
+package mongorep
+
+import (
+	"context"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Match rules of a user are kept in one document of the match_rules
+// collection, so that their order is kept. Each change increments the
+// version of the document, which deletions are conditional on.
+type rulesDocument struct {
+	Rules   []domain.MatchRule `bson:"rules"`
+	Version int64              `bson:"version"`
+}
+
+func (r *MongoDBRepository) matchRules() *mongo.Collection {
+	return r.client.Database(r.Database).Collection("match_rules")
+}
+
+// AddMatchRule pushes the rule in one update, so that concurrent additions
+// are all kept, and its index is the last one of the rules it was pushed to.
+func (r *MongoDBRepository) AddMatchRule(ctx context.Context, userID int64, rule domain.MatchRule) (int, error) {
+	filter := bson.D{{Key: "user_id", Value: userID}}
+	update := bson.D{
+		{Key: "$push", Value: bson.D{{Key: "rules", Value: rule}}},
+		{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var data rulesDocument
+	err := r.matchRules().FindOneAndUpdate(ctx, filter, update, opts).Decode(&data)
+	// Concurrent upserts of the first rule may collide on the unique index,
+	// which older servers do not retry.
+	if mongo.IsDuplicateKeyError(err) {
+		err = r.matchRules().FindOneAndUpdate(ctx, filter, update, opts).Decode(&data)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(data.Rules) - 1, nil
+}
+
+// DeleteMatchRule replaces the rules only if no other change came between
+// reading and writing them, and reads them again otherwise, so that the
+// deleted rule is the one of the index the caller saw.
+func (r *MongoDBRepository) DeleteMatchRule(ctx context.Context, userID int64, ruleIndex int) error {
+	deadline := time.Now().Add(lockLease)
+
+	for attempt := 0; ; attempt++ {
+		data, err := r.rulesDocument(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if ruleIndex < 0 || ruleIndex >= len(data.Rules) {
+			return domain.MatchRuleNotFoundError
+		}
+
+		filter := bson.D{{Key: "user_id", Value: userID}, {Key: "version", Value: data.Version}}
+		if data.Version == 0 {
+			// Documents from before versions.
+			filter[1] = bson.E{Key: "version", Value: bson.D{{Key: "$exists", Value: false}}}
+		}
+		update := bson.D{
+			{Key: "$set", Value: bson.D{{Key: "rules", Value: slices.Delete(data.Rules, ruleIndex, ruleIndex+1)}}},
+			{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+		}
+		result, err := r.matchRules().UpdateOne(ctx, filter, update)
+		if err != nil {
+			return err
+		}
+		if result.MatchedCount == 1 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return repository.ConcurrentUpdateError
+		}
+		if err := backoff(ctx, attempt); err != nil {
+			return err
+		}
+	}
+}
+
+func (r *MongoDBRepository) GetMatchRules(ctx context.Context, userID int64) ([]domain.MatchRule, error) {
+	data, err := r.rulesDocument(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return data.Rules, nil
+}
+
+// rulesDocument returns the rules of the user with their version, which is
+// zero when the user has none.
+func (r *MongoDBRepository) rulesDocument(ctx context.Context, userID int64) (rulesDocument, error) {
+	var data rulesDocument
+	err := r.matchRules().FindOne(ctx, bson.D{{Key: "user_id", Value: userID}}).Decode(&data)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return data, err
+	}
+	if data.Rules == nil {
+		data.Rules = []domain.MatchRule{}
+	}
+	return data, nil
+}
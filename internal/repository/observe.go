@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"rental-server/internal/domain"
+	"time"
+)
+
+// OperationObserver is told of each repository operation by method name,
+// with its duration and error.
+type OperationObserver interface {
+	ObserveOperation(operation string, duration time.Duration, err error)
+}
+
+// WithObserver tells the observer of each operation of the repository.
+func WithObserver(rep RentObjectRepository, observer OperationObserver) RentObjectRepository {
+	return &observedRepository{rep: rep, observer: observer}
+}
+
+type observedRepository struct {
+	rep      RentObjectRepository
+	observer OperationObserver
+}
+
+// observe runs the operation and tells the observer of it.
+func observe[T any](r *observedRepository, operation string, fn func() (T, error)) (T, error) {
+	start := time.Now()
+	result, err := fn()
+	r.observer.ObserveOperation(operation, time.Since(start), err)
+	return result, err
+}
+
+func (r *observedRepository) Add(ctx context.Context, userID int64, object domain.RentObject) error {
+	_, err := observe(r, "Add", func() (any, error) {
+		return nil, r.rep.Add(ctx, userID, object)
+	})
+	return err
+}
+
+func (r *observedRepository) Delete(ctx context.Context, userID int64, objectName string) error {
+	_, err := observe(r, "Delete", func() (any, error) {
+		return nil, r.rep.Delete(ctx, userID, objectName)
+	})
+	return err
+}
+
+func (r *observedRepository) Update(ctx context.Context, userID int64, objectName string, input domain.UpdateRentObjectInput) error {
+	_, err := observe(r, "Update", func() (any, error) {
+		return nil, r.rep.Update(ctx, userID, objectName, input)
+	})
+	return err
+}
+
+func (r *observedRepository) GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	return observe(r, "GetByName", func() (domain.RentObject, error) {
+		return r.rep.GetByName(ctx, userID, objectName)
+	})
+}
+
+func (r *observedRepository) GetByNameInPeriod(ctx context.Context, userID int64, objectName string, period domain.Period) (domain.RentObject, error) {
+	return observe(r, "GetByNameInPeriod", func() (domain.RentObject, error) {
+		return r.rep.GetByNameInPeriod(ctx, userID, objectName, period)
+	})
+}
+
+func (r *observedRepository) GetAll(ctx context.Context, userID int64) ([]domain.RentObject, error) {
+	return observe(r, "GetAll", func() ([]domain.RentObject, error) {
+		return r.rep.GetAll(ctx, userID)
+	})
+}
+
+func (r *observedRepository) GetAllInPeriod(ctx context.Context, userID int64, period domain.Period) ([]domain.RentObject, error) {
+	return observe(r, "GetAllInPeriod", func() ([]domain.RentObject, error) {
+		return r.rep.GetAllInPeriod(ctx, userID, period)
+	})
+}
+
+func (r *observedRepository) FindObjects(ctx context.Context, userID int64, query ObjectQuery) (Page[domain.RentObject], error) {
+	return observe(r, "FindObjects", func() (Page[domain.RentObject], error) {
+		return r.rep.FindObjects(ctx, userID, query)
+	})
+}
+
+func (r *observedRepository) AddRecord(ctx context.Context, userID int64, objectName string, record domain.Record) (int, error) {
+	return observe(r, "AddRecord", func() (int, error) {
+		return r.rep.AddRecord(ctx, userID, objectName, record)
+	})
+}
+
+func (r *observedRepository) AddRecords(ctx context.Context, userID int64, objectName string, records []domain.Record) error {
+	_, err := observe(r, "AddRecords", func() (any, error) {
+		return nil, r.rep.AddRecords(ctx, userID, objectName, records)
+	})
+	return err
+}
+
+func (r *observedRepository) DeleteRecord(ctx context.Context, userID int64, objectName string, recordIndex int) error {
+	_, err := observe(r, "DeleteRecord", func() (any, error) {
+		return nil, r.rep.DeleteRecord(ctx, userID, objectName, recordIndex)
+	})
+	return err
+}
+
+func (r *observedRepository) UpdateRecord(ctx context.Context, userID int64, objectName string, recordIndex int, input domain.UpdateRecordInput) error {
+	_, err := observe(r, "UpdateRecord", func() (any, error) {
+		return nil, r.rep.UpdateRecord(ctx, userID, objectName, recordIndex, input)
+	})
+	return err
+}
+
+func (r *observedRepository) GetRecordByIndex(ctx context.Context, userID int64, objectName string, recordIndex int) (domain.Record, error) {
+	return observe(r, "GetRecordByIndex", func() (domain.Record, error) {
+		return r.rep.GetRecordByIndex(ctx, userID, objectName, recordIndex)
+	})
+}
+
+func (r *observedRepository) GetAllRecords(ctx context.Context, userID int64, objectName string) ([]domain.Record, error) {
+	return observe(r, "GetAllRecords", func() ([]domain.Record, error) {
+		return r.rep.GetAllRecords(ctx, userID, objectName)
+	})
+}
+
+func (r *observedRepository) FindRecords(ctx context.Context, userID int64, objectName string, query RecordQuery) (Page[RecordEntry], error) {
+	return observe(r, "FindRecords", func() (Page[RecordEntry], error) {
+		return r.rep.FindRecords(ctx, userID, objectName, query)
+	})
+}
+
+func (r *observedRepository) TotalsByObject(ctx context.Context, userID int64, query TotalsQuery) ([]ObjectTotals, error) {
+	return observe(r, "TotalsByObject", func() ([]ObjectTotals, error) {
+		return r.rep.TotalsByObject(ctx, userID, query)
+	})
+}
+
+func (r *observedRepository) TotalsByPeriod(ctx context.Context, userID int64, query TotalsQuery) ([]PeriodTotals, error) {
+	return observe(r, "TotalsByPeriod", func() ([]PeriodTotals, error) {
+		return r.rep.TotalsByPeriod(ctx, userID, query)
+	})
+}
+
+func (r *observedRepository) TotalsByCategory(ctx context.Context, userID int64, query TotalsQuery) ([]CategoryTotal, error) {
+	return observe(r, "TotalsByCategory", func() ([]CategoryTotal, error) {
+		return r.rep.TotalsByCategory(ctx, userID, query)
+	})
+}
+
+func (r *observedRepository) AddMatchRule(ctx context.Context, userID int64, rule domain.MatchRule) (int, error) {
+	return observe(r, "AddMatchRule", func() (int, error) {
+		return r.rep.AddMatchRule(ctx, userID, rule)
+	})
+}
+
+func (r *observedRepository) DeleteMatchRule(ctx context.Context, userID int64, ruleIndex int) error {
+	_, err := observe(r, "DeleteMatchRule", func() (any, error) {
+		return nil, r.rep.DeleteMatchRule(ctx, userID, ruleIndex)
+	})
+	return err
+}
+
+func (r *observedRepository) GetMatchRules(ctx context.Context, userID int64) ([]domain.MatchRule, error) {
+	return observe(r, "GetMatchRules", func() ([]domain.MatchRule, error) {
+		return r.rep.GetMatchRules(ctx, userID)
+	})
+}
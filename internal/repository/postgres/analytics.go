@@ -0,0 +1,144 @@
+package postgresrep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// totalsColumns sums up the records r into the fields of repository.Totals,
+// which are scanned by scanTotals.
+const totalsColumns = "coalesce(sum(r.rent), 0), coalesce(sum(" + expensesExpression + "), 0), count(r.id)"
+
+func scanTotals(row pgx.Row, totals *repository.Totals, dest ...any) error {
+	if err := row.Scan(append(dest, &totals.Income, &totals.Expenses, &totals.Records)...); err != nil {
+		return err
+	}
+	totals.Profit = totals.Income - totals.Expenses
+	return nil
+}
+
+// recordsWhere matches the records r of the query by the index of object
+// and date, with the arguments $1 to $3.
+func (r *PostgresRepository) recordsWhere(ctx context.Context, userID int64, query repository.TotalsQuery) (string, []any, error) {
+	where := "r.object_id IN (SELECT id FROM rent_objects WHERE user_id = $1)"
+	args := []any{userID}
+	if query.ObjectName != "" {
+		var objectID int64
+		err := r.pool.QueryRow(ctx, "SELECT id FROM rent_objects WHERE user_id = $1 AND name = $2", userID, query.ObjectName).Scan(&objectID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil, repository.ObjectNotFoundError
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		where, args = "r.object_id = $1", []any{objectID}
+	}
+	where += " AND ($2::timestamptz IS NULL OR r.date >= $2) AND ($3::timestamptz IS NULL OR r.date < $3)"
+	return where, append(args, query.From, query.To), nil
+}
+
+// TotalsByObject sums up the records of each object in the database. Names
+// are compared bytewise, as in repository.SumByObject.
+func (r *PostgresRepository) TotalsByObject(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.ObjectTotals, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT o.name, `+totalsColumns+` FROM rent_objects o
+		LEFT JOIN records r ON r.object_id = o.id
+			AND ($3::timestamptz IS NULL OR r.date >= $3)
+			AND ($4::timestamptz IS NULL OR r.date < $4)
+		WHERE o.user_id = $1 AND ($2 = '' OR o.name = $2)
+		GROUP BY o.id, o.name
+		ORDER BY o.name COLLATE "C"`,
+		userID, query.ObjectName, query.From, query.To,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := []repository.ObjectTotals{}
+	for rows.Next() {
+		var object repository.ObjectTotals
+		if err := scanTotals(rows, &object.Totals, &object.Name); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if query.ObjectName != "" && len(objects) == 0 {
+		return nil, repository.ObjectNotFoundError
+	}
+	return objects, nil
+}
+
+// TotalsByPeriod sums up the records of each period in the database, whose
+// start is truncated in UTC as in repository.PeriodStart.
+func (r *PostgresRepository) TotalsByPeriod(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.PeriodTotals, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+	where, args, err := r.recordsWhere(ctx, userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT date_trunc($4, r.date, 'UTC') AS start, `+totalsColumns+` FROM records r
+		WHERE `+where+`
+		GROUP BY start
+		ORDER BY start`,
+		append(args, string(query.Granularity))...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := []repository.PeriodTotals{}
+	for rows.Next() {
+		var period repository.PeriodTotals
+		if err := scanTotals(rows, &period.Totals, &period.Start); err != nil {
+			return nil, err
+		}
+		period.Start = period.Start.UTC()
+		periods = append(periods, period)
+	}
+	return periods, rows.Err()
+}
+
+// TotalsByCategory sums up each amount column of the records in the
+// database.
+func (r *PostgresRepository) TotalsByCategory(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.CategoryTotal, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+	where, args, err := r.recordsWhere(ctx, userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Categories are named after the columns.
+	columns := make([]string, len(repository.Categories))
+	sums := make([]domain.RUB, len(repository.Categories))
+	dest := make([]any, len(repository.Categories))
+	for i, category := range repository.Categories {
+		columns[i] = fmt.Sprintf("coalesce(sum(r.%s), 0)", category)
+		dest[i] = &sums[i]
+	}
+	err = r.pool.QueryRow(ctx, "SELECT "+strings.Join(columns, ", ")+" FROM records r WHERE "+where, args...).Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	return repository.NewCategoryTotals(sums), nil
+}
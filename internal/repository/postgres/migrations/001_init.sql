@@ -0,0 +1,42 @@
+CREATE TABLE rent_objects (
+	id          BIGSERIAL PRIMARY KEY,
+	user_id     BIGINT NOT NULL,
+	name        TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	area        DOUBLE PRECISION NOT NULL DEFAULT 0,
+	CONSTRAINT rent_objects_user_id_name_key UNIQUE (user_id, name)
+);
+
+-- position is the index of the record in the object. Positions are swapped
+-- by record changes, so their uniqueness is checked at commit.
+CREATE TABLE records (
+	id           BIGSERIAL PRIMARY KEY,
+	object_id    BIGINT NOT NULL REFERENCES rent_objects (id) ON DELETE CASCADE,
+	position     INTEGER NOT NULL,
+	date         TIMESTAMPTZ NOT NULL,
+	rent         DOUBLE PRECISION NOT NULL DEFAULT 0,
+	heat         DOUBLE PRECISION NOT NULL DEFAULT 0,
+	exploitation DOUBLE PRECISION NOT NULL DEFAULT 0,
+	mop          DOUBLE PRECISION NOT NULL DEFAULT 0,
+	renovation   DOUBLE PRECISION NOT NULL DEFAULT 0,
+	tbo          DOUBLE PRECISION NOT NULL DEFAULT 0,
+	electricity  DOUBLE PRECISION NOT NULL DEFAULT 0,
+	earth_rent   DOUBLE PRECISION NOT NULL DEFAULT 0,
+	other        DOUBLE PRECISION NOT NULL DEFAULT 0,
+	security     DOUBLE PRECISION NOT NULL DEFAULT 0,
+	CONSTRAINT records_object_id_position_key UNIQUE (object_id, position) DEFERRABLE INITIALLY DEFERRED
+);
+
+CREATE INDEX records_object_id_date_idx ON records (object_id, date);
+
+CREATE TABLE match_rules (
+	id           BIGSERIAL PRIMARY KEY,
+	user_id      BIGINT NOT NULL,
+	position     INTEGER NOT NULL,
+	object_name  TEXT NOT NULL,
+	field        TEXT NOT NULL,
+	direction    TEXT NOT NULL DEFAULT '',
+	counterparty TEXT NOT NULL DEFAULT '',
+	purpose      TEXT NOT NULL DEFAULT '',
+	CONSTRAINT match_rules_user_id_position_key UNIQUE (user_id, position) DEFERRABLE INITIALLY DEFERRED
+);
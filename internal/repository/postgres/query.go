@@ -0,0 +1,190 @@
+package postgresrep
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// expensesExpression and profitExpression compute the expenses and profit
+// of a record row the same way as domain.Record does.
+const (
+	expensesExpression = "heat + exploitation + mop + renovation + tbo + electricity + earth_rent + other + security"
+	profitExpression   = "rent - (" + expensesExpression + ")"
+)
+
+// afterCursor matches rows that come after the cursor values $n and $n+1 in
+// the order of column and then tieColumn.
+func afterCursor(column, tieColumn string, n int, descending bool) string {
+	op := ">"
+	if descending {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s ($%d, $%d)", column, tieColumn, op, n, n+1)
+}
+
+func sortDirection(descending bool) string {
+	if descending {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// FindObjects sums up the profit of each object over the period in the
+// query, in the order of records so that the profit of a cursor is the same
+// on the next page, and reads the records of the page only. Names are
+// compared bytewise, as in repository.PageObjects.
+func (r *PostgresRepository) FindObjects(ctx context.Context, userID int64, query repository.ObjectQuery) (repository.Page[domain.RentObject], error) {
+	var page repository.Page[domain.RentObject]
+	cursor, err := query.Normalize()
+	if err != nil {
+		return page, err
+	}
+
+	sortColumn := map[repository.SortField]string{
+		repository.SortByName:   `name COLLATE "C"`,
+		repository.SortByArea:   "area",
+		repository.SortByProfit: "profit",
+	}[query.SortBy]
+
+	args := []any{userID, query.NamePrefix, query.From, query.To, query.MinProfit, query.MaxProfit}
+	sql := `
+		SELECT id, name, description, area, profit FROM (
+			SELECT o.id, o.name, o.description, o.area, coalesce((
+				SELECT sum(` + profitExpression + ` ORDER BY position) FROM records
+				WHERE records.object_id = o.id
+					AND ($3::timestamptz IS NULL OR records.date >= $3)
+					AND ($4::timestamptz IS NULL OR records.date < $4)
+			), 0) AS profit
+			FROM rent_objects o
+			WHERE o.user_id = $1 AND starts_with(o.name, $2)
+		) objects
+		WHERE ($5::float8 IS NULL OR profit >= $5) AND ($6::float8 IS NULL OR profit <= $6)`
+	if cursor != nil {
+		value := map[repository.SortField]any{
+			repository.SortByName:   cursor.Name,
+			repository.SortByArea:   cursor.Area,
+			repository.SortByProfit: cursor.Profit,
+		}[query.SortBy]
+		sql += " AND " + afterCursor(sortColumn, `name COLLATE "C"`, len(args)+1, query.Descending)
+		args = append(args, value, cursor.Name)
+	}
+	direction := sortDirection(query.Descending)
+	sql += fmt.Sprintf(` ORDER BY %s %s, name COLLATE "C" %s LIMIT %d`, sortColumn, direction, direction, query.Limit+1)
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return page, err
+	}
+	page.Items = []domain.RentObject{}
+	var ids []int64
+	var profits []domain.RUB
+	for rows.Next() {
+		var id int64
+		var profit domain.RUB
+		object := domain.NewRentObject("", "", 0)
+		if err := rows.Scan(&id, &object.Name, &object.Description, &object.Area, &profit); err != nil {
+			rows.Close()
+			return page, err
+		}
+		ids = append(ids, id)
+		profits = append(profits, profit)
+		page.Items = append(page.Items, object)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Items) > query.Limit {
+		last := page.Items[query.Limit-1]
+		page.NextCursor = repository.EncodeCursor(repository.ObjectCursor{
+			SortBy: query.SortBy,
+			Name:   last.Name,
+			Area:   last.Area,
+			Profit: profits[query.Limit-1],
+		})
+		page.Items, ids = page.Items[:query.Limit], ids[:query.Limit]
+	}
+	return page, r.addRecords(ctx, page.Items, ids, domain.Period{})
+}
+
+// FindRecords filters and sorts the records of the object in the database,
+// and reads the records of the page only.
+func (r *PostgresRepository) FindRecords(ctx context.Context, userID int64, objectName string, query repository.RecordQuery) (repository.Page[repository.RecordEntry], error) {
+	var page repository.Page[repository.RecordEntry]
+	cursor, err := query.Normalize()
+	if err != nil {
+		return page, err
+	}
+
+	var objectID int64
+	err = r.pool.QueryRow(ctx, "SELECT id FROM rent_objects WHERE user_id = $1 AND name = $2", userID, objectName).Scan(&objectID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return page, repository.ObjectNotFoundError
+	}
+	if err != nil {
+		return page, err
+	}
+
+	sortColumn := map[repository.SortField]string{
+		repository.SortByDate:   "date",
+		repository.SortByAmount: "amount",
+	}[query.SortBy]
+
+	args := []any{objectID, query.From, query.To, query.MinAmount, query.MaxAmount}
+	sql := `
+		SELECT position, amount, ` + recordColumns + ` FROM (
+			SELECT position, ` + profitExpression + ` AS amount, ` + recordColumns + ` FROM records
+			WHERE object_id = $1
+				AND ($2::timestamptz IS NULL OR date >= $2)
+				AND ($3::timestamptz IS NULL OR date < $3)
+		) records
+		WHERE ($4::float8 IS NULL OR amount >= $4) AND ($5::float8 IS NULL OR amount <= $5)`
+	if cursor != nil {
+		value := map[repository.SortField]any{
+			repository.SortByDate:   cursor.Date,
+			repository.SortByAmount: cursor.Amount,
+		}[query.SortBy]
+		sql += " AND " + afterCursor(sortColumn, "position", len(args)+1, query.Descending)
+		args = append(args, value, cursor.Index)
+	}
+	direction := sortDirection(query.Descending)
+	sql += fmt.Sprintf(" ORDER BY %s %s, position %s LIMIT %d", sortColumn, direction, direction, query.Limit+1)
+
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	page.Items = []repository.RecordEntry{}
+	var amounts []domain.RUB
+	for rows.Next() {
+		var entry repository.RecordEntry
+		var amount domain.RUB
+		if err := scanRecord(rows, &entry.Record, &entry.Index, &amount); err != nil {
+			return page, err
+		}
+		page.Items = append(page.Items, entry)
+		amounts = append(amounts, amount)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Items) > query.Limit {
+		last := page.Items[query.Limit-1]
+		page.NextCursor = repository.EncodeCursor(repository.RecordCursor{
+			SortBy: query.SortBy,
+			Index:  last.Index,
+			Date:   last.Date,
+			Amount: amounts[query.Limit-1],
+		})
+		page.Items = page.Items[:query.Limit]
+	}
+	return page, nil
+}
@@ -0,0 +1,75 @@
+package postgresrep
+
+import (
+	"context"
+	"fmt"
+	"rental-server/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Match rules of a user keep their order in the position column.
+func (r *PostgresRepository) AddMatchRule(ctx context.Context, userID int64, rule domain.MatchRule) (int, error) {
+	var index int
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockRules(ctx, tx, userID); err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx, `
+			INSERT INTO match_rules (user_id, position, object_name, field, direction, counterparty, purpose)
+			SELECT $1, count(*), $2, $3, $4, $5, $6 FROM match_rules WHERE user_id = $1
+			RETURNING position`,
+			userID, rule.ObjectName, rule.Field, rule.Direction, rule.Counterparty, rule.Purpose,
+		).Scan(&index)
+	})
+	return index, err
+}
+
+func (r *PostgresRepository) DeleteMatchRule(ctx context.Context, userID int64, ruleIndex int) error {
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		if err := lockRules(ctx, tx, userID); err != nil {
+			return err
+		}
+		tag, err := tx.Exec(ctx, "DELETE FROM match_rules WHERE user_id = $1 AND position = $2", userID, ruleIndex)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return domain.MatchRuleNotFoundError
+		}
+		_, err = tx.Exec(ctx, "UPDATE match_rules SET position = position - 1 WHERE user_id = $1 AND position > $2", userID, ruleIndex)
+		return err
+	})
+}
+
+func (r *PostgresRepository) GetMatchRules(ctx context.Context, userID int64) ([]domain.MatchRule, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT object_name, field, direction, counterparty, purpose FROM match_rules
+		WHERE user_id = $1 ORDER BY position`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []domain.MatchRule{}
+	for rows.Next() {
+		var rule domain.MatchRule
+		if err := rows.Scan(&rule.ObjectName, &rule.Field, &rule.Direction, &rule.Counterparty, &rule.Purpose); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// rulesLock is the class of the advisory locks of match rules.
+const rulesLock = 7_010_002
+
+// lockRules serializes the changes of the rules of a user until the
+// transaction ends, which a row lock cannot do for a user without rules.
+func lockRules(ctx context.Context, tx pgx.Tx, userID int64) error {
+	_, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", rulesLock, fmt.Sprint(userID))
+	return err
+}
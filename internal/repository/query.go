@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"rental-server/internal/domain"
+	"sort"
+	"strings"
+	"time"
+)
+
+var InvalidQueryError = errors.New("Invalid query")
+
+const DefaultPageLimit = 50
+const MaxPageLimit = 1000
+
+type SortField string
+
+const (
+	SortByName   SortField = "name"
+	SortByArea   SortField = "area"
+	SortByProfit SortField = "profit"
+	SortByDate   SortField = "date"
+	// SortByAmount sorts records by their profit, i.e. income minus expenses.
+	SortByAmount SortField = "amount"
+)
+
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// RecordEntry is a record with its index in the object, so that records of a
+// page sorted by amount can still be updated or deleted.
+type RecordEntry struct {
+	Index int `json:"index"`
+	domain.Record
+}
+
+// ObjectQuery filters and sorts objects by their profit over Period.
+type ObjectQuery struct {
+	domain.Period
+	NamePrefix string
+	MinProfit  *domain.RUB
+	MaxProfit  *domain.RUB
+	SortBy     SortField
+	Descending bool
+	Cursor     string
+	Limit      int
+}
+
+type RecordQuery struct {
+	domain.Period
+	MinAmount  *domain.RUB
+	MaxAmount  *domain.RUB
+	SortBy     SortField
+	Descending bool
+	Cursor     string
+	Limit      int
+}
+
+// ObjectCursor holds the sort keys of the last object of a page.
+type ObjectCursor struct {
+	SortBy SortField  `json:"s"`
+	Name   string     `json:"n"`
+	Area   float64    `json:"a,omitempty"`
+	Profit domain.RUB `json:"p,omitempty"`
+}
+
+// RecordCursor holds the sort keys of the last record of a page.
+type RecordCursor struct {
+	SortBy SortField  `json:"s"`
+	Index  int        `json:"i"`
+	Date   time.Time  `json:"d"`
+	Amount domain.RUB `json:"a,omitempty"`
+}
+
+// Normalize fills in defaults and validates the query. The returned cursor
+// is nil for the first page.
+func (q *ObjectQuery) Normalize() (*ObjectCursor, error) {
+	if q.SortBy == "" {
+		q.SortBy = SortByName
+	}
+	if q.SortBy != SortByName && q.SortBy != SortByArea && q.SortBy != SortByProfit {
+		return nil, InvalidQueryError
+	}
+	if err := normalizeLimit(&q.Limit); err != nil {
+		return nil, err
+	}
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	var cursor ObjectCursor
+	if err := decodeCursor(q.Cursor, &cursor); err != nil || cursor.SortBy != q.SortBy {
+		return nil, InvalidQueryError
+	}
+	return &cursor, nil
+}
+
+func (q *RecordQuery) Normalize() (*RecordCursor, error) {
+	if q.SortBy == "" {
+		q.SortBy = SortByDate
+	}
+	if q.SortBy != SortByDate && q.SortBy != SortByAmount {
+		return nil, InvalidQueryError
+	}
+	if err := normalizeLimit(&q.Limit); err != nil {
+		return nil, err
+	}
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	var cursor RecordCursor
+	if err := decodeCursor(q.Cursor, &cursor); err != nil || cursor.SortBy != q.SortBy {
+		return nil, InvalidQueryError
+	}
+	return &cursor, nil
+}
+
+func normalizeLimit(limit *int) error {
+	if *limit == 0 {
+		*limit = DefaultPageLimit
+	}
+	if *limit < 0 || *limit > MaxPageLimit {
+		return InvalidQueryError
+	}
+	return nil
+}
+
+func EncodeCursor(cursor any) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string, cursor any) error {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, cursor)
+}
+
+func NewObjectCursor(sortBy SortField, object domain.RentObject) ObjectCursor {
+	return ObjectCursor{SortBy: sortBy, Name: object.Name, Area: object.Area, Profit: object.Profit()}
+}
+
+func NewRecordCursor(sortBy SortField, entry RecordEntry) RecordCursor {
+	return RecordCursor{SortBy: sortBy, Index: entry.Index, Date: entry.Date, Amount: entry.Profit()}
+}
+
+// PageObjects applies the query to objects in memory. It is meant for
+// repositories that can't push the query down to the storage.
+func PageObjects(objects []domain.RentObject, query ObjectQuery) (Page[domain.RentObject], error) {
+	cursor, err := query.Normalize()
+	if err != nil {
+		return Page[domain.RentObject]{}, err
+	}
+
+	type entry struct {
+		object domain.RentObject
+		key    ObjectCursor
+	}
+
+	var filtered []entry
+	for _, object := range objects {
+		key := NewObjectCursor(query.SortBy, object.InPeriod(query.Period))
+		if !strings.HasPrefix(object.Name, query.NamePrefix) || !inRange(key.Profit, query.MinProfit, query.MaxProfit) {
+			continue
+		}
+		if cursor != nil {
+			c := compareObjects(query.SortBy, key, *cursor)
+			if (!query.Descending && c <= 0) || (query.Descending && c >= 0) {
+				continue
+			}
+		}
+		filtered = append(filtered, entry{object, key})
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if query.Descending {
+			return compareObjects(query.SortBy, filtered[i].key, filtered[j].key) > 0
+		}
+		return compareObjects(query.SortBy, filtered[i].key, filtered[j].key) < 0
+	})
+
+	page := newPage(filtered, query.Limit, func(e entry) string {
+		return EncodeCursor(e.key)
+	})
+
+	result := Page[domain.RentObject]{Items: []domain.RentObject{}, NextCursor: page.NextCursor}
+	for _, e := range page.Items {
+		result.Items = append(result.Items, e.object)
+	}
+	return result, nil
+}
+
+// PageRecords applies the query to records in memory. The index of a record
+// is its position in records.
+func PageRecords(records []domain.Record, query RecordQuery) (Page[RecordEntry], error) {
+	cursor, err := query.Normalize()
+	if err != nil {
+		return Page[RecordEntry]{}, err
+	}
+
+	compare := func(a, b RecordEntry) int {
+		return compareRecords(query.SortBy, NewRecordCursor(query.SortBy, a), NewRecordCursor(query.SortBy, b))
+	}
+
+	var filtered []RecordEntry
+	for i, record := range records {
+		entry := RecordEntry{Index: i, Record: record}
+		if !query.Contains(record.Date) || !inRange(record.Profit(), query.MinAmount, query.MaxAmount) {
+			continue
+		}
+		if cursor != nil {
+			c := compareRecords(query.SortBy, NewRecordCursor(query.SortBy, entry), *cursor)
+			if (!query.Descending && c <= 0) || (query.Descending && c >= 0) {
+				continue
+			}
+		}
+		filtered = append(filtered, entry)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if query.Descending {
+			return compare(filtered[i], filtered[j]) > 0
+		}
+		return compare(filtered[i], filtered[j]) < 0
+	})
+
+	return newPage(filtered, query.Limit, func(entry RecordEntry) string {
+		return EncodeCursor(NewRecordCursor(query.SortBy, entry))
+	}), nil
+}
+
+func newPage[T any](items []T, limit int, cursor func(T) string) Page[T] {
+	page := Page[T]{Items: items}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		page.NextCursor = cursor(page.Items[limit-1])
+	}
+	return page
+}
+
+func compareObjects(sortBy SortField, a, b ObjectCursor) int {
+	var c int
+	switch sortBy {
+	case SortByArea:
+		c = compareOrdered(a.Area, b.Area)
+	case SortByProfit:
+		c = compareOrdered(a.Profit, b.Profit)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.Name, b.Name)
+}
+
+func compareRecords(sortBy SortField, a, b RecordCursor) int {
+	var c int
+	switch sortBy {
+	case SortByDate:
+		c = a.Date.Compare(b.Date)
+	case SortByAmount:
+		c = compareOrdered(a.Amount, b.Amount)
+	}
+	if c != 0 {
+		return c
+	}
+	return compareOrdered(a.Index, b.Index)
+}
+
+func compareOrdered[T int | float64 | domain.RUB](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func inRange(value domain.RUB, min, max *domain.RUB) bool {
+	if min != nil && value < *min {
+		return false
+	}
+	if max != nil && value > *max {
+		return false
+	}
+	return true
+}
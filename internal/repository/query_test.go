@@ -0,0 +1,149 @@
+package repository_test
+
+import (
+	"fmt"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newObject(name string, area float64, profit domain.RUB) domain.RentObject {
+	object := domain.NewRentObject(name, "", area)
+	object.AddRecord(domain.Record{Rent: profit})
+	return object
+}
+
+func names(objects []domain.RentObject) []string {
+	var result []string
+	for _, object := range objects {
+		result = append(result, object.Name)
+	}
+	return result
+}
+
+func TestPageObjects(t *testing.T) {
+	objects := []domain.RentObject{
+		newObject("b", 30, 100),
+		newObject("a", 10, 300),
+		newObject("ab", 20, 200),
+		newObject("c", 20, 0),
+	}
+
+	t.Run("Should sort by name by default", func(t *testing.T) {
+		page, err := repository.PageObjects(objects, repository.ObjectQuery{})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"a", "ab", "b", "c"}, names(page.Items))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Should sort by area breaking ties by name", func(t *testing.T) {
+		page, err := repository.PageObjects(objects, repository.ObjectQuery{SortBy: repository.SortByArea, Descending: true})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"b", "c", "ab", "a"}, names(page.Items))
+	})
+
+	t.Run("Should filter by name prefix and profit", func(t *testing.T) {
+		minProfit, maxProfit := domain.RUB(100), domain.RUB(250)
+		page, err := repository.PageObjects(objects, repository.ObjectQuery{NamePrefix: "a", MinProfit: &minProfit, MaxProfit: &maxProfit})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"ab"}, names(page.Items))
+	})
+
+	t.Run("Should sort by profit over the period", func(t *testing.T) {
+		from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		recent := newObject("recent", 10, 0)
+		recent.AddRecord(domain.Record{Date: from, Rent: 50})
+
+		page, err := repository.PageObjects(append([]domain.RentObject{recent}, objects...), repository.ObjectQuery{
+			Period: domain.Period{From: &from}, SortBy: repository.SortByProfit, Descending: true, Limit: 1,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"recent"}, names(page.Items))
+		assert.Equal(t, domain.RUB(0), page.Items[0].Records[0].Profit(), "objects are returned with all their records")
+	})
+
+	t.Run("Should walk through all pages with cursor", func(t *testing.T) {
+		query := repository.ObjectQuery{SortBy: repository.SortByProfit, Limit: 3}
+		var got []string
+		for {
+			page, err := repository.PageObjects(objects, query)
+			if !assert.NoError(t, err) {
+				t.Fatal(err)
+			}
+			got = append(got, names(page.Items)...)
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+		assert.Equal(t, []string{"c", "b", "ab", "a"}, got)
+	})
+
+	t.Run("Should reject invalid queries", func(t *testing.T) {
+		for _, query := range []repository.ObjectQuery{
+			{SortBy: "date"},
+			{Limit: repository.MaxPageLimit + 1},
+			{Cursor: "garbage"},
+			{Cursor: repository.EncodeCursor(repository.ObjectCursor{SortBy: repository.SortByArea}), SortBy: repository.SortByName},
+		} {
+			_, err := repository.PageObjects(objects, query)
+			assert.ErrorIs(t, err, repository.InvalidQueryError, "query %+v", query)
+		}
+	})
+}
+
+func TestPageRecords(t *testing.T) {
+	var records []domain.Record
+	for month := 1; month <= 12; month++ {
+		records = append(records, domain.Record{
+			Date: time.Date(2025, time.Month(month), 1, 0, 0, 0, 0, time.UTC),
+			Rent: domain.RUB(month % 4 * 100),
+		})
+	}
+
+	t.Run("Should filter by date range and keep record indexes", func(t *testing.T) {
+		from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC)
+
+		page, err := repository.PageRecords(records, repository.RecordQuery{Period: domain.Period{From: &from, To: &to}})
+		assert.NoError(t, err)
+		assert.Equal(t, []repository.RecordEntry{{Index: 2, Record: records[2]}, {Index: 3, Record: records[3]}}, page.Items)
+	})
+
+	t.Run("Should filter by amount", func(t *testing.T) {
+		minAmount := domain.RUB(300)
+		page, err := repository.PageRecords(records, repository.RecordQuery{MinAmount: &minAmount})
+		assert.NoError(t, err)
+
+		var indexes []int
+		for _, entry := range page.Items {
+			indexes = append(indexes, entry.Index)
+		}
+		assert.Equal(t, []int{2, 6, 10}, indexes)
+	})
+
+	t.Run("Should walk through all pages sorted by amount", func(t *testing.T) {
+		query := repository.RecordQuery{SortBy: repository.SortByAmount, Descending: true, Limit: 5}
+		var got []string
+		for {
+			page, err := repository.PageRecords(records, query)
+			if !assert.NoError(t, err) {
+				t.Fatal(err)
+			}
+			for _, entry := range page.Items {
+				got = append(got, fmt.Sprintf("%v:%d", entry.Rent, entry.Index))
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+		assert.Equal(t, []string{
+			"300:10", "300:6", "300:2", "200:9", "200:5", "200:1",
+			"100:8", "100:4", "100:0", "0:11", "0:7", "0:3",
+		}, got)
+	})
+}
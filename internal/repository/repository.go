@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"errors"
 	"rental-server/internal/domain"
 )
@@ -8,16 +9,63 @@ import (
 var ObjectNotFoundError = errors.New("Object not found")
 var ObjectAlreadyExists = errors.New("Object exists")
 
+// ConcurrentUpdateError is returned when other changes kept an object busy
+// while a change of it waited, and the change can be tried again later.
+var ConcurrentUpdateError = errors.New("Object changed concurrently")
+
+// Methods stop when the context is done, and return its error, which may be
+// wrapped by the storage driver.
 type RentObjectRepository interface {
-	Add(userID int64, object domain.RentObject) error
-	Delete(userID int64, objectName string) error
-	Update(userID int64, objectName string, object domain.UpdateRentObjectInput) error
-	GetByName(userID int64, objectName string) (domain.RentObject, error)
-	GetAll(userID int64) ([]domain.RentObject, error)
-
-	AddRecord(userID int64, objectName string, record domain.Record) (int, error)
-	DeleteRecord(userID int64, objectName string, recordIndex int) error
-	UpdateRecord(userID int64, objectName string, recordIndex int, record domain.UpdateRecordInput) error
-	GetRecordByIndex(userID int64, objectName string, recordIndex int) (domain.Record, error)
-	GetAllRecords(userID int64, objectName string) ([]domain.Record, error)
+	Add(ctx context.Context, userID int64, object domain.RentObject) error
+	Delete(ctx context.Context, userID int64, objectName string) error
+	Update(ctx context.Context, userID int64, objectName string, object domain.UpdateRentObjectInput) error
+	GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error)
+	// GetByNameInPeriod returns the object with only the records of the
+	// period, which lets storages read only those.
+	GetByNameInPeriod(ctx context.Context, userID int64, objectName string, period domain.Period) (domain.RentObject, error)
+	GetAll(ctx context.Context, userID int64) ([]domain.RentObject, error)
+	// GetAllInPeriod returns the objects with only the records of the
+	// period, as GetByNameInPeriod does.
+	GetAllInPeriod(ctx context.Context, userID int64, period domain.Period) ([]domain.RentObject, error)
+	FindObjects(ctx context.Context, userID int64, query ObjectQuery) (Page[domain.RentObject], error)
+
+	AddRecord(ctx context.Context, userID int64, objectName string, record domain.Record) (int, error)
+	// AddRecords adds the records as AddRecord does, all of them or none,
+	// e.g. for imports that must not be left halfway.
+	AddRecords(ctx context.Context, userID int64, objectName string, records []domain.Record) error
+	DeleteRecord(ctx context.Context, userID int64, objectName string, recordIndex int) error
+	UpdateRecord(ctx context.Context, userID int64, objectName string, recordIndex int, record domain.UpdateRecordInput) error
+	GetRecordByIndex(ctx context.Context, userID int64, objectName string, recordIndex int) (domain.Record, error)
+	GetAllRecords(ctx context.Context, userID int64, objectName string) ([]domain.Record, error)
+	FindRecords(ctx context.Context, userID int64, objectName string, query RecordQuery) (Page[RecordEntry], error)
+
+	// Totals are summed up by the storage where it can, so that reports need
+	// not load every object.
+	TotalsByObject(ctx context.Context, userID int64, query TotalsQuery) ([]ObjectTotals, error)
+	TotalsByPeriod(ctx context.Context, userID int64, query TotalsQuery) ([]PeriodTotals, error)
+	TotalsByCategory(ctx context.Context, userID int64, query TotalsQuery) ([]CategoryTotal, error)
+
+	// Match rules are kept in the order they were added, which is the order
+	// they are tried in.
+	AddMatchRule(ctx context.Context, userID int64, rule domain.MatchRule) (int, error)
+	DeleteMatchRule(ctx context.Context, userID int64, ruleIndex int) error
+	GetMatchRules(ctx context.Context, userID int64) ([]domain.MatchRule, error)
+}
+
+// Counts are the numbers of objects and records of all users.
+type Counts struct {
+	Objects int64
+	Records int64
+}
+
+// Counter is implemented by storages that count what they keep, e.g. for
+// metrics.
+type Counter interface {
+	Count(ctx context.Context) (Counts, error)
+}
+
+// Pinger is implemented by storages that can check that they are reachable,
+// e.g. for readiness checks.
+type Pinger interface {
+	Ping(ctx context.Context) error
 }
@@ -0,0 +1,460 @@
+// Package repositorytest is a conformance suite that every
+// repository.RentObjectRepository must pass, so that storages can be swapped
+// without changing the behaviour of the service.
+package repositorytest
+
+import (
+	"context"
+	"fmt"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var ctx = context.Background()
+
+const userID int64 = 1
+
+// Run runs the suite. newRepository returns an empty repository for each
+// test, and may skip the test when the storage is not available.
+func Run(t *testing.T, newRepository func(t *testing.T) repository.RentObjectRepository) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, rep repository.RentObjectRepository)
+	}{
+		{"Objects", testObjects},
+		{"Update", testUpdate},
+		{"Records", testRecords},
+		{"AddRecords", testAddRecords},
+		{"GetByNameInPeriod", testGetByNameInPeriod},
+		{"GetAllInPeriod", testGetAllInPeriod},
+		{"FindObjects", testFindObjects},
+		{"FindRecords", testFindRecords},
+		{"Totals", testTotals},
+		{"MatchRules", testMatchRules},
+		{"Count", testCount},
+		{"Ping", testPing},
+		{"Canceled", testCanceled},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+func date(month time.Month) time.Time {
+	return time.Date(2024, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// utc drops the location of dates, which storages may not keep.
+func utc(records []domain.Record) []domain.Record {
+	result := []domain.Record{}
+	for _, record := range records {
+		record.Date = record.Date.UTC()
+		result = append(result, record)
+	}
+	return result
+}
+
+func assertObject(t *testing.T, want, got domain.RentObject) {
+	t.Helper()
+	assert.Equal(t, want.Name, got.Name)
+	assert.Equal(t, want.Description, got.Description)
+	assert.Equal(t, want.Area, got.Area)
+	assert.Equal(t, utc(want.Records), utc(got.Records))
+}
+
+func newObject(name string, records ...domain.Record) domain.RentObject {
+	object := domain.NewRentObject(name, "Description of "+name, 100)
+	for _, record := range records {
+		object.AddRecord(record)
+	}
+	return object
+}
+
+func testObjects(t *testing.T, rep repository.RentObjectRepository) {
+	object := newObject("Shop", domain.Record{Date: date(time.January), Rent: 1000, Heat: 100})
+	require.NoError(t, rep.Add(ctx, userID, object))
+
+	got, err := rep.GetByName(ctx, userID, object.Name)
+	require.NoError(t, err)
+	assertObject(t, object, got)
+
+	err = rep.Add(ctx, userID, newObject("Shop"))
+	assert.ErrorIs(t, err, repository.ObjectAlreadyExists)
+	got, _ = rep.GetByName(ctx, userID, object.Name)
+	assert.Len(t, got.Records, 1, "an existing object should be kept")
+
+	_, err = rep.GetByName(ctx, userID, "Missing")
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	_, err = rep.GetByName(ctx, userID+1, object.Name)
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError, "objects of other users should not be found")
+
+	require.NoError(t, rep.Add(ctx, userID, newObject("Office")))
+	objects, err := rep.GetAll(ctx, userID)
+	require.NoError(t, err)
+	if assert.Len(t, objects, 2) {
+		assert.Equal(t, "Office", objects[0].Name)
+		assertObject(t, object, objects[1])
+	}
+	objects, err = rep.GetAll(ctx, userID+1)
+	assert.NoError(t, err)
+	assert.Empty(t, objects)
+
+	require.NoError(t, rep.Delete(ctx, userID, object.Name))
+	_, err = rep.GetByName(ctx, userID, object.Name)
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	assert.ErrorIs(t, rep.Delete(ctx, userID, object.Name), repository.ObjectNotFoundError)
+
+	require.NoError(t, rep.Add(ctx, userID, newObject("Shop")), "a deleted name should be free")
+	got, _ = rep.GetByName(ctx, userID, "Shop")
+	assert.Empty(t, got.Records, "records of a deleted object should be gone")
+}
+
+func testUpdate(t *testing.T, rep repository.RentObjectRepository) {
+	object := newObject("Shop", domain.Record{Date: date(time.January), Rent: 1000})
+	require.NoError(t, rep.Add(ctx, userID, object))
+	require.NoError(t, rep.Add(ctx, userID, newObject("Office")))
+
+	description := "New description"
+	require.NoError(t, rep.Update(ctx, userID, "Shop", domain.UpdateRentObjectInput{Description: &description}))
+	got, _ := rep.GetByName(ctx, userID, "Shop")
+	assert.Equal(t, description, got.Description)
+	assert.Equal(t, object.Area, got.Area, "fields left out should be kept")
+
+	name := "Warehouse"
+	require.NoError(t, rep.Update(ctx, userID, "Shop", domain.UpdateRentObjectInput{Name: &name}))
+	_, err := rep.GetByName(ctx, userID, "Shop")
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError, "a renamed object should not be found by its old name")
+	got, err = rep.GetByName(ctx, userID, name)
+	require.NoError(t, err)
+	assert.Equal(t, utc(object.Records), utc(got.Records), "records should follow a renamed object")
+
+	taken := "Office"
+	err = rep.Update(ctx, userID, name, domain.UpdateRentObjectInput{Name: &taken})
+	assert.ErrorIs(t, err, repository.ObjectAlreadyExists)
+	_, err = rep.GetByName(ctx, userID, name)
+	assert.NoError(t, err)
+
+	err = rep.Update(ctx, userID, "Missing", domain.UpdateRentObjectInput{Description: &description})
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+}
+
+func testRecords(t *testing.T, rep repository.RentObjectRepository) {
+	require.NoError(t, rep.Add(ctx, userID, newObject("Shop")))
+
+	march := domain.Record{Date: date(time.March), Rent: 3}
+	january := domain.Record{Date: date(time.January), Rent: 1}
+	february := domain.Record{Date: date(time.February), Rent: 2}
+	for _, record := range []domain.Record{march, january, february} {
+		index, err := rep.AddRecord(ctx, userID, "Shop", record)
+		require.NoError(t, err)
+		got, err := rep.GetRecordByIndex(ctx, userID, "Shop", index)
+		require.NoError(t, err)
+		assert.Equal(t, record.Rent, got.Rent, "AddRecord should return the index of the new record")
+	}
+
+	records, err := rep.GetAllRecords(ctx, userID, "Shop")
+	require.NoError(t, err)
+	assert.Equal(t, utc([]domain.Record{january, february, march}), utc(records))
+
+	rent := domain.RUB(20)
+	require.NoError(t, rep.UpdateRecord(ctx, userID, "Shop", 1, domain.UpdateRecordInput{Rent: &rent}))
+	got, _ := rep.GetRecordByIndex(ctx, userID, "Shop", 1)
+	assert.Equal(t, rent, got.Rent)
+	assert.True(t, got.Date.Equal(february.Date), "fields left out should be kept")
+
+	// The last record takes the place of the deleted one.
+	require.NoError(t, rep.DeleteRecord(ctx, userID, "Shop", 0))
+	got, _ = rep.GetRecordByIndex(ctx, userID, "Shop", 0)
+	assert.Equal(t, march.Rent, got.Rent)
+	records, _ = rep.GetAllRecords(ctx, userID, "Shop")
+	assert.Len(t, records, 2)
+
+	for _, index := range []int{-1, 2} {
+		_, err = rep.GetRecordByIndex(ctx, userID, "Shop", index)
+		assert.ErrorIs(t, err, domain.RecordNotFoundError)
+		assert.ErrorIs(t, rep.DeleteRecord(ctx, userID, "Shop", index), domain.RecordNotFoundError)
+		assert.ErrorIs(t, rep.UpdateRecord(ctx, userID, "Shop", index, domain.UpdateRecordInput{Rent: &rent}), domain.RecordNotFoundError)
+	}
+
+	_, err = rep.AddRecord(ctx, userID, "Missing", january)
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	_, err = rep.GetRecordByIndex(ctx, userID, "Missing", 0)
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	_, err = rep.GetAllRecords(ctx, userID, "Missing")
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	assert.ErrorIs(t, rep.DeleteRecord(ctx, userID, "Missing", 0), repository.ObjectNotFoundError)
+	assert.ErrorIs(t, rep.UpdateRecord(ctx, userID, "Missing", 0, domain.UpdateRecordInput{}), repository.ObjectNotFoundError)
+}
+
+func testAddRecords(t *testing.T, rep repository.RentObjectRepository) {
+	want := newObject("Shop", domain.Record{Date: date(time.March), Rent: 3})
+	require.NoError(t, rep.Add(ctx, userID, want))
+
+	added := []domain.Record{
+		{Date: date(time.April), Rent: 4},
+		{Date: date(time.January), Rent: 1},
+		{Date: date(time.March), Rent: 30},
+	}
+	require.NoError(t, rep.AddRecords(ctx, userID, "Shop", added))
+	for _, record := range added {
+		want.AddRecord(record)
+	}
+	got, err := rep.GetByName(ctx, userID, "Shop")
+	require.NoError(t, err)
+	assert.Equal(t, utc(want.Records), utc(got.Records), "records should be added as AddRecord adds them")
+
+	assert.ErrorIs(t, rep.AddRecords(ctx, userID, "Missing", added), repository.ObjectNotFoundError)
+}
+
+func testGetByNameInPeriod(t *testing.T, rep repository.RentObjectRepository) {
+	object := newObject("Shop",
+		domain.Record{Date: date(time.January)},
+		domain.Record{Date: date(time.February)},
+		domain.Record{Date: date(time.March)},
+	)
+	require.NoError(t, rep.Add(ctx, userID, object))
+
+	from, to := date(time.February), date(time.March)
+	got, err := rep.GetByNameInPeriod(ctx, userID, "Shop", domain.Period{From: &from, To: &to})
+	require.NoError(t, err)
+	assertObject(t, object.InPeriod(domain.Period{From: &from, To: &to}), got)
+
+	_, err = rep.GetByNameInPeriod(ctx, userID, "Missing", domain.Period{})
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+}
+
+func testGetAllInPeriod(t *testing.T, rep repository.RentObjectRepository) {
+	objects := []domain.RentObject{
+		newObject("Office", domain.Record{Date: date(time.January)}),
+		newObject("Shop",
+			domain.Record{Date: date(time.January)},
+			domain.Record{Date: date(time.February)},
+			domain.Record{Date: date(time.March)},
+		),
+	}
+	for _, object := range objects {
+		require.NoError(t, rep.Add(ctx, userID, object))
+	}
+
+	from, to := date(time.February), date(time.March)
+	period := domain.Period{From: &from, To: &to}
+	got, err := rep.GetAllInPeriod(ctx, userID, period)
+	require.NoError(t, err)
+	require.Len(t, got, len(objects))
+	for i, object := range objects {
+		assertObject(t, object.InPeriod(period), got[i])
+	}
+}
+
+func testFindObjects(t *testing.T, rep repository.RentObjectRepository) {
+	for i := 0; i < 5; i++ {
+		object := newObject(fmt.Sprintf("%d", i), domain.Record{Date: date(time.January), Rent: domain.RUB(i % 2 * 100), Heat: 10})
+		require.NoError(t, rep.Add(ctx, userID, object))
+	}
+
+	query := repository.ObjectQuery{SortBy: repository.SortByProfit, Descending: true, Limit: 2}
+	var names []string
+	for {
+		page, err := rep.FindObjects(ctx, userID, query)
+		require.NoError(t, err)
+		for _, object := range page.Items {
+			names = append(names, object.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"3", "1", "4", "2", "0"}, names)
+
+	names = nil
+	query = repository.ObjectQuery{Limit: 2}
+	for {
+		page, err := rep.FindObjects(ctx, userID, query)
+		require.NoError(t, err)
+		for _, object := range page.Items {
+			names = append(names, object.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []string{"0", "1", "2", "3", "4"}, names)
+
+	minProfit := domain.RUB(0)
+	page, err := rep.FindObjects(ctx, userID, repository.ObjectQuery{NamePrefix: "3", MinProfit: &minProfit})
+	require.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Len(t, page.Items[0].Records, 1, "objects should come with their records")
+	}
+
+	_, err = rep.FindObjects(ctx, userID, repository.ObjectQuery{SortBy: "color"})
+	assert.ErrorIs(t, err, repository.InvalidQueryError)
+}
+
+func testFindRecords(t *testing.T, rep repository.RentObjectRepository) {
+	require.NoError(t, rep.Add(ctx, userID, newObject("Shop")))
+	for i, rent := range []domain.RUB{300, 100, 200} {
+		_, err := rep.AddRecord(ctx, userID, "Shop", domain.Record{Date: date(time.Month(i + 1)), Rent: rent})
+		require.NoError(t, err)
+	}
+
+	query := repository.RecordQuery{SortBy: repository.SortByAmount, Limit: 2}
+	var rents []domain.RUB
+	for {
+		page, err := rep.FindRecords(ctx, userID, "Shop", query)
+		require.NoError(t, err)
+		for _, entry := range page.Items {
+			rents = append(rents, entry.Rent)
+			record, err := rep.GetRecordByIndex(ctx, userID, "Shop", entry.Index)
+			assert.NoError(t, err)
+			assert.Equal(t, entry.Rent, record.Rent, "entries should have the index of their record")
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []domain.RUB{100, 200, 300}, rents)
+
+	query = repository.RecordQuery{Descending: true, Limit: 1}
+	rents = nil
+	for {
+		page, err := rep.FindRecords(ctx, userID, "Shop", query)
+		require.NoError(t, err)
+		for _, entry := range page.Items {
+			rents = append(rents, entry.Rent)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+	assert.Equal(t, []domain.RUB{200, 100, 300}, rents, "records should page by date")
+
+	from, to := date(time.February), date(time.March)
+	page, err := rep.FindRecords(ctx, userID, "Shop", repository.RecordQuery{Period: domain.Period{From: &from}})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 2)
+	page, err = rep.FindRecords(ctx, userID, "Shop", repository.RecordQuery{Period: domain.Period{From: &from, To: &to}})
+	require.NoError(t, err)
+	assert.Len(t, page.Items, 1)
+
+	_, err = rep.FindRecords(ctx, userID, "Missing", repository.RecordQuery{})
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+}
+
+func testTotals(t *testing.T, rep repository.RentObjectRepository) {
+	objects := []domain.RentObject{
+		newObject("Shop",
+			domain.Record{Date: date(time.January), Rent: 1000, Heat: 100},
+			domain.Record{Date: date(time.May), Rent: 1000, TBO: 50},
+		),
+		newObject("Office", domain.Record{Date: date(time.February), Rent: 500, Security: 20}),
+		newObject("Empty"),
+	}
+	for _, object := range objects {
+		require.NoError(t, rep.Add(ctx, userID, object))
+	}
+
+	to := date(time.June)
+	for _, query := range []repository.TotalsQuery{
+		{},
+		{Granularity: repository.ByQuarter, Period: domain.Period{To: &to}},
+		{Granularity: repository.ByYear, ObjectName: "Shop"},
+	} {
+		selected := objects
+		if query.ObjectName != "" {
+			selected = objects[:1]
+		}
+
+		byObject, err := rep.TotalsByObject(ctx, userID, query)
+		require.NoError(t, err)
+		want, _ := repository.SumByObject(selected, query)
+		assert.Equal(t, want, byObject)
+
+		byPeriod, err := rep.TotalsByPeriod(ctx, userID, query)
+		require.NoError(t, err)
+		wantPeriods, _ := repository.SumByPeriod(selected, query)
+		assert.Equal(t, wantPeriods, byPeriod)
+
+		byCategory, err := rep.TotalsByCategory(ctx, userID, query)
+		require.NoError(t, err)
+		wantCategories, _ := repository.SumByCategory(selected, query)
+		assert.Equal(t, wantCategories, byCategory)
+	}
+
+	_, err := rep.TotalsByCategory(ctx, userID, repository.TotalsQuery{ObjectName: "Missing"})
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError)
+	_, err = rep.TotalsByPeriod(ctx, userID, repository.TotalsQuery{Granularity: "week"})
+	assert.ErrorIs(t, err, repository.InvalidQueryError)
+}
+
+func testMatchRules(t *testing.T, rep repository.RentObjectRepository) {
+	first := domain.MatchRule{ObjectName: "first", Field: "rent", Counterparty: "7701234567"}
+	second := domain.MatchRule{ObjectName: "second", Field: "heat", Purpose: "heating"}
+
+	rules, err := rep.GetMatchRules(ctx, userID)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	for i, rule := range []domain.MatchRule{first, second} {
+		index, err := rep.AddMatchRule(ctx, userID, rule)
+		require.NoError(t, err)
+		assert.Equal(t, i, index)
+	}
+	rules, _ = rep.GetMatchRules(ctx, userID)
+	assert.Equal(t, []domain.MatchRule{first, second}, rules)
+
+	require.NoError(t, rep.DeleteMatchRule(ctx, userID, 0))
+	rules, _ = rep.GetMatchRules(ctx, userID)
+	assert.Equal(t, []domain.MatchRule{second}, rules)
+
+	assert.ErrorIs(t, rep.DeleteMatchRule(ctx, userID, 1), domain.MatchRuleNotFoundError)
+	rules, _ = rep.GetMatchRules(ctx, userID+1)
+	assert.Empty(t, rules, "rules of other users should not be found")
+}
+
+// testCount checks storages that count what they keep.
+func testCount(t *testing.T, rep repository.RentObjectRepository) {
+	counter, ok := rep.(repository.Counter)
+	if !ok {
+		t.Skip("repository does not count")
+	}
+	require.NoError(t, rep.Add(ctx, userID, newObject("Shop", domain.Record{Date: date(time.January)}, domain.Record{Date: date(time.February)})))
+	require.NoError(t, rep.Add(ctx, userID+1, newObject("Office", domain.Record{Date: date(time.January)})))
+
+	counts, err := counter.Count(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, repository.Counts{Objects: 2, Records: 3}, counts)
+}
+
+// testPing checks storages that can be pinged.
+func testPing(t *testing.T, rep repository.RentObjectRepository) {
+	pinger, ok := rep.(repository.Pinger)
+	if !ok {
+		t.Skip("repository cannot be pinged")
+	}
+	assert.NoError(t, pinger.Ping(ctx))
+}
+
+func testCanceled(t *testing.T, rep repository.RentObjectRepository) {
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	assert.Error(t, rep.Add(canceled, userID, newObject("Shop")))
+	_, err := rep.GetAll(canceled, userID)
+	assert.Error(t, err)
+
+	_, err = rep.GetByName(ctx, userID, "Shop")
+	assert.ErrorIs(t, err, repository.ObjectNotFoundError, "a canceled change should not be made")
+}
@@ -0,0 +1,152 @@
+package sqliterep
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"strings"
+	"time"
+)
+
+// totalsColumns sums up the records r into the fields of repository.Totals,
+// which are scanned by scanTotals.
+const totalsColumns = "total(r.rent), total(" + expensesExpression + "), count(r.id)"
+
+func scanTotals(row scanner, totals *repository.Totals, dest ...any) error {
+	if err := row.Scan(append(dest, &totals.Income, &totals.Expenses, &totals.Records)...); err != nil {
+		return err
+	}
+	totals.Profit = totals.Income - totals.Expenses
+	return nil
+}
+
+// periodStart truncates the date of records r to the start of its period
+// in UTC, as repository.PeriodStart does.
+var periodStart = map[repository.Granularity]string{
+	repository.ByMonth:   "strftime('%Y-%m-01', r.date)",
+	repository.ByQuarter: "printf('%s-%02d-01', strftime('%Y', r.date), (cast(strftime('%m', r.date) AS INTEGER) - 1) / 3 * 3 + 1)",
+	repository.ByYear:    "strftime('%Y-01-01', r.date)",
+}
+
+// recordsWhere matches the records r of the query by the index of object
+// and date, with the arguments ?1 to ?3.
+func (r *SQLiteRepository) recordsWhere(ctx context.Context, userID int64, query repository.TotalsQuery) (string, []any, error) {
+	where := "r.object_id IN (SELECT id FROM rent_objects WHERE user_id = ?1)"
+	args := []any{userID}
+	if query.ObjectName != "" {
+		var objectID int64
+		err := r.db.QueryRowContext(ctx, "SELECT id FROM rent_objects WHERE user_id = ? AND name = ?", userID, query.ObjectName).Scan(&objectID)
+		if err == sql.ErrNoRows {
+			return "", nil, repository.ObjectNotFoundError
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		where, args = "r.object_id = ?1", []any{objectID}
+	}
+	where += " AND (?2 IS NULL OR julianday(r.date) >= julianday(?2)) AND (?3 IS NULL OR julianday(r.date) < julianday(?3))"
+	return where, append(args, dateValue(query.From), dateValue(query.To)), nil
+}
+
+// TotalsByObject sums up the records of each object in the database.
+func (r *SQLiteRepository) TotalsByObject(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.ObjectTotals, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT o.name, `+totalsColumns+` FROM rent_objects o
+		LEFT JOIN records r ON r.object_id = o.id
+			AND (?3 IS NULL OR julianday(r.date) >= julianday(?3))
+			AND (?4 IS NULL OR julianday(r.date) < julianday(?4))
+		WHERE o.user_id = ?1 AND (?2 = '' OR o.name = ?2)
+		GROUP BY o.id
+		ORDER BY o.name`,
+		userID, query.ObjectName, dateValue(query.From), dateValue(query.To),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := []repository.ObjectTotals{}
+	for rows.Next() {
+		var object repository.ObjectTotals
+		if err := scanTotals(rows, &object.Totals, &object.Name); err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if query.ObjectName != "" && len(objects) == 0 {
+		return nil, repository.ObjectNotFoundError
+	}
+	return objects, nil
+}
+
+// TotalsByPeriod sums up the records of each period in the database.
+func (r *SQLiteRepository) TotalsByPeriod(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.PeriodTotals, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+	where, args, err := r.recordsWhere(ctx, userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+periodStart[query.Granularity]+` AS start, `+totalsColumns+` FROM records r
+		WHERE `+where+`
+		GROUP BY start
+		ORDER BY start`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	periods := []repository.PeriodTotals{}
+	for rows.Next() {
+		var period repository.PeriodTotals
+		var start string
+		if err := scanTotals(rows, &period.Totals, &start); err != nil {
+			return nil, err
+		}
+		if period.Start, err = time.Parse(time.DateOnly, start); err != nil {
+			return nil, err
+		}
+		periods = append(periods, period)
+	}
+	return periods, rows.Err()
+}
+
+// TotalsByCategory sums up each amount column of the records in the
+// database.
+func (r *SQLiteRepository) TotalsByCategory(ctx context.Context, userID int64, query repository.TotalsQuery) ([]repository.CategoryTotal, error) {
+	if err := query.Normalize(); err != nil {
+		return nil, err
+	}
+	where, args, err := r.recordsWhere(ctx, userID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	// Categories are named after the columns.
+	columns := make([]string, len(repository.Categories))
+	sums := make([]domain.RUB, len(repository.Categories))
+	dest := make([]any, len(repository.Categories))
+	for i, category := range repository.Categories {
+		columns[i] = fmt.Sprintf("total(r.%s)", category)
+		dest[i] = &sums[i]
+	}
+	err = r.db.QueryRowContext(ctx, "SELECT "+strings.Join(columns, ", ")+" FROM records r WHERE "+where, args...).Scan(dest...)
+	if err != nil {
+		return nil, err
+	}
+	return repository.NewCategoryTotals(sums), nil
+}
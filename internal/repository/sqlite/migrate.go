@@ -0,0 +1,95 @@
+package sqliterep
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migrations are named NNN_description.sql and applied in the order of NNN,
+// each in its own transaction. The schema version is kept in the
+// user_version pragma of the database file.
+//
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+func loadMigrations() ([]migration, error) {
+	files, err := fs.Glob(migrations, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	var result []migration
+	for _, file := range files {
+		name := strings.TrimPrefix(file, "migrations/")
+		prefix, _, _ := strings.Cut(name, "_")
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: name should start with its version", name)
+		}
+		data, err := migrations.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, migration{version, name, string(data)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].version < result[j].version })
+	return result, nil
+}
+
+// Migrate applies the migrations that the database lacks and returns the
+// schema version.
+func Migrate(ctx context.Context, db *sql.DB) (int, error) {
+	all, err := loadMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	var current int
+	if err := db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&current); err != nil {
+		return 0, err
+	}
+
+	for _, m := range all {
+		if m.version <= current {
+			continue
+		}
+		err := inTx(ctx, db, func(tx *sql.Tx) error {
+			if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+				return err
+			}
+			// Pragmas take no parameters.
+			_, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", m.version))
+			return err
+		})
+		if err != nil {
+			return current, fmt.Errorf("migration %s: %w", m.name, err)
+		}
+		current = m.version
+	}
+	return current, nil
+}
+
+// inTx runs fn in a transaction, which is committed unless fn fails.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,40 @@
+CREATE TABLE rent_objects (
+	id          INTEGER PRIMARY KEY,
+	user_id     INTEGER NOT NULL,
+	name        TEXT NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	area        REAL NOT NULL DEFAULT 0,
+	UNIQUE (user_id, name)
+);
+
+-- position is the index of the record in the object, and date is an RFC 3339
+-- timestamp in UTC.
+CREATE TABLE records (
+	id           INTEGER PRIMARY KEY,
+	object_id    INTEGER NOT NULL REFERENCES rent_objects (id) ON DELETE CASCADE,
+	position     INTEGER NOT NULL,
+	date         TEXT NOT NULL,
+	rent         REAL NOT NULL DEFAULT 0,
+	heat         REAL NOT NULL DEFAULT 0,
+	exploitation REAL NOT NULL DEFAULT 0,
+	mop          REAL NOT NULL DEFAULT 0,
+	renovation   REAL NOT NULL DEFAULT 0,
+	tbo          REAL NOT NULL DEFAULT 0,
+	electricity  REAL NOT NULL DEFAULT 0,
+	earth_rent   REAL NOT NULL DEFAULT 0,
+	other        REAL NOT NULL DEFAULT 0,
+	security     REAL NOT NULL DEFAULT 0,
+	UNIQUE (object_id, position)
+);
+
+CREATE TABLE match_rules (
+	id           INTEGER PRIMARY KEY,
+	user_id      INTEGER NOT NULL,
+	position     INTEGER NOT NULL,
+	object_name  TEXT NOT NULL,
+	field        TEXT NOT NULL,
+	direction    TEXT NOT NULL DEFAULT '',
+	counterparty TEXT NOT NULL DEFAULT '',
+	purpose      TEXT NOT NULL DEFAULT '',
+	UNIQUE (user_id, position)
+);
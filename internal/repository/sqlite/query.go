@@ -0,0 +1,199 @@
+package sqliterep
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"time"
+)
+
+// expensesExpression and profitExpression compute the expenses and profit
+// of a record row the same way as domain.Record does.
+const (
+	expensesExpression = "heat + exploitation + mop + renovation + tbo + electricity + earth_rent + other + security"
+	profitExpression   = "rent - (" + expensesExpression + ")"
+)
+
+// afterCursor matches rows that come after the cursor values ?n and ?n+1 in
+// the order of column and then tieColumn. value is the expression of the
+// first cursor value with a %d for n.
+func afterCursor(column, value, tieColumn string, n int, descending bool) string {
+	op := ">"
+	if descending {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s ("+value+", ?%d)", column, tieColumn, op, n, n+1)
+}
+
+func sortDirection(descending bool) string {
+	if descending {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// dateValue is the stored form of the date, or nil. Stored dates differ in
+// the length of their fractions of seconds, so they are compared with
+// julianday rather than as text.
+func dateValue(date *time.Time) any {
+	if date == nil {
+		return nil
+	}
+	return date.UTC().Format(time.RFC3339Nano)
+}
+
+// FindObjects sums up the profit of each object over the period in the
+// query, in the order of records so that the profit of a cursor is the same
+// on the next page, and reads the records of the page only.
+func (r *SQLiteRepository) FindObjects(ctx context.Context, userID int64, query repository.ObjectQuery) (repository.Page[domain.RentObject], error) {
+	var page repository.Page[domain.RentObject]
+	cursor, err := query.Normalize()
+	if err != nil {
+		return page, err
+	}
+
+	sortColumn := map[repository.SortField]string{
+		repository.SortByName:   "name",
+		repository.SortByArea:   "area",
+		repository.SortByProfit: "profit",
+	}[query.SortBy]
+
+	args := []any{userID, query.NamePrefix, dateValue(query.From), dateValue(query.To), query.MinProfit, query.MaxProfit}
+	statement := `
+		SELECT id, name, description, area, profit FROM (
+			SELECT o.id, o.name, o.description, o.area, coalesce((
+				SELECT sum(` + profitExpression + ` ORDER BY position) FROM records
+				WHERE records.object_id = o.id
+					AND (?3 IS NULL OR julianday(records.date) >= julianday(?3))
+					AND (?4 IS NULL OR julianday(records.date) < julianday(?4))
+			), 0) AS profit
+			FROM rent_objects o
+			WHERE o.user_id = ?1 AND substr(o.name, 1, length(?2)) = ?2
+		) objects
+		WHERE (?5 IS NULL OR profit >= ?5) AND (?6 IS NULL OR profit <= ?6)`
+	if cursor != nil {
+		value := map[repository.SortField]any{
+			repository.SortByName:   cursor.Name,
+			repository.SortByArea:   cursor.Area,
+			repository.SortByProfit: cursor.Profit,
+		}[query.SortBy]
+		statement += " AND " + afterCursor(sortColumn, "?%d", "name", len(args)+1, query.Descending)
+		args = append(args, value, cursor.Name)
+	}
+	direction := sortDirection(query.Descending)
+	statement += fmt.Sprintf(" ORDER BY %s %s, name %s LIMIT %d", sortColumn, direction, direction, query.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return page, err
+	}
+	page.Items = []domain.RentObject{}
+	var ids []int64
+	var profits []domain.RUB
+	for rows.Next() {
+		var id int64
+		var profit domain.RUB
+		object := domain.NewRentObject("", "", 0)
+		if err := rows.Scan(&id, &object.Name, &object.Description, &object.Area, &profit); err != nil {
+			rows.Close()
+			return page, err
+		}
+		ids = append(ids, id)
+		profits = append(profits, profit)
+		page.Items = append(page.Items, object)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Items) > query.Limit {
+		last := page.Items[query.Limit-1]
+		page.NextCursor = repository.EncodeCursor(repository.ObjectCursor{
+			SortBy: query.SortBy,
+			Name:   last.Name,
+			Area:   last.Area,
+			Profit: profits[query.Limit-1],
+		})
+		page.Items, ids = page.Items[:query.Limit], ids[:query.Limit]
+	}
+	return page, r.addRecords(ctx, page.Items, ids, domain.Period{})
+}
+
+// FindRecords filters and sorts the records of the object in the database,
+// and reads the records of the page only.
+func (r *SQLiteRepository) FindRecords(ctx context.Context, userID int64, objectName string, query repository.RecordQuery) (repository.Page[repository.RecordEntry], error) {
+	var page repository.Page[repository.RecordEntry]
+	cursor, err := query.Normalize()
+	if err != nil {
+		return page, err
+	}
+
+	var objectID int64
+	err = r.db.QueryRowContext(ctx, "SELECT id FROM rent_objects WHERE user_id = ? AND name = ?", userID, objectName).Scan(&objectID)
+	if err == sql.ErrNoRows {
+		return page, repository.ObjectNotFoundError
+	}
+	if err != nil {
+		return page, err
+	}
+
+	sortColumn, cursorValue := "amount", "?%d"
+	if query.SortBy == repository.SortByDate {
+		sortColumn, cursorValue = "julianday(date)", "julianday(?%d)"
+	}
+
+	args := []any{objectID, dateValue(query.From), dateValue(query.To), query.MinAmount, query.MaxAmount}
+	statement := `
+		SELECT position, amount, ` + recordColumns + ` FROM (
+			SELECT position, ` + profitExpression + ` AS amount, ` + recordColumns + ` FROM records
+			WHERE object_id = ?1
+				AND (?2 IS NULL OR julianday(date) >= julianday(?2))
+				AND (?3 IS NULL OR julianday(date) < julianday(?3))
+		) records
+		WHERE (?4 IS NULL OR amount >= ?4) AND (?5 IS NULL OR amount <= ?5)`
+	if cursor != nil {
+		value := map[repository.SortField]any{
+			repository.SortByDate:   dateValue(&cursor.Date),
+			repository.SortByAmount: cursor.Amount,
+		}[query.SortBy]
+		statement += " AND " + afterCursor(sortColumn, cursorValue, "position", len(args)+1, query.Descending)
+		args = append(args, value, cursor.Index)
+	}
+	direction := sortDirection(query.Descending)
+	statement += fmt.Sprintf(" ORDER BY %s %s, position %s LIMIT %d", sortColumn, direction, direction, query.Limit+1)
+
+	rows, err := r.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	page.Items = []repository.RecordEntry{}
+	var amounts []domain.RUB
+	for rows.Next() {
+		var entry repository.RecordEntry
+		var amount domain.RUB
+		if err := scanRecord(rows, &entry.Record, &entry.Index, &amount); err != nil {
+			return page, err
+		}
+		page.Items = append(page.Items, entry)
+		amounts = append(amounts, amount)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Items) > query.Limit {
+		last := page.Items[query.Limit-1]
+		page.NextCursor = repository.EncodeCursor(repository.RecordCursor{
+			SortBy: query.SortBy,
+			Index:  last.Index,
+			Date:   last.Date,
+			Amount: amounts[query.Limit-1],
+		})
+		page.Items = page.Items[:query.Limit]
+	}
+	return page, nil
+}
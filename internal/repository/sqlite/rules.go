@@ -0,0 +1,69 @@
+package sqliterep
+
+import (
+	"context"
+	"database/sql"
+	"rental-server/internal/domain"
+)
+
+// Match rules of a user keep their order in the position column.
+func (r *SQLiteRepository) AddMatchRule(ctx context.Context, userID int64, rule domain.MatchRule) (int, error) {
+	var index int
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		if err := tx.QueryRowContext(ctx, "SELECT count(*) FROM match_rules WHERE user_id = ?", userID).Scan(&index); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO match_rules (user_id, position, object_name, field, direction, counterparty, purpose)
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			userID, index, rule.ObjectName, rule.Field, rule.Direction, rule.Counterparty, rule.Purpose,
+		)
+		return err
+	})
+	return index, err
+}
+
+func (r *SQLiteRepository) DeleteMatchRule(ctx context.Context, userID int64, ruleIndex int) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, "DELETE FROM match_rules WHERE user_id = ? AND position = ?", userID, ruleIndex)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			if err != nil {
+				return err
+			}
+			return domain.MatchRuleNotFoundError
+		}
+
+		// Unique positions are checked for each row, so the rules are moved
+		// out of the way first.
+		if _, err := tx.ExecContext(ctx, "UPDATE match_rules SET position = -position WHERE user_id = ? AND position > ?", userID, ruleIndex); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE match_rules SET position = -position - 1 WHERE user_id = ? AND position < 0", userID)
+		return err
+	})
+}
+
+func (r *SQLiteRepository) GetMatchRules(ctx context.Context, userID int64) ([]domain.MatchRule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT object_name, field, direction, counterparty, purpose FROM match_rules
+		WHERE user_id = ? ORDER BY position`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []domain.MatchRule{}
+	for rows.Next() {
+		var rule domain.MatchRule
+		if err := rows.Scan(&rule.ObjectName, &rule.Field, &rule.Direction, &rule.Counterparty, &rule.Purpose); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
@@ -0,0 +1,410 @@
+// Package sqliterep keeps rent objects in an SQLite file, for single-node
+// installs that need no database server. The driver is pure Go.
+package sqliterep
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"sort"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+type SQLiteRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteRepository opens the database file of the path, creating it when
+// missing, and migrates its schema. ":memory:" keeps the database in memory.
+func NewSQLiteRepository(path string) (*SQLiteRepository, error) {
+	dsn := "file:" + path + "?" + url.Values{"_pragma": {"foreign_keys(1)", "busy_timeout(5000)", "journal_mode(WAL)"}}.Encode()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite has a single writer, and a single connection also keeps an
+	// in-memory database from being opened anew by each connection.
+	db.SetMaxOpenConns(1)
+
+	if _, err := Migrate(context.TODO(), db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &SQLiteRepository{db: db}, nil
+}
+
+// Migrate applies the migrations that the database lacks, see Migrate.
+func (r *SQLiteRepository) Migrate(ctx context.Context) (int, error) {
+	return Migrate(ctx, r.db)
+}
+
+func (r *SQLiteRepository) Close() error {
+	return r.db.Close()
+}
+
+const recordColumns = "date, rent, heat, exploitation, mop, renovation, tbo, electricity, earth_rent, other, security"
+
+func recordValues(record domain.Record) []any {
+	return []any{
+		record.Date.UTC().Format(time.RFC3339Nano), record.Rent, record.Heat, record.Exploitation, record.MOP, record.Renovation,
+		record.TBO, record.Electricity, record.EarthRent, record.Other, record.Security,
+	}
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRecord(row scanner, record *domain.Record, dest ...any) error {
+	var date string
+	dest = append(dest,
+		&date, &record.Rent, &record.Heat, &record.Exploitation, &record.MOP, &record.Renovation,
+		&record.TBO, &record.Electricity, &record.EarthRent, &record.Other, &record.Security,
+	)
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	var err error
+	record.Date, err = time.Parse(time.RFC3339Nano, date)
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+}
+
+func (r *SQLiteRepository) Add(ctx context.Context, userID int64, object domain.RentObject) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			"INSERT INTO rent_objects (user_id, name, description, area) VALUES (?, ?, ?, ?)",
+			userID, object.Name, object.Description, object.Area,
+		)
+		if isUniqueViolation(err) {
+			return repository.ObjectAlreadyExists
+		}
+		if err != nil {
+			return err
+		}
+		objectID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		for position, record := range object.Records {
+			if err := insertRecord(ctx, tx, objectID, position, record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func insertRecord(ctx context.Context, tx *sql.Tx, objectID int64, position int, record domain.Record) error {
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO records (object_id, position, "+recordColumns+") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		append([]any{objectID, position}, recordValues(record)...)...,
+	)
+	return err
+}
+
+func (r *SQLiteRepository) Delete(ctx context.Context, userID int64, objectName string) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM rent_objects WHERE user_id = ? AND name = ?", userID, objectName)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		if err != nil {
+			return err
+		}
+		return repository.ObjectNotFoundError
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) Update(ctx context.Context, userID int64, objectName string, input domain.UpdateRentObjectInput) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE rent_objects
+		SET name = coalesce(?, name), description = coalesce(?, description), area = coalesce(?, area)
+		WHERE user_id = ? AND name = ?`,
+		input.Name, input.Description, input.Area, userID, objectName,
+	)
+	if isUniqueViolation(err) {
+		return repository.ObjectAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		if err != nil {
+			return err
+		}
+		return repository.ObjectNotFoundError
+	}
+	return nil
+}
+
+func (r *SQLiteRepository) GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	return r.GetByNameInPeriod(ctx, userID, objectName, domain.Period{})
+}
+
+// GetByNameInPeriod reads only the records of the period.
+func (r *SQLiteRepository) GetByNameInPeriod(ctx context.Context, userID int64, objectName string, period domain.Period) (domain.RentObject, error) {
+	objects, err := r.getObjects(ctx, userID, &objectName, period)
+	if err != nil {
+		return domain.RentObject{}, err
+	}
+	if len(objects) == 0 {
+		return domain.RentObject{}, repository.ObjectNotFoundError
+	}
+	return objects[0], nil
+}
+
+func (r *SQLiteRepository) GetAll(ctx context.Context, userID int64) ([]domain.RentObject, error) {
+	return r.GetAllInPeriod(ctx, userID, domain.Period{})
+}
+
+// GetAllInPeriod reads only the records of the period.
+func (r *SQLiteRepository) GetAllInPeriod(ctx context.Context, userID int64, period domain.Period) ([]domain.RentObject, error) {
+	return r.getObjects(ctx, userID, nil, period)
+}
+
+// getObjects returns the objects of the user sorted by name, or only the
+// object of the name when it is set, with their records of the period.
+func (r *SQLiteRepository) getObjects(ctx context.Context, userID int64, objectName *string, period domain.Period) ([]domain.RentObject, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, description, area FROM rent_objects
+		WHERE user_id = ?1 AND (?2 IS NULL OR name = ?2)
+		ORDER BY name`,
+		userID, objectName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []domain.RentObject{}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		object := domain.NewRentObject("", "", 0)
+		if err := rows.Scan(&id, &object.Name, &object.Description, &object.Area); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+		objects = append(objects, object)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return objects, r.addRecords(ctx, objects, ids, period)
+}
+
+// addRecords reads the records of the period of the objects of the ids.
+func (r *SQLiteRepository) addRecords(ctx context.Context, objects []domain.RentObject, ids []int64, period domain.Period) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	indexes := map[int64]int{}
+	for i, id := range ids {
+		indexes[id] = i
+	}
+	data, err := json.Marshal(ids)
+	if err != nil {
+		return err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT object_id, `+recordColumns+` FROM records
+		WHERE object_id IN (SELECT value FROM json_each(?1))
+			AND (?2 IS NULL OR julianday(date) >= julianday(?2))
+			AND (?3 IS NULL OR julianday(date) < julianday(?3))
+		ORDER BY object_id, position`,
+		string(data), dateValue(period.From), dateValue(period.To),
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var objectID int64
+		var record domain.Record
+		if err := scanRecord(rows, &record, &objectID); err != nil {
+			return err
+		}
+		i := indexes[objectID]
+		objects[i].Records = append(objects[i].Records, record)
+	}
+	return rows.Err()
+}
+
+// Clear deletes all the data, for tests.
+func (r *SQLiteRepository) Clear() {
+	r.db.Exec("DELETE FROM rent_objects; DELETE FROM match_rules")
+}
+
+// positionedRecord is a record with the id of its row.
+type positionedRecord struct {
+	id     int64
+	record domain.Record
+}
+
+// objectRecords returns the id of the object with its records in their
+// order. Transactions are serialized by the single connection, so nothing
+// changes them until the transaction ends.
+func objectRecords(ctx context.Context, tx *sql.Tx, userID int64, objectName string) (int64, []positionedRecord, error) {
+	var objectID int64
+	err := tx.QueryRowContext(ctx, "SELECT id FROM rent_objects WHERE user_id = ? AND name = ?", userID, objectName).Scan(&objectID)
+	if err == sql.ErrNoRows {
+		return 0, nil, repository.ObjectNotFoundError
+	}
+	if err != nil {
+		return 0, nil, err
+	}
+
+	rows, err := tx.QueryContext(ctx, "SELECT id, "+recordColumns+" FROM records WHERE object_id = ? ORDER BY position", objectID)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	var records []positionedRecord
+	for rows.Next() {
+		var r positionedRecord
+		if err := scanRecord(rows, &r.record, &r.id); err != nil {
+			return 0, nil, err
+		}
+		records = append(records, r)
+	}
+	return objectID, records, rows.Err()
+}
+
+// AddRecord keeps the records sorted by date, as domain.RentObject does, and
+// returns the position of the new record.
+func (r *SQLiteRepository) AddRecord(ctx context.Context, userID int64, objectName string, record domain.Record) (int, error) {
+	positions, err := r.insertRecords(ctx, userID, objectName, []domain.Record{record})
+	if err != nil {
+		return 0, err
+	}
+	return positions[0], nil
+}
+
+func (r *SQLiteRepository) AddRecords(ctx context.Context, userID int64, objectName string, records []domain.Record) error {
+	_, err := r.insertRecords(ctx, userID, objectName, records)
+	return err
+}
+
+// insertRecords adds the records in one transaction, and returns the positions
+// of the new records in ascending order.
+func (r *SQLiteRepository) insertRecords(ctx context.Context, userID int64, objectName string, added []domain.Record) ([]int, error) {
+	var positions []int
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		objectID, records, err := objectRecords(ctx, tx, userID, objectName)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range added {
+			records = append(records, positionedRecord{record: record})
+		}
+		sort.SliceStable(records, func(i, j int) bool { return records[i].record.Date.Before(records[j].record.Date) })
+
+		// Unique positions are checked for each row, so the records are moved
+		// out of the way first.
+		if _, err := tx.ExecContext(ctx, "UPDATE records SET position = -1 - position WHERE object_id = ?", objectID); err != nil {
+			return err
+		}
+		for position, r := range records {
+			if r.id == 0 {
+				positions = append(positions, position)
+				if err := insertRecord(ctx, tx, objectID, position, r.record); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, "UPDATE records SET position = ? WHERE id = ?", position, r.id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return positions, err
+}
+
+// DeleteRecord moves the last record to the place of the deleted one, as
+// domain.RentObject does.
+func (r *SQLiteRepository) DeleteRecord(ctx context.Context, userID int64, objectName string, recordIndex int) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, records, err := objectRecords(ctx, tx, userID, objectName)
+		if err != nil {
+			return err
+		}
+		if recordIndex < 0 || recordIndex >= len(records) {
+			return domain.RecordNotFoundError
+		}
+
+		if _, err := tx.ExecContext(ctx, "DELETE FROM records WHERE id = ?", records[recordIndex].id); err != nil {
+			return err
+		}
+		if recordIndex == len(records)-1 {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, "UPDATE records SET position = ? WHERE id = ?", recordIndex, records[len(records)-1].id)
+		return err
+	})
+}
+
+func (r *SQLiteRepository) UpdateRecord(ctx context.Context, userID int64, objectName string, recordIndex int, input domain.UpdateRecordInput) error {
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, records, err := objectRecords(ctx, tx, userID, objectName)
+		if err != nil {
+			return err
+		}
+		if recordIndex < 0 || recordIndex >= len(records) {
+			return domain.RecordNotFoundError
+		}
+
+		updated := records[recordIndex].record.Update(input)
+		columns := strings.Split(recordColumns, ", ")
+		_, err = tx.ExecContext(ctx,
+			"UPDATE records SET "+strings.Join(columns, " = ?, ")+" = ? WHERE id = ?",
+			append(recordValues(updated), records[recordIndex].id)...,
+		)
+		return err
+	})
+}
+
+func (r *SQLiteRepository) GetRecordByIndex(ctx context.Context, userID int64, objectName string, recordIndex int) (domain.Record, error) {
+	object, err := r.GetByName(ctx, userID, objectName)
+	if err != nil {
+		return domain.Record{}, err
+	}
+	return object.GetRecordByIndex(recordIndex)
+}
+
+func (r *SQLiteRepository) GetAllRecords(ctx context.Context, userID int64, objectName string) ([]domain.Record, error) {
+	object, err := r.GetByName(ctx, userID, objectName)
+	if err != nil {
+		return nil, err
+	}
+	return object.GetAllRecords(), nil
+}
+
+func (r *SQLiteRepository) Count(ctx context.Context) (repository.Counts, error) {
+	var counts repository.Counts
+	err := r.db.QueryRowContext(ctx, `SELECT (SELECT count(*) FROM rent_objects), (SELECT count(*) FROM records)`).
+		Scan(&counts.Objects, &counts.Records)
+	return counts, err
+}
+
+func (r *SQLiteRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
@@ -0,0 +1,67 @@
+package sqliterep_test
+
+import (
+	"context"
+	"path/filepath"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/repositorytest"
+	sqliterep "rental-server/internal/repository/sqlite"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+var dummyUserID int64 = 1
+
+func newRepository(t *testing.T) *sqliterep.SQLiteRepository {
+	rep, err := sqliterep.NewSQLiteRepository(filepath.Join(t.TempDir(), "rental.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rep.Close() })
+	return rep
+}
+
+func date(month time.Month) time.Time {
+	return time.Date(2025, month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func TestMigrate(t *testing.T) {
+	rep := newRepository(t)
+
+	version, err := rep.Migrate(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, version, "migrating again should keep the schema version")
+}
+
+func TestReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rental.db")
+	rep, err := sqliterep.NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := domain.NewRentObject("Склад", "", 100)
+	object.AddRecord(domain.Record{Date: date(1), Rent: 1000.5})
+	_ = rep.Add(ctx, dummyUserID, object)
+	rep.Close()
+
+	rep, err = sqliterep.NewSQLiteRepository(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rep.Close()
+
+	got, err := rep.GetByName(ctx, dummyUserID, object.Name)
+	assert.NoError(t, err)
+	assert.Equal(t, object, got)
+}
+
+func TestConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repository.RentObjectRepository {
+		return newRepository(t)
+	})
+}
@@ -0,0 +1,272 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rental-server/internal/domain"
+	"strings"
+	"time"
+)
+
+// TimeoutError is returned when an operation runs out of its time, and
+// CanceledError when its caller gives up on it, e.g. the HTTP client goes
+// away. Both wrap the error of the storage.
+var TimeoutError = errors.New("Repository operation timed out")
+var CanceledError = errors.New("Repository operation canceled")
+
+// Timeouts limit the time of repository operations. Zero durations do not
+// limit it.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+	// Operations overrides Read and Write for operations by method name,
+	// e.g. "FindRecords".
+	Operations map[string]time.Duration
+}
+
+var writeOperations = map[string]bool{
+	"Add": true, "Delete": true, "Update": true,
+	"AddRecord": true, "AddRecords": true, "DeleteRecord": true, "UpdateRecord": true,
+	"AddMatchRule": true, "DeleteMatchRule": true,
+}
+
+var readOperations = map[string]bool{
+	"GetByName": true, "GetByNameInPeriod": true, "GetAll": true, "GetAllInPeriod": true, "FindObjects": true,
+	"GetRecordByIndex": true, "GetAllRecords": true, "FindRecords": true,
+	"TotalsByObject": true, "TotalsByPeriod": true, "TotalsByCategory": true,
+	"GetMatchRules": true,
+}
+
+type UnknownOperationError struct {
+	Operation string
+}
+
+func (e UnknownOperationError) Error() string {
+	return fmt.Sprintf("Unknown repository operation %q", e.Operation)
+}
+
+// ParseOperationTimeouts parses "Operation=duration" pairs separated by
+// commas, e.g. "FindRecords=30s,GetAll=10s".
+func ParseOperationTimeouts(s string) (map[string]time.Duration, error) {
+	timeouts := map[string]time.Duration{}
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		operation, value, ok := strings.Cut(pair, "=")
+		operation = strings.TrimSpace(operation)
+		if !ok {
+			return nil, fmt.Errorf("Invalid operation timeout %q, want Operation=duration", pair)
+		}
+		if !readOperations[operation] && !writeOperations[operation] {
+			return nil, UnknownOperationError{operation}
+		}
+		timeout, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		timeouts[operation] = timeout
+	}
+	return timeouts, nil
+}
+
+func (t Timeouts) of(operation string) time.Duration {
+	if timeout, ok := t.Operations[operation]; ok {
+		return timeout
+	}
+	if writeOperations[operation] {
+		return t.Write
+	}
+	return t.Read
+}
+
+// outcomes are errors that storages answer with, which are kept even when
+// the context is done by the time they are returned.
+var outcomes = []error{
+	ObjectNotFoundError, ObjectAlreadyExists, ConcurrentUpdateError, InvalidQueryError,
+	domain.RecordNotFoundError, domain.MatchRuleNotFoundError,
+}
+
+// ContextError wraps err with TimeoutError or CanceledError when the
+// operation failed because its context is done.
+func ContextError(ctx context.Context, err error) error {
+	if err == nil || errors.Is(err, TimeoutError) || errors.Is(err, CanceledError) {
+		return err
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%w: %w", TimeoutError, err)
+	case errors.Is(err, context.Canceled):
+		return fmt.Errorf("%w: %w", CanceledError, err)
+	}
+	for _, outcome := range outcomes {
+		if errors.Is(err, outcome) {
+			return err
+		}
+	}
+	// Drivers do not always keep the error of the context.
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("%w: %w", TimeoutError, err)
+	case context.Canceled:
+		return fmt.Errorf("%w: %w", CanceledError, err)
+	}
+	return err
+}
+
+// WithTimeouts limits the time of each operation of the repository, and
+// reports operations that ran out of time or were canceled with
+// TimeoutError and CanceledError.
+func WithTimeouts(rep RentObjectRepository, timeouts Timeouts) RentObjectRepository {
+	return &timeoutRepository{rep: rep, timeouts: timeouts}
+}
+
+type timeoutRepository struct {
+	rep      RentObjectRepository
+	timeouts Timeouts
+}
+
+// call runs the operation with the timeout of its name.
+func call[T any](r *timeoutRepository, ctx context.Context, operation string, fn func(ctx context.Context) (T, error)) (T, error) {
+	if timeout := r.timeouts.of(operation); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	result, err := fn(ctx)
+	return result, ContextError(ctx, err)
+}
+
+func (r *timeoutRepository) Add(ctx context.Context, userID int64, object domain.RentObject) error {
+	_, err := call(r, ctx, "Add", func(ctx context.Context) (any, error) {
+		return nil, r.rep.Add(ctx, userID, object)
+	})
+	return err
+}
+
+func (r *timeoutRepository) Delete(ctx context.Context, userID int64, objectName string) error {
+	_, err := call(r, ctx, "Delete", func(ctx context.Context) (any, error) {
+		return nil, r.rep.Delete(ctx, userID, objectName)
+	})
+	return err
+}
+
+func (r *timeoutRepository) Update(ctx context.Context, userID int64, objectName string, input domain.UpdateRentObjectInput) error {
+	_, err := call(r, ctx, "Update", func(ctx context.Context) (any, error) {
+		return nil, r.rep.Update(ctx, userID, objectName, input)
+	})
+	return err
+}
+
+func (r *timeoutRepository) GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	return call(r, ctx, "GetByName", func(ctx context.Context) (domain.RentObject, error) {
+		return r.rep.GetByName(ctx, userID, objectName)
+	})
+}
+
+func (r *timeoutRepository) GetByNameInPeriod(ctx context.Context, userID int64, objectName string, period domain.Period) (domain.RentObject, error) {
+	return call(r, ctx, "GetByNameInPeriod", func(ctx context.Context) (domain.RentObject, error) {
+		return r.rep.GetByNameInPeriod(ctx, userID, objectName, period)
+	})
+}
+
+func (r *timeoutRepository) GetAll(ctx context.Context, userID int64) ([]domain.RentObject, error) {
+	return call(r, ctx, "GetAll", func(ctx context.Context) ([]domain.RentObject, error) {
+		return r.rep.GetAll(ctx, userID)
+	})
+}
+
+func (r *timeoutRepository) GetAllInPeriod(ctx context.Context, userID int64, period domain.Period) ([]domain.RentObject, error) {
+	return call(r, ctx, "GetAllInPeriod", func(ctx context.Context) ([]domain.RentObject, error) {
+		return r.rep.GetAllInPeriod(ctx, userID, period)
+	})
+}
+
+func (r *timeoutRepository) FindObjects(ctx context.Context, userID int64, query ObjectQuery) (Page[domain.RentObject], error) {
+	return call(r, ctx, "FindObjects", func(ctx context.Context) (Page[domain.RentObject], error) {
+		return r.rep.FindObjects(ctx, userID, query)
+	})
+}
+
+func (r *timeoutRepository) AddRecord(ctx context.Context, userID int64, objectName string, record domain.Record) (int, error) {
+	return call(r, ctx, "AddRecord", func(ctx context.Context) (int, error) {
+		return r.rep.AddRecord(ctx, userID, objectName, record)
+	})
+}
+
+func (r *timeoutRepository) AddRecords(ctx context.Context, userID int64, objectName string, records []domain.Record) error {
+	_, err := call(r, ctx, "AddRecords", func(ctx context.Context) (any, error) {
+		return nil, r.rep.AddRecords(ctx, userID, objectName, records)
+	})
+	return err
+}
+
+func (r *timeoutRepository) DeleteRecord(ctx context.Context, userID int64, objectName string, recordIndex int) error {
+	_, err := call(r, ctx, "DeleteRecord", func(ctx context.Context) (any, error) {
+		return nil, r.rep.DeleteRecord(ctx, userID, objectName, recordIndex)
+	})
+	return err
+}
+
+func (r *timeoutRepository) UpdateRecord(ctx context.Context, userID int64, objectName string, recordIndex int, input domain.UpdateRecordInput) error {
+	_, err := call(r, ctx, "UpdateRecord", func(ctx context.Context) (any, error) {
+		return nil, r.rep.UpdateRecord(ctx, userID, objectName, recordIndex, input)
+	})
+	return err
+}
+
+func (r *timeoutRepository) GetRecordByIndex(ctx context.Context, userID int64, objectName string, recordIndex int) (domain.Record, error) {
+	return call(r, ctx, "GetRecordByIndex", func(ctx context.Context) (domain.Record, error) {
+		return r.rep.GetRecordByIndex(ctx, userID, objectName, recordIndex)
+	})
+}
+
+func (r *timeoutRepository) GetAllRecords(ctx context.Context, userID int64, objectName string) ([]domain.Record, error) {
+	return call(r, ctx, "GetAllRecords", func(ctx context.Context) ([]domain.Record, error) {
+		return r.rep.GetAllRecords(ctx, userID, objectName)
+	})
+}
+
+func (r *timeoutRepository) FindRecords(ctx context.Context, userID int64, objectName string, query RecordQuery) (Page[RecordEntry], error) {
+	return call(r, ctx, "FindRecords", func(ctx context.Context) (Page[RecordEntry], error) {
+		return r.rep.FindRecords(ctx, userID, objectName, query)
+	})
+}
+
+func (r *timeoutRepository) TotalsByObject(ctx context.Context, userID int64, query TotalsQuery) ([]ObjectTotals, error) {
+	return call(r, ctx, "TotalsByObject", func(ctx context.Context) ([]ObjectTotals, error) {
+		return r.rep.TotalsByObject(ctx, userID, query)
+	})
+}
+
+func (r *timeoutRepository) TotalsByPeriod(ctx context.Context, userID int64, query TotalsQuery) ([]PeriodTotals, error) {
+	return call(r, ctx, "TotalsByPeriod", func(ctx context.Context) ([]PeriodTotals, error) {
+		return r.rep.TotalsByPeriod(ctx, userID, query)
+	})
+}
+
+func (r *timeoutRepository) TotalsByCategory(ctx context.Context, userID int64, query TotalsQuery) ([]CategoryTotal, error) {
+	return call(r, ctx, "TotalsByCategory", func(ctx context.Context) ([]CategoryTotal, error) {
+		return r.rep.TotalsByCategory(ctx, userID, query)
+	})
+}
+
+func (r *timeoutRepository) AddMatchRule(ctx context.Context, userID int64, rule domain.MatchRule) (int, error) {
+	return call(r, ctx, "AddMatchRule", func(ctx context.Context) (int, error) {
+		return r.rep.AddMatchRule(ctx, userID, rule)
+	})
+}
+
+func (r *timeoutRepository) DeleteMatchRule(ctx context.Context, userID int64, ruleIndex int) error {
+	_, err := call(r, ctx, "DeleteMatchRule", func(ctx context.Context) (any, error) {
+		return nil, r.rep.DeleteMatchRule(ctx, userID, ruleIndex)
+	})
+	return err
+}
+
+func (r *timeoutRepository) GetMatchRules(ctx context.Context, userID int64) ([]domain.MatchRule, error) {
+	return call(r, ctx, "GetMatchRules", func(ctx context.Context) ([]domain.MatchRule, error) {
+		return r.rep.GetMatchRules(ctx, userID)
+	})
+}
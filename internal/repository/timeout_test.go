@@ -0,0 +1,117 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/memory"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// slowRepository reads objects only when its context is done.
+type slowRepository struct {
+	*memory.MemoryObjectRepository
+}
+
+func (r slowRepository) GetAll(ctx context.Context, userID int64) ([]domain.RentObject, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestWithTimeouts(t *testing.T) {
+	ctx := context.Background()
+	slow := slowRepository{memory.NewMemoryObjectRepository(nil)}
+
+	t.Run("Slow operation should return TimeoutError", func(t *testing.T) {
+		rep := repository.WithTimeouts(slow, repository.Timeouts{Read: 10 * time.Millisecond})
+
+		_, err := rep.GetAll(ctx, 1)
+
+		assert.ErrorIs(t, err, repository.TimeoutError)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("Canceled operation should return CanceledError", func(t *testing.T) {
+		rep := repository.WithTimeouts(slow, repository.Timeouts{})
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		_, err := rep.GetAll(canceled, 1)
+
+		assert.ErrorIs(t, err, repository.CanceledError)
+		assert.NotErrorIs(t, err, repository.TimeoutError)
+	})
+
+	t.Run("Operation timeout overrides read timeout", func(t *testing.T) {
+		rep := repository.WithTimeouts(slow, repository.Timeouts{
+			Read:       time.Hour,
+			Operations: map[string]time.Duration{"GetAll": 10 * time.Millisecond},
+		})
+
+		_, err := rep.GetAll(ctx, 1)
+
+		assert.ErrorIs(t, err, repository.TimeoutError)
+	})
+
+	t.Run("Happy path. Results and other errors are passed through", func(t *testing.T) {
+		rep := repository.WithTimeouts(memory.NewMemoryObjectRepository(nil), repository.Timeouts{Read: time.Second, Write: time.Second})
+		object := domain.NewRentObject("Name", "Description", 100)
+
+		assert.NoError(t, rep.Add(ctx, 1, object))
+		got, err := rep.GetByName(ctx, 1, "Name")
+		assert.NoError(t, err)
+		assert.Equal(t, object, got)
+
+		_, err = rep.GetByName(ctx, 1, "Unknown")
+		assert.Equal(t, repository.ObjectNotFoundError, err)
+	})
+}
+
+func TestContextError(t *testing.T) {
+	done, cancel := context.WithTimeout(context.Background(), 0)
+	defer cancel()
+	<-done.Done()
+
+	t.Run("Answers of the storage are kept when the context is done", func(t *testing.T) {
+		for _, err := range []error{repository.ObjectNotFoundError, domain.RecordNotFoundError, repository.ObjectAlreadyExists} {
+			assert.Equal(t, err, repository.ContextError(done, err))
+		}
+	})
+
+	t.Run("Other errors are put down to the context", func(t *testing.T) {
+		err := repository.ContextError(done, errors.New("connection closed"))
+		assert.ErrorIs(t, err, repository.TimeoutError)
+	})
+}
+
+func TestParseOperationTimeouts(t *testing.T) {
+	t.Run("Happy path", func(t *testing.T) {
+		timeouts, err := repository.ParseOperationTimeouts("FindRecords=30s, GetAll = 1m")
+
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]time.Duration{"FindRecords": 30 * time.Second, "GetAll": time.Minute}, timeouts)
+	})
+
+	t.Run("Empty string means no overrides", func(t *testing.T) {
+		timeouts, err := repository.ParseOperationTimeouts("")
+
+		assert.NoError(t, err)
+		assert.Empty(t, timeouts)
+	})
+
+	t.Run("Unknown operation should return UnknownOperationError", func(t *testing.T) {
+		_, err := repository.ParseOperationTimeouts("Find=1s")
+
+		assert.Equal(t, repository.UnknownOperationError{Operation: "Find"}, err)
+	})
+
+	t.Run("Invalid duration should return error", func(t *testing.T) {
+		_, err := repository.ParseOperationTimeouts("GetAll=soon")
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"rental-server/internal/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("rental-server/internal/repository")
+
+// WithTracing makes a span of each operation of the repository, named after
+// its method. Spans of the storage, e.g. MongoDB commands, are its children.
+func WithTracing(rep RentObjectRepository) RentObjectRepository {
+	return &tracedRepository{rep: rep}
+}
+
+type tracedRepository struct {
+	rep RentObjectRepository
+}
+
+// span runs the operation in a span of the user. Objects and records that
+// are not found are not errors of the span, since clients ask for them.
+func span[T any](ctx context.Context, operation string, userID int64, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx, span := tracer.Start(ctx, "repository."+operation, trace.WithAttributes(
+		attribute.String("repository.operation", operation),
+		attribute.Int64("rental.user_id", userID),
+	))
+	defer span.End()
+
+	result, err := fn(ctx)
+	if err != nil && !errors.Is(err, ObjectNotFoundError) && !errors.Is(err, domain.RecordNotFoundError) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return result, err
+}
+
+func (r *tracedRepository) Add(ctx context.Context, userID int64, object domain.RentObject) error {
+	_, err := span(ctx, "Add", userID, func(ctx context.Context) (any, error) {
+		return nil, r.rep.Add(ctx, userID, object)
+	})
+	return err
+}
+
+func (r *tracedRepository) Delete(ctx context.Context, userID int64, objectName string) error {
+	_, err := span(ctx, "Delete", userID, func(ctx context.Context) (any, error) {
+		return nil, r.rep.Delete(ctx, userID, objectName)
+	})
+	return err
+}
+
+func (r *tracedRepository) Update(ctx context.Context, userID int64, objectName string, input domain.UpdateRentObjectInput) error {
+	_, err := span(ctx, "Update", userID, func(ctx context.Context) (any, error) {
+		return nil, r.rep.Update(ctx, userID, objectName, input)
+	})
+	return err
+}
+
+func (r *tracedRepository) GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	return span(ctx, "GetByName", userID, func(ctx context.Context) (domain.RentObject, error) {
+		return r.rep.GetByName(ctx, userID, objectName)
+	})
+}
+
+func (r *tracedRepository) GetByNameInPeriod(ctx context.Context, userID int64, objectName string, period domain.Period) (domain.RentObject, error) {
+	return span(ctx, "GetByNameInPeriod", userID, func(ctx context.Context) (domain.RentObject, error) {
+		return r.rep.GetByNameInPeriod(ctx, userID, objectName, period)
+	})
+}
+
+func (r *tracedRepository) GetAll(ctx context.Context, userID int64) ([]domain.RentObject, error) {
+	return span(ctx, "GetAll", userID, func(ctx context.Context) ([]domain.RentObject, error) {
+		return r.rep.GetAll(ctx, userID)
+	})
+}
+
+func (r *tracedRepository) GetAllInPeriod(ctx context.Context, userID int64, period domain.Period) ([]domain.RentObject, error) {
+	return span(ctx, "GetAllInPeriod", userID, func(ctx context.Context) ([]domain.RentObject, error) {
+		return r.rep.GetAllInPeriod(ctx, userID, period)
+	})
+}
+
+func (r *tracedRepository) FindObjects(ctx context.Context, userID int64, query ObjectQuery) (Page[domain.RentObject], error) {
+	return span(ctx, "FindObjects", userID, func(ctx context.Context) (Page[domain.RentObject], error) {
+		return r.rep.FindObjects(ctx, userID, query)
+	})
+}
+
+func (r *tracedRepository) AddRecord(ctx context.Context, userID int64, objectName string, record domain.Record) (int, error) {
+	return span(ctx, "AddRecord", userID, func(ctx context.Context) (int, error) {
+		return r.rep.AddRecord(ctx, userID, objectName, record)
+	})
+}
+
+func (r *tracedRepository) AddRecords(ctx context.Context, userID int64, objectName string, records []domain.Record) error {
+	_, err := span(ctx, "AddRecords", userID, func(ctx context.Context) (any, error) {
+		return nil, r.rep.AddRecords(ctx, userID, objectName, records)
+	})
+	return err
+}
+
+func (r *tracedRepository) DeleteRecord(ctx context.Context, userID int64, objectName string, recordIndex int) error {
+	_, err := span(ctx, "DeleteRecord", userID, func(ctx context.Context) (any, error) {
+		return nil, r.rep.DeleteRecord(ctx, userID, objectName, recordIndex)
+	})
+	return err
+}
+
+func (r *tracedRepository) UpdateRecord(ctx context.Context, userID int64, objectName string, recordIndex int, input domain.UpdateRecordInput) error {
+	_, err := span(ctx, "UpdateRecord", userID, func(ctx context.Context) (any, error) {
+		return nil, r.rep.UpdateRecord(ctx, userID, objectName, recordIndex, input)
+	})
+	return err
+}
+
+func (r *tracedRepository) GetRecordByIndex(ctx context.Context, userID int64, objectName string, recordIndex int) (domain.Record, error) {
+	return span(ctx, "GetRecordByIndex", userID, func(ctx context.Context) (domain.Record, error) {
+		return r.rep.GetRecordByIndex(ctx, userID, objectName, recordIndex)
+	})
+}
+
+func (r *tracedRepository) GetAllRecords(ctx context.Context, userID int64, objectName string) ([]domain.Record, error) {
+	return span(ctx, "GetAllRecords", userID, func(ctx context.Context) ([]domain.Record, error) {
+		return r.rep.GetAllRecords(ctx, userID, objectName)
+	})
+}
+
+func (r *tracedRepository) FindRecords(ctx context.Context, userID int64, objectName string, query RecordQuery) (Page[RecordEntry], error) {
+	return span(ctx, "FindRecords", userID, func(ctx context.Context) (Page[RecordEntry], error) {
+		return r.rep.FindRecords(ctx, userID, objectName, query)
+	})
+}
+
+func (r *tracedRepository) TotalsByObject(ctx context.Context, userID int64, query TotalsQuery) ([]ObjectTotals, error) {
+	return span(ctx, "TotalsByObject", userID, func(ctx context.Context) ([]ObjectTotals, error) {
+		return r.rep.TotalsByObject(ctx, userID, query)
+	})
+}
+
+func (r *tracedRepository) TotalsByPeriod(ctx context.Context, userID int64, query TotalsQuery) ([]PeriodTotals, error) {
+	return span(ctx, "TotalsByPeriod", userID, func(ctx context.Context) ([]PeriodTotals, error) {
+		return r.rep.TotalsByPeriod(ctx, userID, query)
+	})
+}
+
+func (r *tracedRepository) TotalsByCategory(ctx context.Context, userID int64, query TotalsQuery) ([]CategoryTotal, error) {
+	return span(ctx, "TotalsByCategory", userID, func(ctx context.Context) ([]CategoryTotal, error) {
+		return r.rep.TotalsByCategory(ctx, userID, query)
+	})
+}
+
+func (r *tracedRepository) AddMatchRule(ctx context.Context, userID int64, rule domain.MatchRule) (int, error) {
+	return span(ctx, "AddMatchRule", userID, func(ctx context.Context) (int, error) {
+		return r.rep.AddMatchRule(ctx, userID, rule)
+	})
+}
+
+func (r *tracedRepository) DeleteMatchRule(ctx context.Context, userID int64, ruleIndex int) error {
+	_, err := span(ctx, "DeleteMatchRule", userID, func(ctx context.Context) (any, error) {
+		return nil, r.rep.DeleteMatchRule(ctx, userID, ruleIndex)
+	})
+	return err
+}
+
+func (r *tracedRepository) GetMatchRules(ctx context.Context, userID int64) ([]domain.MatchRule, error) {
+	return span(ctx, "GetMatchRules", userID, func(ctx context.Context) ([]domain.MatchRule, error) {
+		return r.rep.GetMatchRules(ctx, userID)
+	})
+}
@@ -0,0 +1,57 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/memory"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// failingRepository fails to delete objects.
+type failingRepository struct {
+	*memory.MemoryObjectRepository
+}
+
+func (r failingRepository) Delete(ctx context.Context, userID int64, objectName string) error {
+	return errors.New("storage is down")
+}
+
+func TestWithTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	rep := repository.WithTracing(failingRepository{memory.NewMemoryObjectRepository(nil)})
+
+	require.NoError(t, rep.Add(ctx, 1, domain.NewRentObject("Name", "Description", 100)))
+	_, err := rep.GetByName(ctx, 1, "Unknown")
+	require.Equal(t, repository.ObjectNotFoundError, err)
+	require.Error(t, rep.Delete(ctx, 1, "Name"))
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 4)
+	for i, want := range []struct {
+		name   string
+		status codes.Code
+	}{
+		{"repository.Add", codes.Unset},
+		{"repository.GetByName", codes.Unset},
+		{"repository.Delete", codes.Error},
+	} {
+		span := spans[i]
+		assert.Equal(t, want.name, span.Name())
+		assert.Equal(t, want.status, span.Status().Code)
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+		assert.Contains(t, span.Attributes(), attribute.Int64("rental.user_id", 1))
+	}
+}
@@ -0,0 +1,219 @@
+package graphqlapi_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/memory"
+	graphqlapi "rental-server/internal/server/graphql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var ctx = context.Background()
+
+var dummyUserID int64 = 1
+
+type result struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func do(t *testing.T, h *graphqlapi.Handler, query string, variables map[string]any) result {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	json.NewEncoder(buf).Encode(graphqlapi.Request{Query: query, Variables: variables})
+
+	request := httptest.NewRequest(http.MethodPost, "/graphql", buf)
+	responce := httptest.NewRecorder()
+	h.ServeHTTP(responce, request)
+
+	if responce.Code != http.StatusOK {
+		t.Fatalf("did not get correct status, got %d, want %d", responce.Code, http.StatusOK)
+	}
+
+	var got result
+	json.NewDecoder(responce.Body).Decode(&got)
+	return got
+}
+
+func newRepository() *memory.MemoryObjectRepository {
+	object := domain.NewRentObject("Rodionova", "HSE", 100)
+	for month := 1; month <= 3; month++ {
+		object.AddRecord(domain.Record{
+			Date:      time.Date(2025, time.Month(month), 1, 0, 0, 0, 0, time.UTC),
+			Rent:      1000,
+			EarthRent: 200,
+		})
+	}
+
+	rep := memory.NewMemoryObjectRepository(nil)
+	_ = rep.Add(ctx, dummyUserID, object)
+	return rep
+}
+
+// periodRepository fails to read whole objects, so that queries of a period
+// must read only its records.
+type periodRepository struct {
+	repository.RentObjectRepository
+}
+
+func (periodRepository) GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	return domain.RentObject{}, errors.New("whole object read")
+}
+
+func (periodRepository) GetAll(ctx context.Context, userID int64) ([]domain.RentObject, error) {
+	return nil, errors.New("whole objects read")
+}
+
+func TestQuery(t *testing.T) {
+	t.Run("Should return objects with records and metrics", func(t *testing.T) {
+		h := graphqlapi.NewHandler(newRepository())
+
+		got := do(t, h, `{ objects(userId: 1) { name area income expenses profit profitByArea records { index rent earthRent profit profitByArea } } }`, nil)
+
+		assert.Empty(t, got.Errors)
+		objects := got.Data["objects"].([]any)
+		if !assert.Len(t, objects, 1) {
+			t.Fatal()
+		}
+		object := objects[0].(map[string]any)
+		assert.Equal(t, "Rodionova", object["name"])
+		assert.Equal(t, 3000.0, object["income"])
+		assert.Equal(t, 600.0, object["expenses"])
+		assert.Equal(t, 2400.0, object["profit"])
+		assert.Equal(t, 24.0, object["profitByArea"])
+
+		records := object["records"].([]any)
+		assert.Len(t, records, 3)
+		assert.Equal(t, map[string]any{"index": 0.0, "rent": 1000.0, "earthRent": 200.0, "profit": 800.0, "profitByArea": 8.0}, records[0])
+	})
+
+	t.Run("Should narrow records and totals to period", func(t *testing.T) {
+		h := graphqlapi.NewHandler(newRepository())
+
+		got := do(t, h, `query($from: DateTime, $to: DateTime) {
+			object(userId: 1, name: "Rodionova", from: $from, to: $to) { income records { index } }
+		}`, map[string]any{"from": "2025-02-01T00:00:00Z", "to": "2025-03-01T00:00:00Z"})
+
+		assert.Empty(t, got.Errors)
+		object := got.Data["object"].(map[string]any)
+		assert.Equal(t, 1000.0, object["income"])
+		assert.Equal(t, []any{map[string]any{"index": 1.0}}, object["records"])
+	})
+
+	t.Run("Should read only the records of the period", func(t *testing.T) {
+		h := graphqlapi.NewHandler(periodRepository{newRepository()})
+
+		got := do(t, h, `query($from: DateTime) {
+			objects(userId: 1, from: $from) { income records { index } }
+			object(userId: 1, name: "Rodionova", from: $from) { income records { index } }
+		}`, map[string]any{"from": "2025-02-01T00:00:00Z"})
+
+		assert.Empty(t, got.Errors)
+		want := map[string]any{"income": 2000.0, "records": []any{map[string]any{"index": 1.0}, map[string]any{"index": 2.0}}}
+		assert.Equal(t, []any{want}, got.Data["objects"])
+		assert.Equal(t, want, got.Data["object"])
+	})
+
+	t.Run("Should report repository errors", func(t *testing.T) {
+		h := graphqlapi.NewHandler(newRepository())
+
+		got := do(t, h, `{ object(userId: 1, name: "Unknown") { name } }`, nil)
+
+		if assert.Len(t, got.Errors, 1) {
+			assert.Equal(t, "Object not found", got.Errors[0].Message)
+		}
+	})
+}
+
+func TestMutation(t *testing.T) {
+	t.Run("Should add and update object", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		h := graphqlapi.NewHandler(rep)
+
+		got := do(t, h, `mutation { addObject(userId: 1, object: {name: "Name", area: 50}) }`, nil)
+		assert.Empty(t, got.Errors)
+
+		got = do(t, h, `mutation { updateObject(userId: 1, name: "Name", input: {description: "Description"}) }`, nil)
+		assert.Empty(t, got.Errors)
+
+		object, err := rep.GetByName(ctx, dummyUserID, "Name")
+		assert.NoError(t, err)
+		assert.Equal(t, domain.NewRentObject("Name", "Description", 50), object)
+	})
+
+	t.Run("Should add, update and delete record", func(t *testing.T) {
+		rep := newRepository()
+		h := graphqlapi.NewHandler(rep)
+
+		got := do(t, h, `mutation { addRecord(userId: 1, objectName: "Rodionova", record: {date: "2025-04-01T00:00:00Z", rent: 500}) }`, nil)
+		assert.Empty(t, got.Errors)
+		assert.Equal(t, 3.0, got.Data["addRecord"])
+
+		got = do(t, h, `mutation { updateRecord(userId: 1, objectName: "Rodionova", index: 3, input: {heat: 100}) }`, nil)
+		assert.Empty(t, got.Errors)
+
+		record, _ := rep.GetRecordByIndex(ctx, dummyUserID, "Rodionova", 3)
+		assert.Equal(t, domain.RUB(500), record.Rent)
+		assert.Equal(t, domain.RUB(100), record.Heat)
+
+		got = do(t, h, `mutation { deleteRecord(userId: 1, objectName: "Rodionova", index: 3) }`, nil)
+		assert.Empty(t, got.Errors)
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, "Rodionova")
+		assert.Len(t, records, 3)
+	})
+}
+
+func TestGet(t *testing.T) {
+	get := func(h *graphqlapi.Handler, query, operationName string) *httptest.ResponseRecorder {
+		values := url.Values{"query": {query}}
+		if operationName != "" {
+			values.Set("operationName", operationName)
+		}
+		request := httptest.NewRequest(http.MethodGet, "/graphql?"+values.Encode(), nil)
+		responce := httptest.NewRecorder()
+		h.ServeHTTP(responce, request)
+		return responce
+	}
+
+	t.Run("Should run queries", func(t *testing.T) {
+		responce := get(graphqlapi.NewHandler(newRepository()), `{ object(userId: 1, name: "Rodionova") { name } }`, "")
+
+		assert.Equal(t, http.StatusOK, responce.Code)
+		assert.Contains(t, responce.Body.String(), "Rodionova")
+	})
+
+	t.Run("Should reject mutations", func(t *testing.T) {
+		rep := newRepository()
+		h := graphqlapi.NewHandler(rep)
+
+		responce := get(h, `mutation { deleteObject(userId: 1, name: "Rodionova") }`, "")
+		assert.Equal(t, http.StatusMethodNotAllowed, responce.Code)
+		assert.Equal(t, http.MethodPost, responce.Header().Get("Allow"))
+
+		responce = get(h, `query Read { object(userId: 1, name: "Rodionova") { name } } mutation Delete { deleteObject(userId: 1, name: "Rodionova") }`, "Delete")
+		assert.Equal(t, http.StatusMethodNotAllowed, responce.Code)
+
+		_, err := rep.GetByName(ctx, dummyUserID, "Rodionova")
+		assert.NoError(t, err)
+	})
+
+	t.Run("Should run the chosen query of several operations", func(t *testing.T) {
+		responce := get(graphqlapi.NewHandler(newRepository()), `query Read { object(userId: 1, name: "Rodionova") { name } } mutation Delete { deleteObject(userId: 1, name: "Rodionova") }`, "Read")
+
+		assert.Equal(t, http.StatusOK, responce.Code)
+		assert.Contains(t, responce.Body.String(), "Rodionova")
+	})
+}
@@ -0,0 +1,106 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"rental-server/internal/repository"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+type Handler struct {
+	schema graphql.Schema
+}
+
+// NewHandler panics if the schema is invalid, as it is built from constant
+// definitions and can't fail at runtime.
+func NewHandler(rep repository.RentObjectRepository) *Handler {
+	schema, err := NewSchema(rep)
+	if err != nil {
+		panic(err)
+	}
+	return &Handler{schema: schema}
+}
+
+// ServeHTTP accepts queries as a JSON body of POST requests or in the query
+// string of GET requests. GET requests may only read, so that mutations
+// cannot be made from a link or an image on another site. Errors of
+// resolvers are reported in the result with status 200 as the GraphQL over
+// HTTP convention suggests.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var request Request
+
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		request.Query = query.Get("query")
+		request.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				http.Error(w, "Error while parsing variables", http.StatusUnprocessableEntity)
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Error while parsing body", http.StatusUnprocessableEntity)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if request.Query == "" {
+		http.Error(w, "Missing query", http.StatusUnprocessableEntity)
+		return
+	}
+	if r.Method == http.MethodGet && !isQuery(request) {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only queries are allowed with GET, use POST", http.StatusMethodNotAllowed)
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         h.schema,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+		Context:        r.Context(),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// isQuery tells whether the operation of the request that would be executed
+// is a query. Documents that cannot be parsed, or whose operation cannot be
+// chosen, are left to graphql.Do to report, since it executes nothing then.
+func isQuery(request Request) bool {
+	document, err := parser.Parse(parser.ParseParams{Source: request.Query})
+	if err != nil {
+		return true
+	}
+	var operations []*ast.OperationDefinition
+	for _, definition := range document.Definitions {
+		if operation, ok := definition.(*ast.OperationDefinition); ok {
+			operations = append(operations, operation)
+		}
+	}
+	for _, operation := range operations {
+		selected := len(operations) == 1 && request.OperationName == "" ||
+			operation.Name != nil && operation.Name.Value == request.OperationName
+		if selected {
+			return operation.Operation == ast.OperationTypeQuery
+		}
+	}
+	return true
+}
@@ -0,0 +1,517 @@
+package graphqlapi
+
+import (
+	"context"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+var int64Type = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "Int64",
+	Description: "64-bit signed integer, used for user identifiers.",
+	Serialize: func(value interface{}) interface{} {
+		return toInt64(value)
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return toInt64(value)
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		switch valueAST := valueAST.(type) {
+		case *ast.IntValue:
+			return toInt64(valueAST.Value)
+		case *ast.StringValue:
+			return toInt64(valueAST.Value)
+		}
+		return nil
+	},
+})
+
+var rubType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "RUB",
+	Description: "Amount of money in roubles.",
+	Serialize: func(value interface{}) interface{} {
+		if rub, ok := value.(domain.RUB); ok {
+			return float64(rub)
+		}
+		return nil
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return toRUB(value)
+	},
+	ParseLiteral: func(valueAST ast.Value) interface{} {
+		switch valueAST := valueAST.(type) {
+		case *ast.IntValue:
+			return toRUB(valueAST.Value)
+		case *ast.FloatValue:
+			return toRUB(valueAST.Value)
+		}
+		return nil
+	},
+})
+
+func toInt64(value interface{}) interface{} {
+	switch value := value.(type) {
+	case int64:
+		return value
+	case int:
+		return int64(value)
+	case float64:
+		if value != float64(int64(value)) {
+			return nil
+		}
+		return int64(value)
+	case string:
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil
+		}
+		return parsed
+	}
+	return nil
+}
+
+func toRUB(value interface{}) interface{} {
+	switch value := value.(type) {
+	case float64:
+		return domain.RUB(value)
+	case int:
+		return domain.RUB(value)
+	case string:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil
+		}
+		return domain.RUB(parsed)
+	}
+	return nil
+}
+
+type recordAmount struct {
+	name  string
+	field func(r *domain.Record) *domain.RUB
+	input func(r *domain.UpdateRecordInput) **domain.RUB
+}
+
+var recordAmounts = []recordAmount{
+	{"rent", func(r *domain.Record) *domain.RUB { return &r.Rent }, func(r *domain.UpdateRecordInput) **domain.RUB { return &r.Rent }},
+	{"heat", func(r *domain.Record) *domain.RUB { return &r.Heat }, func(r *domain.UpdateRecordInput) **domain.RUB { return &r.Heat }},
+	{"exploitation", func(r *domain.Record) *domain.RUB { return &r.Exploitation }, func(r *domain.UpdateRecordInput) **domain.RUB { return &r.Exploitation }},
+	{"mop", func(r *domain.Record) *domain.RUB { return &r.MOP }, func(r *domain.UpdateRecordInput) **domain.RUB { return &r.MOP }},
+	{"renovation", func(r *domain.Record) *domain.RUB { return &r.Renovation }, func(r *domain.UpdateRecordInput) **domain.RUB { return &r.Renovation }},
+	{"tbo", func(r *domain.Record) *domain.RUB { return &r.TBO }, func(r *domain.UpdateRecordInput) **domain.RUB { return &r.TBO }},
+	{"electricity", func(r *domain.Record) *domain.RUB { return &r.Electricity }, func(r *domain.UpdateRecordInput) **domain.RUB { return &r.Electricity }},
+	{"earthRent", func(r *domain.Record) *domain.RUB { return &r.EarthRent }, func(r *domain.UpdateRecordInput) **domain.RUB { return &r.EarthRent }},
+	{"other", func(r *domain.Record) *domain.RUB { return &r.Other }, func(r *domain.UpdateRecordInput) **domain.RUB { return &r.Other }},
+	{"security", func(r *domain.Record) *domain.RUB { return &r.Security }, func(r *domain.UpdateRecordInput) **domain.RUB { return &r.Security }},
+}
+
+// recordSource is a record together with its position among all records of
+// the object, which is index past offset, and the object area, needed for
+// the by-area metrics.
+type recordSource struct {
+	index  int
+	offset *recordOffset
+	record domain.Record
+	area   float64
+}
+
+// objectSource is an object read with only the records of the requested
+// period, so that the computed totals cover the same records.
+type objectSource struct {
+	object  domain.RentObject
+	records []recordSource
+}
+
+func newObjectSource(object domain.RentObject, offset *recordOffset) objectSource {
+	source := objectSource{object: object}
+	for i, record := range object.GetAllRecords() {
+		source.records = append(source.records, recordSource{index: i, offset: offset, record: record, area: object.Area})
+	}
+	return source
+}
+
+// recordOffset is the position of the first record of a period among all
+// records of the object. Records read for a period come without their
+// positions, so it is looked up once, and only when an index is requested.
+type recordOffset struct {
+	find  func() (int, error)
+	once  sync.Once
+	value int
+	err   error
+}
+
+// newRecordOffset returns nil when the period has no start, since then the
+// records of the period are the first ones.
+func newRecordOffset(ctx context.Context, rep repository.RentObjectRepository, userID int64, objectName string, period domain.Period) *recordOffset {
+	if period.From == nil {
+		return nil
+	}
+	return &recordOffset{find: func() (int, error) {
+		query := repository.RecordQuery{Period: period, SortBy: repository.SortByDate, Limit: 1}
+		page, err := rep.FindRecords(ctx, userID, objectName, query)
+		if err != nil || len(page.Items) == 0 {
+			return 0, err
+		}
+		return page.Items[0].Index, nil
+	}}
+}
+
+func (o *recordOffset) get() (int, error) {
+	if o == nil {
+		return 0, nil
+	}
+	o.once.Do(func() {
+		o.value, o.err = o.find()
+	})
+	return o.value, o.err
+}
+
+func byArea(amount domain.RUB, area float64) domain.RUB {
+	if area == 0 {
+		return 0
+	}
+	return domain.RUB(float64(amount) / area)
+}
+
+func newRecordType() *graphql.Object {
+	fields := graphql.Fields{
+		"index": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.Int),
+			Description: "Position of the record among all records of the object sorted by date.",
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				source := p.Source.(recordSource)
+				offset, err := source.offset.get()
+				if err != nil {
+					return nil, err
+				}
+				return offset + source.index, nil
+			},
+		},
+		"date": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.DateTime),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				return p.Source.(recordSource).record.Date, nil
+			},
+		},
+	}
+
+	for _, amount := range recordAmounts {
+		field := amount.field
+		fields[amount.name] = &graphql.Field{
+			Type: graphql.NewNonNull(rubType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				record := p.Source.(recordSource).record
+				return *field(&record), nil
+			},
+		}
+	}
+
+	metric := func(compute func(r *domain.Record) domain.RUB, perArea bool) *graphql.Field {
+		return &graphql.Field{
+			Type: graphql.NewNonNull(rubType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				source := p.Source.(recordSource)
+				if perArea {
+					return byArea(compute(&source.record), source.area), nil
+				}
+				return compute(&source.record), nil
+			},
+		}
+	}
+	fields["income"] = metric((*domain.Record).Income, false)
+	fields["expenses"] = metric((*domain.Record).Expenses, false)
+	fields["profit"] = metric((*domain.Record).Profit, false)
+	fields["incomeByArea"] = metric((*domain.Record).Income, true)
+	fields["expensesByArea"] = metric((*domain.Record).Expenses, true)
+	fields["profitByArea"] = metric((*domain.Record).Profit, true)
+
+	return graphql.NewObject(graphql.ObjectConfig{Name: "Record", Fields: fields})
+}
+
+func newRentObjectType(recordType *graphql.Object) *graphql.Object {
+	metric := func(compute func(r *domain.RentObject) domain.RUB, perArea bool) *graphql.Field {
+		return &graphql.Field{
+			Type: graphql.NewNonNull(rubType),
+			Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+				source := p.Source.(objectSource)
+				if perArea {
+					return byArea(compute(&source.object), source.object.Area), nil
+				}
+				return compute(&source.object), nil
+			},
+		}
+	}
+
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "RentObject",
+		Fields: graphql.Fields{
+			"name": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(objectSource).object.Name, nil
+				},
+			},
+			"description": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.String),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(objectSource).object.Description, nil
+				},
+			},
+			"area": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Float),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(objectSource).object.Area, nil
+				},
+			},
+			"records": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(recordType))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					records := p.Source.(objectSource).records
+					if records == nil {
+						records = []recordSource{}
+					}
+					return records, nil
+				},
+			},
+			"income":         metric((*domain.RentObject).Income, false),
+			"expenses":       metric((*domain.RentObject).Expenses, false),
+			"profit":         metric((*domain.RentObject).Profit, false),
+			"incomeByArea":   metric((*domain.RentObject).Income, true),
+			"expensesByArea": metric((*domain.RentObject).Expenses, true),
+			"profitByArea":   metric((*domain.RentObject).Profit, true),
+		},
+	})
+}
+
+func newRecordInputs() (*graphql.InputObject, *graphql.InputObject) {
+	recordFields := graphql.InputObjectConfigFieldMap{
+		"date": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.DateTime)},
+	}
+	updateFields := graphql.InputObjectConfigFieldMap{
+		"date": &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+	}
+	for _, amount := range recordAmounts {
+		recordFields[amount.name] = &graphql.InputObjectFieldConfig{Type: rubType, DefaultValue: 0.0}
+		updateFields[amount.name] = &graphql.InputObjectFieldConfig{Type: rubType}
+	}
+
+	recordInput := graphql.NewInputObject(graphql.InputObjectConfig{Name: "RecordInput", Fields: recordFields})
+	updateInput := graphql.NewInputObject(graphql.InputObjectConfig{Name: "UpdateRecordInput", Fields: updateFields})
+	return recordInput, updateInput
+}
+
+func newObjectInputs() (*graphql.InputObject, *graphql.InputObject) {
+	objectInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "RentObjectInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String, DefaultValue: ""},
+			"area":        &graphql.InputObjectFieldConfig{Type: graphql.Float, DefaultValue: 0.0},
+		},
+	})
+	updateInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "UpdateRentObjectInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"name":        &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"description": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"area":        &graphql.InputObjectFieldConfig{Type: graphql.Float},
+		},
+	})
+	return objectInput, updateInput
+}
+
+func NewSchema(rep repository.RentObjectRepository) (graphql.Schema, error) {
+	recordType := newRecordType()
+	objectType := newRentObjectType(recordType)
+	recordInput, updateRecordInput := newRecordInputs()
+	objectInput, updateObjectInput := newObjectInputs()
+
+	userIDArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(int64Type)}
+	nameArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}
+	indexArg := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.Int)}
+	fromArg := &graphql.ArgumentConfig{Type: graphql.DateTime, Description: "Only records dated at or after this moment."}
+	toArg := &graphql.ArgumentConfig{Type: graphql.DateTime, Description: "Only records dated before this moment."}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"objects": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(objectType))),
+				Description: "All rent objects of a user sorted by name.",
+				Args:        graphql.FieldConfigArgument{"userId": userIDArg, "from": fromArg, "to": toArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					userID := p.Args["userId"].(int64)
+					period := periodArgs(p.Args)
+					objects, err := rep.GetAllInPeriod(p.Context, userID, period)
+					if err != nil {
+						return nil, err
+					}
+					sources := []objectSource{}
+					for _, object := range objects {
+						offset := newRecordOffset(p.Context, rep, userID, object.Name, period)
+						sources = append(sources, newObjectSource(object, offset))
+					}
+					return sources, nil
+				},
+			},
+			"object": &graphql.Field{
+				Type: objectType,
+				Args: graphql.FieldConfigArgument{"userId": userIDArg, "name": nameArg, "from": fromArg, "to": toArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					userID, name := p.Args["userId"].(int64), p.Args["name"].(string)
+					period := periodArgs(p.Args)
+					object, err := rep.GetByNameInPeriod(p.Context, userID, name, period)
+					if err != nil {
+						return nil, err
+					}
+					return newObjectSource(object, newRecordOffset(p.Context, rep, userID, name, period)), nil
+				},
+			},
+			"record": &graphql.Field{
+				Type: recordType,
+				Args: graphql.FieldConfigArgument{"userId": userIDArg, "objectName": nameArg, "index": indexArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					object, err := rep.GetByName(p.Context, p.Args["userId"].(int64), p.Args["objectName"].(string))
+					if err != nil {
+						return nil, err
+					}
+					index := p.Args["index"].(int)
+					record, err := object.GetRecordByIndex(index)
+					if err != nil {
+						return nil, err
+					}
+					return recordSource{index: index, record: record, area: object.Area}, nil
+				},
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"addObject": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{"userId": userIDArg, "object": &graphql.ArgumentConfig{Type: graphql.NewNonNull(objectInput)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := p.Args["object"].(map[string]interface{})
+					object := domain.NewRentObject(input["name"].(string), input["description"].(string), input["area"].(float64))
+					return succeeded(rep.Add(p.Context, p.Args["userId"].(int64), object))
+				},
+			},
+			"deleteObject": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{"userId": userIDArg, "name": nameArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return succeeded(rep.Delete(p.Context, p.Args["userId"].(int64), p.Args["name"].(string)))
+				},
+			},
+			"updateObject": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{"userId": userIDArg, "name": nameArg, "input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateObjectInput)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := updateRentObjectInput(p.Args["input"].(map[string]interface{}))
+					return succeeded(rep.Update(p.Context, p.Args["userId"].(int64), p.Args["name"].(string), input))
+				},
+			},
+			"addRecord": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.Int),
+				Description: "Adds a record and returns its index.",
+				Args:        graphql.FieldConfigArgument{"userId": userIDArg, "objectName": nameArg, "record": &graphql.ArgumentConfig{Type: graphql.NewNonNull(recordInput)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					record := newRecord(p.Args["record"].(map[string]interface{}))
+					return rep.AddRecord(p.Context, p.Args["userId"].(int64), p.Args["objectName"].(string), record)
+				},
+			},
+			"deleteRecord": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{"userId": userIDArg, "objectName": nameArg, "index": indexArg},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return succeeded(rep.DeleteRecord(p.Context, p.Args["userId"].(int64), p.Args["objectName"].(string), p.Args["index"].(int)))
+				},
+			},
+			"updateRecord": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{"userId": userIDArg, "objectName": nameArg, "index": indexArg, "input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateRecordInput)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					input := updateRecordInputFrom(p.Args["input"].(map[string]interface{}))
+					return succeeded(rep.UpdateRecord(p.Context, p.Args["userId"].(int64), p.Args["objectName"].(string), p.Args["index"].(int), input))
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func succeeded(err error) (interface{}, error) {
+	if err != nil {
+		return nil, err
+	}
+	return true, nil
+}
+
+func periodArgs(args map[string]interface{}) domain.Period {
+	var period domain.Period
+	if value, ok := args["from"].(time.Time); ok {
+		period.From = &value
+	}
+	if value, ok := args["to"].(time.Time); ok {
+		period.To = &value
+	}
+	return period
+}
+
+func newRecord(input map[string]interface{}) domain.Record {
+	record := domain.Record{Date: input["date"].(time.Time)}
+	for _, amount := range recordAmounts {
+		*amount.field(&record) = rubValue(input[amount.name])
+	}
+	return record
+}
+
+func updateRecordInputFrom(input map[string]interface{}) domain.UpdateRecordInput {
+	var update domain.UpdateRecordInput
+	if date, ok := input["date"].(time.Time); ok {
+		update.Date = &date
+	}
+	for _, amount := range recordAmounts {
+		if value, ok := input[amount.name]; ok && value != nil {
+			rub := rubValue(value)
+			*amount.input(&update) = &rub
+		}
+	}
+	return update
+}
+
+// rubValue handles both parsed values and input field defaults, which are
+// passed through without coercion.
+func rubValue(value interface{}) domain.RUB {
+	switch value := value.(type) {
+	case domain.RUB:
+		return value
+	case float64:
+		return domain.RUB(value)
+	default:
+		return 0
+	}
+}
+
+func updateRentObjectInput(input map[string]interface{}) domain.UpdateRentObjectInput {
+	var update domain.UpdateRentObjectInput
+	if name, ok := input["name"].(string); ok {
+		update.Name = &name
+	}
+	if description, ok := input["description"].(string); ok {
+		update.Description = &description
+	}
+	if area, ok := input["area"].(float64); ok {
+		update.Area = &area
+	}
+	return update
+}
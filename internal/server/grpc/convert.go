@@ -0,0 +1,181 @@
+package grpcapi
+
+import (
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	pb "rental-server/internal/server/grpc/rentalpb"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func toPBRecord(record domain.Record) *pb.Record {
+	return &pb.Record{
+		Date:         timestamppb.New(record.Date),
+		Rent:         float64(record.Rent),
+		Heat:         float64(record.Heat),
+		Exploitation: float64(record.Exploitation),
+		Mop:          float64(record.MOP),
+		Renovation:   float64(record.Renovation),
+		Tbo:          float64(record.TBO),
+		Electricity:  float64(record.Electricity),
+		EarthRent:    float64(record.EarthRent),
+		Other:        float64(record.Other),
+		Security:     float64(record.Security),
+	}
+}
+
+func toPBRecords(records []domain.Record) []*pb.Record {
+	result := make([]*pb.Record, 0, len(records))
+	for _, record := range records {
+		result = append(result, toPBRecord(record))
+	}
+	return result
+}
+
+func fromPBRecord(record *pb.Record) domain.Record {
+	return domain.Record{
+		Date:         record.GetDate().AsTime(),
+		Rent:         domain.RUB(record.GetRent()),
+		Heat:         domain.RUB(record.GetHeat()),
+		Exploitation: domain.RUB(record.GetExploitation()),
+		MOP:          domain.RUB(record.GetMop()),
+		Renovation:   domain.RUB(record.GetRenovation()),
+		TBO:          domain.RUB(record.GetTbo()),
+		Electricity:  domain.RUB(record.GetElectricity()),
+		EarthRent:    domain.RUB(record.GetEarthRent()),
+		Other:        domain.RUB(record.GetOther()),
+		Security:     domain.RUB(record.GetSecurity()),
+	}
+}
+
+func fromPBUpdateRecordInput(input *pb.UpdateRecordInput) domain.UpdateRecordInput {
+	var update domain.UpdateRecordInput
+	if input.Date != nil {
+		date := input.Date.AsTime()
+		update.Date = &date
+	}
+	update.Rent = rubPtr(input.Rent)
+	update.Heat = rubPtr(input.Heat)
+	update.Exploitation = rubPtr(input.Exploitation)
+	update.MOP = rubPtr(input.Mop)
+	update.Renovation = rubPtr(input.Renovation)
+	update.TBO = rubPtr(input.Tbo)
+	update.Electricity = rubPtr(input.Electricity)
+	update.EarthRent = rubPtr(input.EarthRent)
+	update.Other = rubPtr(input.Other)
+	update.Security = rubPtr(input.Security)
+	return update
+}
+
+func rubPtr(value *float64) *domain.RUB {
+	if value == nil {
+		return nil
+	}
+	rub := domain.RUB(*value)
+	return &rub
+}
+
+func toPBObject(object domain.RentObject) *pb.RentObject {
+	return &pb.RentObject{
+		Name:        object.Name,
+		Description: object.Description,
+		Area:        object.Area,
+		Records:     toPBRecords(object.Records),
+	}
+}
+
+func fromPBObject(object *pb.RentObject) domain.RentObject {
+	result := domain.NewRentObject(object.GetName(), object.GetDescription(), object.GetArea())
+	for _, record := range object.GetRecords() {
+		result.AddRecord(fromPBRecord(record))
+	}
+	return result
+}
+
+func fromPBUpdateRentObjectInput(input *pb.UpdateRentObjectInput) domain.UpdateRentObjectInput {
+	return domain.UpdateRentObjectInput{
+		Name:        input.Name,
+		Description: input.Description,
+		Area:        input.Area,
+	}
+}
+
+func fromPBPeriod(from, to *timestamppb.Timestamp) domain.Period {
+	var period domain.Period
+	if from != nil {
+		date := from.AsTime()
+		period.From = &date
+	}
+	if to != nil {
+		date := to.AsTime()
+		period.To = &date
+	}
+	return period
+}
+
+func toPBTimestamp(date *time.Time) *timestamppb.Timestamp {
+	if date == nil {
+		return nil
+	}
+	return timestamppb.New(*date)
+}
+
+func toPBTotals(totals repository.Totals) *pb.Totals {
+	return &pb.Totals{
+		Income:   float64(totals.Income),
+		Expenses: float64(totals.Expenses),
+		Profit:   float64(totals.Profit),
+		Records:  int32(totals.Records),
+	}
+}
+
+func toPBObjectInfo(info domain.RentObjectInfo) *pb.RentObjectInfo {
+	result := &pb.RentObjectInfo{
+		Name:        info.Name,
+		Description: info.Description,
+		Area:        info.Area,
+		Total: &pb.Summary{
+			Income:         float64(info.Total.Income),
+			Expenses:       float64(info.Total.Expenses),
+			Profit:         float64(info.Total.Profit),
+			IncomeByArea:   float64(info.Total.IncomeByArea),
+			ExpensesByArea: float64(info.Total.ExpensesByArea),
+			ProfitByArea:   float64(info.Total.ProfitByArea),
+		},
+		From: toPBTimestamp(info.From),
+		To:   toPBTimestamp(info.To),
+	}
+	for _, recordInfo := range info.RecordsInfo {
+		result.RecordsInfo = append(result.RecordsInfo, &pb.RecordInfo{
+			Record:         toPBRecord(recordInfo.Record),
+			Income:         float64(recordInfo.Income),
+			Expenses:       float64(recordInfo.Expenses),
+			Profit:         float64(recordInfo.Profit),
+			IncomeByArea:   float64(recordInfo.IncomeByArea),
+			ExpensesByArea: float64(recordInfo.ExpensesByArea),
+			ProfitByArea:   float64(recordInfo.ProfitByArea),
+		})
+	}
+	return result
+}
+
+func toPBMatchRule(rule domain.MatchRule) *pb.MatchRule {
+	return &pb.MatchRule{
+		ObjectName:   rule.ObjectName,
+		Field:        rule.Field,
+		Direction:    rule.Direction,
+		Counterparty: rule.Counterparty,
+		Purpose:      rule.Purpose,
+	}
+}
+
+func fromPBMatchRule(rule *pb.MatchRule) domain.MatchRule {
+	return domain.MatchRule{
+		ObjectName:   rule.ObjectName,
+		Field:        rule.Field,
+		Direction:    rule.Direction,
+		Counterparty: rule.Counterparty,
+		Purpose:      rule.Purpose,
+	}
+}
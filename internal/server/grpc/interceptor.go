@@ -0,0 +1,143 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"rental-server/internal/server"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var tracer = otel.Tracer("rental-server/internal/server/grpc")
+
+// requestIDKey is the metadata key of request IDs, as in the HTTP API.
+var requestIDKey = strings.ToLower(server.RequestIDHeader)
+
+// Instrument gives calls an ID and a span, which continues the trace of the
+// client, and tells the observer and the logger of them as the HTTP server
+// does. Either of them may be nil. Calls are observed with the HTTP status
+// of their code, so that they are counted with HTTP requests.
+func Instrument(observer server.RequestObserver, logger *slog.Logger) grpc.ServerOption {
+	return grpc.ChainUnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		md, _ := metadata.FromIncomingContext(ctx)
+		id := server.KeepRequestID(first(md.Get(requestIDKey)))
+		grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+
+		ctx = otel.GetTextMapPropagator().Extract(ctx, metadataCarrier(md))
+		ctx, span := tracer.Start(ctx, info.FullMethod, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", info.FullMethod),
+			attribute.String("rental.request_id", id),
+		))
+
+		resp, err := handler(ctx, req)
+
+		duration := time.Since(start)
+		code := status.Code(err)
+		httpCode := httpStatus(code)
+		attrs := []slog.Attr{
+			slog.String("request_id", id),
+			slog.String("method", "gRPC"),
+			slog.String("route", info.FullMethod),
+			slog.String("code", code.String()),
+			slog.Duration("duration", duration),
+		}
+		span.SetAttributes(attribute.Int("rpc.grpc.status_code", int(code)))
+		if r, ok := req.(interface{ GetUserId() int64 }); ok {
+			span.SetAttributes(attribute.Int64("rental.user_id", r.GetUserId()))
+			attrs = append(attrs, slog.Int64("user_id", r.GetUserId()))
+		}
+
+		level := slog.LevelInfo
+		if httpCode >= http.StatusInternalServerError {
+			level = slog.LevelError
+			// The cause of repository errors is kept from clients.
+			cause := err
+			var statusErr *statusError
+			if errors.As(err, &statusErr) && statusErr.err != nil {
+				cause = statusErr.err
+			}
+			span.RecordError(cause)
+			span.SetStatus(otelcodes.Error, code.String())
+			attrs = append(attrs, slog.String("error", cause.Error()))
+		}
+		span.End()
+
+		if observer != nil {
+			observer.ObserveRequest(info.FullMethod, httpCode, duration)
+		}
+		if logger != nil {
+			logger.LogAttrs(ctx, level, "Request", attrs...)
+		}
+		return resp, err
+	})
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// httpStatus maps the code as grpc-gateway does.
+func httpStatus(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return server.StatusClientClosedRequest
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// metadataCarrier lets the propagator read the trace of the client from the
+// metadata of the call, whose keys are lower case.
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	return first(metadata.MD(c).Get(key))
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
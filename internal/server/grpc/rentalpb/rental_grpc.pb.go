@@ -0,0 +1,809 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: rental.proto
+
+package rentalpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RentObjectService_AddObject_FullMethodName         = "/rental.v1.RentObjectService/AddObject"
+	RentObjectService_DeleteObject_FullMethodName      = "/rental.v1.RentObjectService/DeleteObject"
+	RentObjectService_UpdateObject_FullMethodName      = "/rental.v1.RentObjectService/UpdateObject"
+	RentObjectService_GetObject_FullMethodName         = "/rental.v1.RentObjectService/GetObject"
+	RentObjectService_GetAllObjects_FullMethodName     = "/rental.v1.RentObjectService/GetAllObjects"
+	RentObjectService_FindObjects_FullMethodName       = "/rental.v1.RentObjectService/FindObjects"
+	RentObjectService_GetObjectInfo_FullMethodName     = "/rental.v1.RentObjectService/GetObjectInfo"
+	RentObjectService_AddRecord_FullMethodName         = "/rental.v1.RentObjectService/AddRecord"
+	RentObjectService_DeleteRecord_FullMethodName      = "/rental.v1.RentObjectService/DeleteRecord"
+	RentObjectService_UpdateRecord_FullMethodName      = "/rental.v1.RentObjectService/UpdateRecord"
+	RentObjectService_GetRecord_FullMethodName         = "/rental.v1.RentObjectService/GetRecord"
+	RentObjectService_GetRecords_FullMethodName        = "/rental.v1.RentObjectService/GetRecords"
+	RentObjectService_FindRecords_FullMethodName       = "/rental.v1.RentObjectService/FindRecords"
+	RentObjectService_GetObjectTotals_FullMethodName   = "/rental.v1.RentObjectService/GetObjectTotals"
+	RentObjectService_GetPeriodTotals_FullMethodName   = "/rental.v1.RentObjectService/GetPeriodTotals"
+	RentObjectService_GetCategoryTotals_FullMethodName = "/rental.v1.RentObjectService/GetCategoryTotals"
+	RentObjectService_AddMatchRule_FullMethodName      = "/rental.v1.RentObjectService/AddMatchRule"
+	RentObjectService_DeleteMatchRule_FullMethodName   = "/rental.v1.RentObjectService/DeleteMatchRule"
+	RentObjectService_GetMatchRules_FullMethodName     = "/rental.v1.RentObjectService/GetMatchRules"
+)
+
+// RentObjectServiceClient is the client API for RentObjectService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RentObjectService mirrors the HTTP API of the server.
+type RentObjectServiceClient interface {
+	AddObject(ctx context.Context, in *AddObjectRequest, opts ...grpc.CallOption) (*AddObjectResponse, error)
+	DeleteObject(ctx context.Context, in *DeleteObjectRequest, opts ...grpc.CallOption) (*DeleteObjectResponse, error)
+	UpdateObject(ctx context.Context, in *UpdateObjectRequest, opts ...grpc.CallOption) (*UpdateObjectResponse, error)
+	GetObject(ctx context.Context, in *GetObjectRequest, opts ...grpc.CallOption) (*GetObjectResponse, error)
+	GetAllObjects(ctx context.Context, in *GetAllObjectsRequest, opts ...grpc.CallOption) (*GetAllObjectsResponse, error)
+	FindObjects(ctx context.Context, in *FindObjectsRequest, opts ...grpc.CallOption) (*FindObjectsResponse, error)
+	GetObjectInfo(ctx context.Context, in *GetObjectInfoRequest, opts ...grpc.CallOption) (*GetObjectInfoResponse, error)
+	AddRecord(ctx context.Context, in *AddRecordRequest, opts ...grpc.CallOption) (*AddRecordResponse, error)
+	DeleteRecord(ctx context.Context, in *DeleteRecordRequest, opts ...grpc.CallOption) (*DeleteRecordResponse, error)
+	UpdateRecord(ctx context.Context, in *UpdateRecordRequest, opts ...grpc.CallOption) (*UpdateRecordResponse, error)
+	GetRecord(ctx context.Context, in *GetRecordRequest, opts ...grpc.CallOption) (*GetRecordResponse, error)
+	GetRecords(ctx context.Context, in *GetRecordsRequest, opts ...grpc.CallOption) (*GetRecordsResponse, error)
+	FindRecords(ctx context.Context, in *FindRecordsRequest, opts ...grpc.CallOption) (*FindRecordsResponse, error)
+	GetObjectTotals(ctx context.Context, in *GetObjectTotalsRequest, opts ...grpc.CallOption) (*GetObjectTotalsResponse, error)
+	GetPeriodTotals(ctx context.Context, in *GetPeriodTotalsRequest, opts ...grpc.CallOption) (*GetPeriodTotalsResponse, error)
+	GetCategoryTotals(ctx context.Context, in *GetCategoryTotalsRequest, opts ...grpc.CallOption) (*GetCategoryTotalsResponse, error)
+	AddMatchRule(ctx context.Context, in *AddMatchRuleRequest, opts ...grpc.CallOption) (*AddMatchRuleResponse, error)
+	DeleteMatchRule(ctx context.Context, in *DeleteMatchRuleRequest, opts ...grpc.CallOption) (*DeleteMatchRuleResponse, error)
+	GetMatchRules(ctx context.Context, in *GetMatchRulesRequest, opts ...grpc.CallOption) (*GetMatchRulesResponse, error)
+}
+
+type rentObjectServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRentObjectServiceClient(cc grpc.ClientConnInterface) RentObjectServiceClient {
+	return &rentObjectServiceClient{cc}
+}
+
+func (c *rentObjectServiceClient) AddObject(ctx context.Context, in *AddObjectRequest, opts ...grpc.CallOption) (*AddObjectResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddObjectResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_AddObject_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) DeleteObject(ctx context.Context, in *DeleteObjectRequest, opts ...grpc.CallOption) (*DeleteObjectResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteObjectResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_DeleteObject_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) UpdateObject(ctx context.Context, in *UpdateObjectRequest, opts ...grpc.CallOption) (*UpdateObjectResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateObjectResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_UpdateObject_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) GetObject(ctx context.Context, in *GetObjectRequest, opts ...grpc.CallOption) (*GetObjectResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetObjectResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_GetObject_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) GetAllObjects(ctx context.Context, in *GetAllObjectsRequest, opts ...grpc.CallOption) (*GetAllObjectsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetAllObjectsResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_GetAllObjects_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) FindObjects(ctx context.Context, in *FindObjectsRequest, opts ...grpc.CallOption) (*FindObjectsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FindObjectsResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_FindObjects_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) GetObjectInfo(ctx context.Context, in *GetObjectInfoRequest, opts ...grpc.CallOption) (*GetObjectInfoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetObjectInfoResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_GetObjectInfo_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) AddRecord(ctx context.Context, in *AddRecordRequest, opts ...grpc.CallOption) (*AddRecordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddRecordResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_AddRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) DeleteRecord(ctx context.Context, in *DeleteRecordRequest, opts ...grpc.CallOption) (*DeleteRecordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteRecordResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_DeleteRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) UpdateRecord(ctx context.Context, in *UpdateRecordRequest, opts ...grpc.CallOption) (*UpdateRecordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UpdateRecordResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_UpdateRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) GetRecord(ctx context.Context, in *GetRecordRequest, opts ...grpc.CallOption) (*GetRecordResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRecordResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_GetRecord_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) GetRecords(ctx context.Context, in *GetRecordsRequest, opts ...grpc.CallOption) (*GetRecordsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetRecordsResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_GetRecords_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) FindRecords(ctx context.Context, in *FindRecordsRequest, opts ...grpc.CallOption) (*FindRecordsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(FindRecordsResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_FindRecords_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) GetObjectTotals(ctx context.Context, in *GetObjectTotalsRequest, opts ...grpc.CallOption) (*GetObjectTotalsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetObjectTotalsResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_GetObjectTotals_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) GetPeriodTotals(ctx context.Context, in *GetPeriodTotalsRequest, opts ...grpc.CallOption) (*GetPeriodTotalsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetPeriodTotalsResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_GetPeriodTotals_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) GetCategoryTotals(ctx context.Context, in *GetCategoryTotalsRequest, opts ...grpc.CallOption) (*GetCategoryTotalsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetCategoryTotalsResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_GetCategoryTotals_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) AddMatchRule(ctx context.Context, in *AddMatchRuleRequest, opts ...grpc.CallOption) (*AddMatchRuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AddMatchRuleResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_AddMatchRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) DeleteMatchRule(ctx context.Context, in *DeleteMatchRuleRequest, opts ...grpc.CallOption) (*DeleteMatchRuleResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DeleteMatchRuleResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_DeleteMatchRule_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rentObjectServiceClient) GetMatchRules(ctx context.Context, in *GetMatchRulesRequest, opts ...grpc.CallOption) (*GetMatchRulesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetMatchRulesResponse)
+	err := c.cc.Invoke(ctx, RentObjectService_GetMatchRules_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RentObjectServiceServer is the server API for RentObjectService service.
+// All implementations must embed UnimplementedRentObjectServiceServer
+// for forward compatibility.
+//
+// RentObjectService mirrors the HTTP API of the server.
+type RentObjectServiceServer interface {
+	AddObject(context.Context, *AddObjectRequest) (*AddObjectResponse, error)
+	DeleteObject(context.Context, *DeleteObjectRequest) (*DeleteObjectResponse, error)
+	UpdateObject(context.Context, *UpdateObjectRequest) (*UpdateObjectResponse, error)
+	GetObject(context.Context, *GetObjectRequest) (*GetObjectResponse, error)
+	GetAllObjects(context.Context, *GetAllObjectsRequest) (*GetAllObjectsResponse, error)
+	FindObjects(context.Context, *FindObjectsRequest) (*FindObjectsResponse, error)
+	GetObjectInfo(context.Context, *GetObjectInfoRequest) (*GetObjectInfoResponse, error)
+	AddRecord(context.Context, *AddRecordRequest) (*AddRecordResponse, error)
+	DeleteRecord(context.Context, *DeleteRecordRequest) (*DeleteRecordResponse, error)
+	UpdateRecord(context.Context, *UpdateRecordRequest) (*UpdateRecordResponse, error)
+	GetRecord(context.Context, *GetRecordRequest) (*GetRecordResponse, error)
+	GetRecords(context.Context, *GetRecordsRequest) (*GetRecordsResponse, error)
+	FindRecords(context.Context, *FindRecordsRequest) (*FindRecordsResponse, error)
+	GetObjectTotals(context.Context, *GetObjectTotalsRequest) (*GetObjectTotalsResponse, error)
+	GetPeriodTotals(context.Context, *GetPeriodTotalsRequest) (*GetPeriodTotalsResponse, error)
+	GetCategoryTotals(context.Context, *GetCategoryTotalsRequest) (*GetCategoryTotalsResponse, error)
+	AddMatchRule(context.Context, *AddMatchRuleRequest) (*AddMatchRuleResponse, error)
+	DeleteMatchRule(context.Context, *DeleteMatchRuleRequest) (*DeleteMatchRuleResponse, error)
+	GetMatchRules(context.Context, *GetMatchRulesRequest) (*GetMatchRulesResponse, error)
+	mustEmbedUnimplementedRentObjectServiceServer()
+}
+
+// UnimplementedRentObjectServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRentObjectServiceServer struct{}
+
+func (UnimplementedRentObjectServiceServer) AddObject(context.Context, *AddObjectRequest) (*AddObjectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddObject not implemented")
+}
+func (UnimplementedRentObjectServiceServer) DeleteObject(context.Context, *DeleteObjectRequest) (*DeleteObjectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteObject not implemented")
+}
+func (UnimplementedRentObjectServiceServer) UpdateObject(context.Context, *UpdateObjectRequest) (*UpdateObjectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateObject not implemented")
+}
+func (UnimplementedRentObjectServiceServer) GetObject(context.Context, *GetObjectRequest) (*GetObjectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetObject not implemented")
+}
+func (UnimplementedRentObjectServiceServer) GetAllObjects(context.Context, *GetAllObjectsRequest) (*GetAllObjectsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAllObjects not implemented")
+}
+func (UnimplementedRentObjectServiceServer) FindObjects(context.Context, *FindObjectsRequest) (*FindObjectsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindObjects not implemented")
+}
+func (UnimplementedRentObjectServiceServer) GetObjectInfo(context.Context, *GetObjectInfoRequest) (*GetObjectInfoResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetObjectInfo not implemented")
+}
+func (UnimplementedRentObjectServiceServer) AddRecord(context.Context, *AddRecordRequest) (*AddRecordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddRecord not implemented")
+}
+func (UnimplementedRentObjectServiceServer) DeleteRecord(context.Context, *DeleteRecordRequest) (*DeleteRecordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRecord not implemented")
+}
+func (UnimplementedRentObjectServiceServer) UpdateRecord(context.Context, *UpdateRecordRequest) (*UpdateRecordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateRecord not implemented")
+}
+func (UnimplementedRentObjectServiceServer) GetRecord(context.Context, *GetRecordRequest) (*GetRecordResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecord not implemented")
+}
+func (UnimplementedRentObjectServiceServer) GetRecords(context.Context, *GetRecordsRequest) (*GetRecordsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRecords not implemented")
+}
+func (UnimplementedRentObjectServiceServer) FindRecords(context.Context, *FindRecordsRequest) (*FindRecordsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindRecords not implemented")
+}
+func (UnimplementedRentObjectServiceServer) GetObjectTotals(context.Context, *GetObjectTotalsRequest) (*GetObjectTotalsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetObjectTotals not implemented")
+}
+func (UnimplementedRentObjectServiceServer) GetPeriodTotals(context.Context, *GetPeriodTotalsRequest) (*GetPeriodTotalsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPeriodTotals not implemented")
+}
+func (UnimplementedRentObjectServiceServer) GetCategoryTotals(context.Context, *GetCategoryTotalsRequest) (*GetCategoryTotalsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCategoryTotals not implemented")
+}
+func (UnimplementedRentObjectServiceServer) AddMatchRule(context.Context, *AddMatchRuleRequest) (*AddMatchRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddMatchRule not implemented")
+}
+func (UnimplementedRentObjectServiceServer) DeleteMatchRule(context.Context, *DeleteMatchRuleRequest) (*DeleteMatchRuleResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMatchRule not implemented")
+}
+func (UnimplementedRentObjectServiceServer) GetMatchRules(context.Context, *GetMatchRulesRequest) (*GetMatchRulesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMatchRules not implemented")
+}
+func (UnimplementedRentObjectServiceServer) mustEmbedUnimplementedRentObjectServiceServer() {}
+func (UnimplementedRentObjectServiceServer) testEmbeddedByValue()                           {}
+
+// UnsafeRentObjectServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RentObjectServiceServer will
+// result in compilation errors.
+type UnsafeRentObjectServiceServer interface {
+	mustEmbedUnimplementedRentObjectServiceServer()
+}
+
+func RegisterRentObjectServiceServer(s grpc.ServiceRegistrar, srv RentObjectServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRentObjectServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RentObjectService_ServiceDesc, srv)
+}
+
+func _RentObjectService_AddObject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddObjectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).AddObject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_AddObject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).AddObject(ctx, req.(*AddObjectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_DeleteObject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteObjectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).DeleteObject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_DeleteObject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).DeleteObject(ctx, req.(*DeleteObjectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_UpdateObject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateObjectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).UpdateObject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_UpdateObject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).UpdateObject(ctx, req.(*UpdateObjectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_GetObject_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetObjectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).GetObject(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_GetObject_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).GetObject(ctx, req.(*GetObjectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_GetAllObjects_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetAllObjectsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).GetAllObjects(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_GetAllObjects_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).GetAllObjects(ctx, req.(*GetAllObjectsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_FindObjects_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindObjectsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).FindObjects(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_FindObjects_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).FindObjects(ctx, req.(*FindObjectsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_GetObjectInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetObjectInfoRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).GetObjectInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_GetObjectInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).GetObjectInfo(ctx, req.(*GetObjectInfoRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_AddRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).AddRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_AddRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).AddRecord(ctx, req.(*AddRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_DeleteRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).DeleteRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_DeleteRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).DeleteRecord(ctx, req.(*DeleteRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_UpdateRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).UpdateRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_UpdateRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).UpdateRecord(ctx, req.(*UpdateRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_GetRecord_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRecordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).GetRecord(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_GetRecord_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).GetRecord(ctx, req.(*GetRecordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_GetRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRecordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).GetRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_GetRecords_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).GetRecords(ctx, req.(*GetRecordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_FindRecords_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindRecordsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).FindRecords(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_FindRecords_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).FindRecords(ctx, req.(*FindRecordsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_GetObjectTotals_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetObjectTotalsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).GetObjectTotals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_GetObjectTotals_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).GetObjectTotals(ctx, req.(*GetObjectTotalsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_GetPeriodTotals_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetPeriodTotalsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).GetPeriodTotals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_GetPeriodTotals_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).GetPeriodTotals(ctx, req.(*GetPeriodTotalsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_GetCategoryTotals_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetCategoryTotalsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).GetCategoryTotals(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_GetCategoryTotals_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).GetCategoryTotals(ctx, req.(*GetCategoryTotalsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_AddMatchRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddMatchRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).AddMatchRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_AddMatchRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).AddMatchRule(ctx, req.(*AddMatchRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_DeleteMatchRule_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMatchRuleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).DeleteMatchRule(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_DeleteMatchRule_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).DeleteMatchRule(ctx, req.(*DeleteMatchRuleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RentObjectService_GetMatchRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMatchRulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RentObjectServiceServer).GetMatchRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RentObjectService_GetMatchRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RentObjectServiceServer).GetMatchRules(ctx, req.(*GetMatchRulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RentObjectService_ServiceDesc is the grpc.ServiceDesc for RentObjectService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RentObjectService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "rental.v1.RentObjectService",
+	HandlerType: (*RentObjectServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "AddObject",
+			Handler:    _RentObjectService_AddObject_Handler,
+		},
+		{
+			MethodName: "DeleteObject",
+			Handler:    _RentObjectService_DeleteObject_Handler,
+		},
+		{
+			MethodName: "UpdateObject",
+			Handler:    _RentObjectService_UpdateObject_Handler,
+		},
+		{
+			MethodName: "GetObject",
+			Handler:    _RentObjectService_GetObject_Handler,
+		},
+		{
+			MethodName: "GetAllObjects",
+			Handler:    _RentObjectService_GetAllObjects_Handler,
+		},
+		{
+			MethodName: "FindObjects",
+			Handler:    _RentObjectService_FindObjects_Handler,
+		},
+		{
+			MethodName: "GetObjectInfo",
+			Handler:    _RentObjectService_GetObjectInfo_Handler,
+		},
+		{
+			MethodName: "AddRecord",
+			Handler:    _RentObjectService_AddRecord_Handler,
+		},
+		{
+			MethodName: "DeleteRecord",
+			Handler:    _RentObjectService_DeleteRecord_Handler,
+		},
+		{
+			MethodName: "UpdateRecord",
+			Handler:    _RentObjectService_UpdateRecord_Handler,
+		},
+		{
+			MethodName: "GetRecord",
+			Handler:    _RentObjectService_GetRecord_Handler,
+		},
+		{
+			MethodName: "GetRecords",
+			Handler:    _RentObjectService_GetRecords_Handler,
+		},
+		{
+			MethodName: "FindRecords",
+			Handler:    _RentObjectService_FindRecords_Handler,
+		},
+		{
+			MethodName: "GetObjectTotals",
+			Handler:    _RentObjectService_GetObjectTotals_Handler,
+		},
+		{
+			MethodName: "GetPeriodTotals",
+			Handler:    _RentObjectService_GetPeriodTotals_Handler,
+		},
+		{
+			MethodName: "GetCategoryTotals",
+			Handler:    _RentObjectService_GetCategoryTotals_Handler,
+		},
+		{
+			MethodName: "AddMatchRule",
+			Handler:    _RentObjectService_AddMatchRule_Handler,
+		},
+		{
+			MethodName: "DeleteMatchRule",
+			Handler:    _RentObjectService_DeleteMatchRule_Handler,
+		},
+		{
+			MethodName: "GetMatchRules",
+			Handler:    _RentObjectService_GetMatchRules_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "rental.proto",
+}
@@ -0,0 +1,304 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"rental-server/internal/bankimport"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	pb "rental-server/internal/server/grpc/rentalpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type RentObjectServer struct {
+	pb.UnimplementedRentObjectServiceServer
+	rep repository.RentObjectRepository
+}
+
+func NewRentObjectServer(rep repository.RentObjectRepository) *RentObjectServer {
+	return &RentObjectServer{rep: rep}
+}
+
+// NewServer returns a gRPC server with the rent object service registered.
+func NewServer(rep repository.RentObjectRepository, opts ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(opts...)
+	pb.RegisterRentObjectServiceServer(server, NewRentObjectServer(rep))
+	return server
+}
+
+func (s *RentObjectServer) AddObject(ctx context.Context, req *pb.AddObjectRequest) (*pb.AddObjectResponse, error) {
+	if req.Object == nil {
+		return nil, status.Error(codes.InvalidArgument, "Missing fields in request: `object`")
+	}
+
+	if err := s.rep.Add(ctx, req.UserId, fromPBObject(req.Object)); err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.AddObjectResponse{}, nil
+}
+
+func (s *RentObjectServer) DeleteObject(ctx context.Context, req *pb.DeleteObjectRequest) (*pb.DeleteObjectResponse, error) {
+	if err := s.rep.Delete(ctx, req.UserId, req.ObjectName); err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.DeleteObjectResponse{}, nil
+}
+
+func (s *RentObjectServer) UpdateObject(ctx context.Context, req *pb.UpdateObjectRequest) (*pb.UpdateObjectResponse, error) {
+	if req.UpdateInput == nil {
+		return nil, status.Error(codes.InvalidArgument, "Missing fields in request: `update_input`")
+	}
+
+	err := s.rep.Update(ctx, req.UserId, req.ObjectName, fromPBUpdateRentObjectInput(req.UpdateInput))
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.UpdateObjectResponse{}, nil
+}
+
+func (s *RentObjectServer) GetObject(ctx context.Context, req *pb.GetObjectRequest) (*pb.GetObjectResponse, error) {
+	object, err := s.rep.GetByName(ctx, req.UserId, req.ObjectName)
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.GetObjectResponse{Object: toPBObject(object)}, nil
+}
+
+func (s *RentObjectServer) GetAllObjects(ctx context.Context, req *pb.GetAllObjectsRequest) (*pb.GetAllObjectsResponse, error) {
+	objects, err := s.rep.GetAll(ctx, req.UserId)
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+
+	response := &pb.GetAllObjectsResponse{}
+	for _, object := range objects {
+		response.Objects = append(response.Objects, toPBObject(object))
+	}
+	return response, nil
+}
+
+func (s *RentObjectServer) FindObjects(ctx context.Context, req *pb.FindObjectsRequest) (*pb.FindObjectsResponse, error) {
+	page, err := s.rep.FindObjects(ctx, req.UserId, repository.ObjectQuery{
+		Period:     fromPBPeriod(req.From, req.To),
+		NamePrefix: req.NamePrefix,
+		MinProfit:  rubPtr(req.MinProfit),
+		MaxProfit:  rubPtr(req.MaxProfit),
+		SortBy:     repository.SortField(req.SortBy),
+		Descending: req.Descending,
+		Cursor:     req.Cursor,
+		Limit:      int(req.Limit),
+	})
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+
+	response := &pb.FindObjectsResponse{NextCursor: page.NextCursor}
+	for _, object := range page.Items {
+		response.Objects = append(response.Objects, toPBObject(object))
+	}
+	return response, nil
+}
+
+func (s *RentObjectServer) GetObjectInfo(ctx context.Context, req *pb.GetObjectInfoRequest) (*pb.GetObjectInfoResponse, error) {
+	period := fromPBPeriod(req.From, req.To)
+	object, err := s.rep.GetByNameInPeriod(ctx, req.UserId, req.ObjectName, period)
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.GetObjectInfoResponse{Info: toPBObjectInfo(domain.NewRentObjectInfoForPeriod(object, period))}, nil
+}
+
+func (s *RentObjectServer) AddRecord(ctx context.Context, req *pb.AddRecordRequest) (*pb.AddRecordResponse, error) {
+	if req.Record == nil {
+		return nil, status.Error(codes.InvalidArgument, "Missing fields in request: `record`")
+	}
+
+	index, err := s.rep.AddRecord(ctx, req.UserId, req.ObjectName, fromPBRecord(req.Record))
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.AddRecordResponse{RecordIndex: int32(index)}, nil
+}
+
+func (s *RentObjectServer) DeleteRecord(ctx context.Context, req *pb.DeleteRecordRequest) (*pb.DeleteRecordResponse, error) {
+	if err := s.rep.DeleteRecord(ctx, req.UserId, req.ObjectName, int(req.RecordIndex)); err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.DeleteRecordResponse{}, nil
+}
+
+func (s *RentObjectServer) UpdateRecord(ctx context.Context, req *pb.UpdateRecordRequest) (*pb.UpdateRecordResponse, error) {
+	if req.UpdateInput == nil {
+		return nil, status.Error(codes.InvalidArgument, "Missing fields in request: `update_input`")
+	}
+
+	err := s.rep.UpdateRecord(ctx, req.UserId, req.ObjectName, int(req.RecordIndex), fromPBUpdateRecordInput(req.UpdateInput))
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.UpdateRecordResponse{}, nil
+}
+
+func (s *RentObjectServer) GetRecord(ctx context.Context, req *pb.GetRecordRequest) (*pb.GetRecordResponse, error) {
+	record, err := s.rep.GetRecordByIndex(ctx, req.UserId, req.ObjectName, int(req.RecordIndex))
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.GetRecordResponse{Record: toPBRecord(record)}, nil
+}
+
+func (s *RentObjectServer) GetRecords(ctx context.Context, req *pb.GetRecordsRequest) (*pb.GetRecordsResponse, error) {
+	records, err := s.rep.GetAllRecords(ctx, req.UserId, req.ObjectName)
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.GetRecordsResponse{Records: toPBRecords(records)}, nil
+}
+
+func (s *RentObjectServer) FindRecords(ctx context.Context, req *pb.FindRecordsRequest) (*pb.FindRecordsResponse, error) {
+	page, err := s.rep.FindRecords(ctx, req.UserId, req.ObjectName, repository.RecordQuery{
+		Period:     fromPBPeriod(req.From, req.To),
+		MinAmount:  rubPtr(req.MinAmount),
+		MaxAmount:  rubPtr(req.MaxAmount),
+		SortBy:     repository.SortField(req.SortBy),
+		Descending: req.Descending,
+		Cursor:     req.Cursor,
+		Limit:      int(req.Limit),
+	})
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+
+	response := &pb.FindRecordsResponse{NextCursor: page.NextCursor}
+	for _, entry := range page.Items {
+		response.Records = append(response.Records, &pb.RecordEntry{Index: int32(entry.Index), Record: toPBRecord(entry.Record)})
+	}
+	return response, nil
+}
+
+func (s *RentObjectServer) GetObjectTotals(ctx context.Context, req *pb.GetObjectTotalsRequest) (*pb.GetObjectTotalsResponse, error) {
+	query := repository.TotalsQuery{Period: fromPBPeriod(req.From, req.To), ObjectName: req.ObjectName}
+	totals, err := s.rep.TotalsByObject(ctx, req.UserId, query)
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+
+	response := &pb.GetObjectTotalsResponse{}
+	for _, t := range totals {
+		response.Totals = append(response.Totals, &pb.ObjectTotals{Name: t.Name, Totals: toPBTotals(t.Totals)})
+	}
+	return response, nil
+}
+
+func (s *RentObjectServer) GetPeriodTotals(ctx context.Context, req *pb.GetPeriodTotalsRequest) (*pb.GetPeriodTotalsResponse, error) {
+	query := repository.TotalsQuery{
+		Period:      fromPBPeriod(req.From, req.To),
+		ObjectName:  req.ObjectName,
+		Granularity: repository.Granularity(req.Granularity),
+	}
+	totals, err := s.rep.TotalsByPeriod(ctx, req.UserId, query)
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+
+	response := &pb.GetPeriodTotalsResponse{}
+	for _, t := range totals {
+		response.Totals = append(response.Totals, &pb.PeriodTotals{Start: toPBTimestamp(&t.Start), Totals: toPBTotals(t.Totals)})
+	}
+	return response, nil
+}
+
+func (s *RentObjectServer) GetCategoryTotals(ctx context.Context, req *pb.GetCategoryTotalsRequest) (*pb.GetCategoryTotalsResponse, error) {
+	query := repository.TotalsQuery{Period: fromPBPeriod(req.From, req.To), ObjectName: req.ObjectName}
+	totals, err := s.rep.TotalsByCategory(ctx, req.UserId, query)
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+
+	response := &pb.GetCategoryTotalsResponse{}
+	for _, t := range totals {
+		response.Totals = append(response.Totals, &pb.CategoryTotal{Category: t.Category, Amount: float64(t.Amount)})
+	}
+	return response, nil
+}
+
+func (s *RentObjectServer) AddMatchRule(ctx context.Context, req *pb.AddMatchRuleRequest) (*pb.AddMatchRuleResponse, error) {
+	if req.Rule == nil {
+		return nil, status.Error(codes.InvalidArgument, "Missing fields in request: `rule`")
+	}
+	rule := fromPBMatchRule(req.Rule)
+	if err := bankimport.ValidateRule(rule); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	index, err := s.rep.AddMatchRule(ctx, req.UserId, rule)
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.AddMatchRuleResponse{RuleIndex: int32(index)}, nil
+}
+
+func (s *RentObjectServer) DeleteMatchRule(ctx context.Context, req *pb.DeleteMatchRuleRequest) (*pb.DeleteMatchRuleResponse, error) {
+	if err := s.rep.DeleteMatchRule(ctx, req.UserId, int(req.RuleIndex)); err != nil {
+		return nil, processRepositoryError(err)
+	}
+	return &pb.DeleteMatchRuleResponse{}, nil
+}
+
+func (s *RentObjectServer) GetMatchRules(ctx context.Context, req *pb.GetMatchRulesRequest) (*pb.GetMatchRulesResponse, error) {
+	rules, err := s.rep.GetMatchRules(ctx, req.UserId)
+	if err != nil {
+		return nil, processRepositoryError(err)
+	}
+
+	response := &pb.GetMatchRulesResponse{}
+	for _, rule := range rules {
+		response.Rules = append(response.Rules, toPBMatchRule(rule))
+	}
+	return response, nil
+}
+
+// statusError is the status that clients get for an error, which keeps the
+// error for the log.
+type statusError struct {
+	status *status.Status
+	err    error
+}
+
+func (e *statusError) Error() string {
+	return e.status.Message()
+}
+
+func (e *statusError) GRPCStatus() *status.Status {
+	return e.status
+}
+
+func (e *statusError) Unwrap() error {
+	return e.err
+}
+
+func processRepositoryError(err error) error {
+	code, message := codes.Internal, "Error happend on server"
+	switch {
+	case errors.Is(err, domain.RecordNotFoundError):
+		code, message = codes.NotFound, "Record not found"
+	case errors.Is(err, domain.MatchRuleNotFoundError):
+		code, message = codes.NotFound, "Match rule not found"
+	case errors.Is(err, repository.ObjectNotFoundError):
+		code, message = codes.NotFound, "Object not found"
+	case errors.Is(err, repository.ObjectAlreadyExists):
+		code, message = codes.AlreadyExists, "Object already exists"
+	case errors.Is(err, repository.InvalidQueryError):
+		code, message = codes.InvalidArgument, "Invalid query"
+	case errors.Is(err, repository.ConcurrentUpdateError):
+		code, message = codes.Aborted, "Object changed concurrently, try again"
+	case errors.Is(err, repository.TimeoutError), errors.Is(err, context.DeadlineExceeded):
+		code, message = codes.DeadlineExceeded, "Storage did not respond in time"
+	case errors.Is(err, repository.CanceledError), errors.Is(err, context.Canceled):
+		code, message = codes.Canceled, "Request canceled"
+	}
+	return &statusError{status: status.New(code, message), err: err}
+}
@@ -0,0 +1,289 @@
+package grpcapi_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository/memory"
+	grpcapi "rental-server/internal/server/grpc"
+	pb "rental-server/internal/server/grpc/rentalpb"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var ctx = context.Background()
+
+var dummyUserID int64 = 1
+
+func newClient(t *testing.T, rep *memory.MemoryObjectRepository, opts ...grpc.ServerOption) pb.RentObjectServiceClient {
+	t.Helper()
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpcapi.NewServer(rep, opts...)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewRentObjectServiceClient(conn)
+}
+
+func TestObjects(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should add and get object", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		client := newClient(t, rep)
+
+		_, err := client.AddObject(ctx, &pb.AddObjectRequest{UserId: dummyUserID, Object: &pb.RentObject{Name: "Name", Description: "Description", Area: 100}})
+		assert.NoError(t, err)
+
+		got, err := client.GetObject(ctx, &pb.GetObjectRequest{UserId: dummyUserID, ObjectName: "Name"})
+		if !assert.NoError(t, err) {
+			t.Fatal(err)
+		}
+		assert.Equal(t, "Description", got.Object.Description)
+		assert.Equal(t, 100.0, got.Object.Area)
+	})
+
+	t.Run("Should return InvalidArgument if object is missing", func(t *testing.T) {
+		client := newClient(t, memory.NewMemoryObjectRepository(nil))
+
+		_, err := client.AddObject(ctx, &pb.AddObjectRequest{UserId: dummyUserID})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Should return NotFound if object doesnt exist", func(t *testing.T) {
+		client := newClient(t, memory.NewMemoryObjectRepository(nil))
+
+		_, err := client.DeleteObject(ctx, &pb.DeleteObjectRequest{UserId: dummyUserID, ObjectName: "Name"})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+	})
+
+	t.Run("Should update only set fields", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_ = rep.Add(ctx, dummyUserID, domain.NewRentObject("Name", "Description", 100))
+		client := newClient(t, rep)
+
+		area := 200.0
+		_, err := client.UpdateObject(ctx, &pb.UpdateObjectRequest{UserId: dummyUserID, ObjectName: "Name", UpdateInput: &pb.UpdateRentObjectInput{Area: &area}})
+		assert.NoError(t, err)
+
+		got, _ := rep.GetByName(ctx, dummyUserID, "Name")
+		assert.Equal(t, domain.NewRentObject("Name", "Description", 200), got)
+	})
+}
+
+func TestRecords(t *testing.T) {
+	ctx := context.Background()
+	date := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	rep := memory.NewMemoryObjectRepository(nil)
+	_ = rep.Add(ctx, dummyUserID, domain.NewRentObject("Name", "Description", 100))
+	client := newClient(t, rep)
+
+	added, err := client.AddRecord(ctx, &pb.AddRecordRequest{UserId: dummyUserID, ObjectName: "Name", Record: &pb.Record{Date: timestamppb.New(date), Rent: 1000, EarthRent: 200}})
+	if !assert.NoError(t, err) {
+		t.Fatal(err)
+	}
+
+	heat := 100.0
+	_, err = client.UpdateRecord(ctx, &pb.UpdateRecordRequest{UserId: dummyUserID, ObjectName: "Name", RecordIndex: added.RecordIndex, UpdateInput: &pb.UpdateRecordInput{Heat: &heat}})
+	assert.NoError(t, err)
+
+	got, err := client.GetRecord(ctx, &pb.GetRecordRequest{UserId: dummyUserID, ObjectName: "Name", RecordIndex: added.RecordIndex})
+	if !assert.NoError(t, err) {
+		t.Fatal(err)
+	}
+	assert.Equal(t, date, got.Record.Date.AsTime())
+	assert.Equal(t, 1000.0, got.Record.Rent)
+	assert.Equal(t, 100.0, got.Record.Heat)
+
+	info, err := client.GetObjectInfo(ctx, &pb.GetObjectInfoRequest{UserId: dummyUserID, ObjectName: "Name"})
+	if assert.NoError(t, err) && assert.Len(t, info.Info.RecordsInfo, 1) {
+		assert.Equal(t, 700.0, info.Info.RecordsInfo[0].Profit)
+		assert.Equal(t, 7.0, info.Info.RecordsInfo[0].ProfitByArea)
+		assert.Equal(t, 700.0, info.Info.Total.Profit)
+	}
+
+	info, err = client.GetObjectInfo(ctx, &pb.GetObjectInfoRequest{UserId: dummyUserID, ObjectName: "Name", From: timestamppb.New(date.AddDate(0, 1, 0))})
+	if assert.NoError(t, err) {
+		assert.Empty(t, info.Info.RecordsInfo)
+		assert.Equal(t, 0.0, info.Info.Total.Profit)
+		assert.Equal(t, date.AddDate(0, 1, 0), info.Info.From.AsTime())
+	}
+
+	_, err = client.DeleteRecord(ctx, &pb.DeleteRecordRequest{UserId: dummyUserID, ObjectName: "Name", RecordIndex: added.RecordIndex})
+	assert.NoError(t, err)
+
+	_, err = client.GetRecord(ctx, &pb.GetRecordRequest{UserId: dummyUserID, ObjectName: "Name", RecordIndex: added.RecordIndex})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func newObjects() *memory.MemoryObjectRepository {
+	rep := memory.NewMemoryObjectRepository(nil)
+	for i, name := range []string{"Office", "Shop", "Warehouse"} {
+		object := domain.NewRentObject(name, "", float64(100*(i+1)))
+		for month := 1; month <= 3; month++ {
+			object.AddRecord(domain.Record{Date: time.Date(2025, time.Month(month), 1, 0, 0, 0, 0, time.UTC), Rent: domain.RUB(1000 * (i + 1)), Heat: 100})
+		}
+		_ = rep.Add(ctx, dummyUserID, object)
+	}
+	return rep
+}
+
+func TestFind(t *testing.T) {
+	client := newClient(t, newObjects())
+
+	t.Run("Should page objects", func(t *testing.T) {
+		request := &pb.FindObjectsRequest{UserId: dummyUserID, SortBy: "profit", Descending: true, Limit: 2}
+		var names []string
+		for {
+			page, err := client.FindObjects(ctx, request)
+			if !assert.NoError(t, err) {
+				t.Fatal(err)
+			}
+			for _, object := range page.Objects {
+				names = append(names, object.Name)
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			request.Cursor = page.NextCursor
+		}
+		assert.Equal(t, []string{"Warehouse", "Shop", "Office"}, names)
+	})
+
+	t.Run("Should filter records with their indexes", func(t *testing.T) {
+		from := timestamppb.New(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+		page, err := client.FindRecords(ctx, &pb.FindRecordsRequest{UserId: dummyUserID, ObjectName: "Shop", From: from, Descending: true})
+		if !assert.NoError(t, err) {
+			t.Fatal(err)
+		}
+		if assert.Len(t, page.Records, 2) {
+			assert.Equal(t, int32(2), page.Records[0].Index)
+			assert.Equal(t, 2000.0, page.Records[0].Record.Rent)
+		}
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("Should return InvalidArgument on invalid query", func(t *testing.T) {
+		_, err := client.FindObjects(ctx, &pb.FindObjectsRequest{UserId: dummyUserID, SortBy: "color"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		_, err = client.FindRecords(ctx, &pb.FindRecordsRequest{UserId: dummyUserID, ObjectName: "Shop", Cursor: "garbage"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestTotals(t *testing.T) {
+	client := newClient(t, newObjects())
+	to := timestamppb.New(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC))
+
+	objects, err := client.GetObjectTotals(ctx, &pb.GetObjectTotalsRequest{UserId: dummyUserID, To: to})
+	if assert.NoError(t, err) && assert.Len(t, objects.Totals, 3) {
+		assert.Equal(t, "Office", objects.Totals[0].Name)
+		assert.Equal(t, &pb.Totals{Income: 2000, Expenses: 200, Profit: 1800, Records: 2}, objects.Totals[0].Totals)
+	}
+
+	periods, err := client.GetPeriodTotals(ctx, &pb.GetPeriodTotalsRequest{UserId: dummyUserID, ObjectName: "Shop", Granularity: "quarter"})
+	if assert.NoError(t, err) && assert.Len(t, periods.Totals, 1) {
+		assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), periods.Totals[0].Start.AsTime())
+		assert.Equal(t, 5700.0, periods.Totals[0].Totals.Profit)
+	}
+
+	categories, err := client.GetCategoryTotals(ctx, &pb.GetCategoryTotalsRequest{UserId: dummyUserID})
+	if assert.NoError(t, err) && assert.NotEmpty(t, categories.Totals) {
+		assert.Equal(t, &pb.CategoryTotal{Category: "rent", Amount: 18000}, categories.Totals[0])
+	}
+
+	_, err = client.GetPeriodTotals(ctx, &pb.GetPeriodTotalsRequest{UserId: dummyUserID, Granularity: "week"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetObjectTotals(ctx, &pb.GetObjectTotalsRequest{UserId: dummyUserID, ObjectName: "Missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMatchRules(t *testing.T) {
+	client := newClient(t, memory.NewMemoryObjectRepository(nil))
+	rule := &pb.MatchRule{ObjectName: "Shop", Field: "rent", Direction: "in", Counterparty: "7701234567"}
+
+	added, err := client.AddMatchRule(ctx, &pb.AddMatchRuleRequest{UserId: dummyUserID, Rule: rule})
+	if !assert.NoError(t, err) {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int32(0), added.RuleIndex)
+
+	got, err := client.GetMatchRules(ctx, &pb.GetMatchRulesRequest{UserId: dummyUserID})
+	if assert.NoError(t, err) && assert.Len(t, got.Rules, 1) {
+		assert.Equal(t, "7701234567", got.Rules[0].Counterparty)
+		assert.Equal(t, "rent", got.Rules[0].Field)
+	}
+
+	_, err = client.AddMatchRule(ctx, &pb.AddMatchRuleRequest{UserId: dummyUserID, Rule: &pb.MatchRule{ObjectName: "Shop", Field: "color"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.DeleteMatchRule(ctx, &pb.DeleteMatchRuleRequest{UserId: dummyUserID, RuleIndex: added.RuleIndex})
+	assert.NoError(t, err)
+
+	_, err = client.DeleteMatchRule(ctx, &pb.DeleteMatchRuleRequest{UserId: dummyUserID, RuleIndex: added.RuleIndex})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+type observer struct {
+	routes []string
+	codes  []int
+}
+
+func (o *observer) ObserveRequest(route string, code int, duration time.Duration) {
+	o.routes = append(o.routes, route)
+	o.codes = append(o.codes, code)
+}
+
+func TestInstrument(t *testing.T) {
+	observed := &observer{}
+	logs := &bytes.Buffer{}
+	client := newClient(t, memory.NewMemoryObjectRepository(nil), grpcapi.Instrument(observed, slog.New(slog.NewJSONHandler(logs, nil))))
+
+	t.Run("Should keep the request ID of the client", func(t *testing.T) {
+		var header metadata.MD
+		ctx := metadata.AppendToOutgoingContext(ctx, "x-request-id", "abc-123")
+		_, err := client.GetObject(ctx, &pb.GetObjectRequest{UserId: dummyUserID, ObjectName: "Missing"}, grpc.Header(&header))
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		assert.Equal(t, []string{"abc-123"}, header.Get("x-request-id"))
+
+		assert.Equal(t, []string{"/rental.v1.RentObjectService/GetObject"}, observed.routes)
+		assert.Equal(t, []int{http.StatusNotFound}, observed.codes)
+		assert.Contains(t, logs.String(), `"request_id":"abc-123"`)
+		assert.Contains(t, logs.String(), `"user_id":1`)
+	})
+
+	t.Run("Should make a request ID", func(t *testing.T) {
+		var header metadata.MD
+		_, err := client.GetAllObjects(ctx, &pb.GetAllObjectsRequest{UserId: dummyUserID}, grpc.Header(&header))
+		assert.NoError(t, err)
+		if assert.Len(t, header.Get("x-request-id"), 1) {
+			assert.Len(t, header.Get("x-request-id")[0], 32)
+		}
+	})
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"rental-server/internal/repository"
+	"time"
+)
+
+var HealthzPath = "/healthz"
+var ReadyzPath = "/readyz"
+
+// ReadinessTimeout limits the ping of the storage, so that probes get an
+// answer before they time out themselves.
+var ReadinessTimeout = 2 * time.Second
+
+// WithPinger makes the server ready only while the storage answers pings.
+// Without it the server is always ready.
+func WithPinger(pinger repository.Pinger) Option {
+	return func(s *RentObjectServer) {
+		s.pinger = pinger
+	}
+}
+
+// healthz tells that the process serves requests, whatever the state of
+// the storage, so that it is not restarted while the storage is down.
+func (s *RentObjectServer) healthz(w http.ResponseWriter, r *http.Request) *appError {
+	io.WriteString(w, "ok")
+	return nil
+}
+
+// readyz tells whether the server can serve requests, which needs the
+// storage.
+func (s *RentObjectServer) readyz(w http.ResponseWriter, r *http.Request) *appError {
+	if s.pinger != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), ReadinessTimeout)
+		defer cancel()
+		if err := s.pinger.Ping(ctx); err != nil {
+			if s.logger != nil {
+				s.logger.WarnContext(ctx, "Storage is not ready", "error", err)
+			}
+			return &appError{err, "Storage is unavailable", http.StatusServiceUnavailable}
+		}
+	}
+	io.WriteString(w, "ok")
+	return nil
+}
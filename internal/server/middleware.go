@@ -0,0 +1,189 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestIDHeader carries the ID of a request. An ID sent by the client or a
+// proxy is kept, otherwise one is made, and it is returned in the response.
+var RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limits IDs taken from clients, since they are logged.
+const maxRequestIDLength = 128
+
+// RequestObserver is told of each request with its route, status code and
+// duration, e.g. for metrics.
+type RequestObserver interface {
+	ObserveRequest(route string, code int, duration time.Duration)
+}
+
+// WithRequestObserver tells the observer of each request.
+func WithRequestObserver(observer RequestObserver) Option {
+	return func(s *RentObjectServer) {
+		s.observer = observer
+	}
+}
+
+// WithLogger logs each request with its ID, method, route, status, duration
+// and user, and the error of the failed ones.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *RentObjectServer) {
+		s.logger = logger
+	}
+}
+
+// requestInfo is filled in while the request is served, for its log.
+type requestInfo struct {
+	id     string
+	userID *int64
+	err    error
+}
+
+type requestInfoKey struct{}
+
+func requestInfoFrom(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+// RequestID returns the ID of the request that the context belongs to, or an
+// empty string outside of requests.
+func RequestID(ctx context.Context) string {
+	if info := requestInfoFrom(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// KeepRequestID returns the ID sent by a client or a proxy when it is
+// valid, or a new one, e.g. for the gRPC server.
+func KeepRequestID(id string) string {
+	if validRequestID(id) {
+		return id
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// validRequestID accepts printable ASCII without spaces, so that IDs cannot
+// forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range []byte(id) {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+var tracer = otel.Tracer("rental-server/internal/server")
+
+// instrument gives requests to the route an ID and a span, which continues
+// the trace of the client, and tells the observer and the logger of them.
+func (s *RentObjectServer) instrument(route string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		info := &requestInfo{id: KeepRequestID(r.Header.Get(RequestIDHeader))}
+		if userID, err := getUserIdParam(r.URL.Query()); err == nil {
+			info.userID = &userID
+		}
+		w.Header().Set(RequestIDHeader, info.id)
+		recorder := &statusRecorder{ResponseWriter: w, code: http.StatusOK}
+
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("rental.request_id", info.id),
+		))
+		r = r.WithContext(context.WithValue(ctx, requestInfoKey{}, info))
+
+		handler.ServeHTTP(recorder, r)
+
+		duration := time.Since(start)
+		endSpan(span, info, recorder.code)
+		if s.observer != nil {
+			s.observer.ObserveRequest(route, recorder.code, duration)
+		}
+		if s.logger != nil {
+			s.logRequest(r, route, info, recorder.code, duration)
+		}
+	})
+}
+
+func endSpan(span trace.Span, info *requestInfo, code int) {
+	span.SetAttributes(attribute.Int("http.response.status_code", code))
+	if info.userID != nil {
+		span.SetAttributes(attribute.Int64("rental.user_id", *info.userID))
+	}
+	if code >= http.StatusInternalServerError {
+		if info.err != nil {
+			span.RecordError(info.err)
+		}
+		span.SetStatus(codes.Error, http.StatusText(code))
+	}
+	span.End()
+}
+
+// logRequest logs server errors with their cause, which clients do not get.
+func (s *RentObjectServer) logRequest(r *http.Request, route string, info *requestInfo, code int, duration time.Duration) {
+	attrs := []slog.Attr{
+		slog.String("request_id", info.id),
+		slog.String("method", r.Method),
+		slog.String("route", route),
+		slog.Int("status", code),
+		slog.Duration("duration", duration),
+	}
+	if info.userID != nil {
+		attrs = append(attrs, slog.Int64("user_id", *info.userID))
+	}
+
+	level := slog.LevelInfo
+	if code >= http.StatusInternalServerError {
+		level = slog.LevelError
+		if info.err != nil {
+			attrs = append(attrs, slog.String("error", info.err.Error()))
+		}
+	}
+	s.logger.LogAttrs(r.Context(), level, "Request", attrs...)
+}
+
+// statusRecorder keeps the status code written to the response.
+type statusRecorder struct {
+	http.ResponseWriter
+	code        int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.code = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the response writer.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,64 @@
+package openapi_test
+
+import (
+	"rental-server/internal/domain"
+	"rental-server/internal/server/openapi"
+	"rental-server/internal/server/requests"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaOf(t *testing.T) {
+	t.Run("Named structs should be registered as components", func(t *testing.T) {
+		doc := openapi.NewDocument(openapi.Info{})
+
+		got := doc.SchemaOf(domain.RentObject{})
+
+		assert.Equal(t, &openapi.Schema{Ref: "#/components/schemas/RentObject"}, got)
+		assert.Contains(t, doc.Components.Schemas, "RentObject")
+		assert.Contains(t, doc.Components.Schemas, "Record")
+
+		records := doc.Components.Schemas["RentObject"].Properties["records"]
+		assert.Equal(t, "array", records.Type)
+		assert.Equal(t, "#/components/schemas/Record", records.Items.Ref)
+	})
+
+	t.Run("Should map field types to json names", func(t *testing.T) {
+		doc := openapi.NewDocument(openapi.Info{})
+		doc.SchemaOf(domain.Record{})
+
+		record := doc.Components.Schemas["Record"]
+
+		assert.Equal(t, &openapi.Schema{Type: "string", Format: "date-time"}, record.Properties["date"])
+		assert.Equal(t, &openapi.Schema{Type: "number", Format: "double"}, record.Properties["earth_rent"])
+		assert.Contains(t, record.Required, "earth_rent")
+	})
+
+	t.Run("Embedded structs should be flattened", func(t *testing.T) {
+		doc := openapi.NewDocument(openapi.Info{})
+		doc.SchemaOf(domain.RecordInfo{})
+
+		info := doc.Components.Schemas["RecordInfo"]
+
+		assert.Contains(t, info.Properties, "rent")
+		assert.Contains(t, info.Properties, "profit_by_area")
+	})
+
+	t.Run("Pointer fields of update inputs should be optional", func(t *testing.T) {
+		doc := openapi.NewDocument(openapi.Info{})
+		doc.SchemaOf(domain.UpdateRecordInput{})
+
+		assert.Empty(t, doc.Components.Schemas["UpdateRecordInput"].Required)
+	})
+
+	t.Run("Pointer fields of requests should be required", func(t *testing.T) {
+		doc := openapi.NewDocument(openapi.Info{})
+		doc.RequestSchemaOf(requests.AddRecordRequest{})
+		doc.RequestSchemaOf(requests.AddRecordRequest{})
+
+		got := doc.Components.Schemas["AddRecordRequest"].Required
+
+		assert.Equal(t, []string{"user_id", "object_name", "record"}, got)
+	})
+}
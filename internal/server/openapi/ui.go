@@ -0,0 +1,58 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	swaggerFiles "github.com/swaggo/files/v2"
+)
+
+const indexPage = `<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="UTF-8">
+    <title>%s</title>
+    <link rel="stylesheet" type="text/css" href="swagger-ui.css" />
+    <link rel="stylesheet" type="text/css" href="index.css" />
+    <link rel="icon" type="image/png" href="favicon-32x32.png" sizes="32x32" />
+    <link rel="icon" type="image/png" href="favicon-16x16.png" sizes="16x16" />
+  </head>
+  <body>
+    <div id="swagger-ui"></div>
+    <script src="swagger-ui-bundle.js" charset="UTF-8"></script>
+    <script src="swagger-ui-standalone-preset.js" charset="UTF-8"></script>
+    <script>
+      window.onload = function() {
+        window.ui = SwaggerUIBundle({
+          url: %q,
+          dom_id: '#swagger-ui',
+          deepLinking: true,
+          presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+          layout: "StandaloneLayout"
+        });
+      };
+    </script>
+  </body>
+</html>
+`
+
+// UIHandler serves a bundled Swagger UI under prefix that loads the document
+// from specURL. No external resources are requested by the page.
+func UIHandler(prefix string, title string, specURL string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/") + "/"
+	index := fmt.Sprintf(indexPage, title, specURL)
+	files := http.StripPrefix(prefix, http.FileServer(http.FS(swaggerFiles.FS)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case strings.TrimSuffix(prefix, "/"):
+			http.Redirect(w, r, prefix, http.StatusMovedPermanently)
+		case prefix, prefix + "index.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, index)
+		default:
+			files.ServeHTTP(w, r)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package requests
 import (
 	"fmt"
 	"reflect"
+	"rental-server/internal/bankimport"
 	"rental-server/internal/domain"
 )
 
@@ -73,3 +74,18 @@ type UpdateRecordRequest struct {
 	RecordIndex *int                      `json:"record_index"`
 	UpdateInput *domain.UpdateRecordInput `json:"update_input"`
 }
+
+type AddMatchRuleRequest struct {
+	UserID *int64            `json:"user_id"`
+	Rule   *domain.MatchRule `json:"rule"`
+}
+
+type DeleteMatchRuleRequest struct {
+	UserID    *int64 `json:"user_id"`
+	RuleIndex *int   `json:"rule_index"`
+}
+
+type ConfirmBankRecordsRequest struct {
+	UserID  *int64                       `json:"user_id"`
+	Records *[]bankimport.ProposedRecord `json:"records"`
+}
@@ -1,20 +1,55 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
+	"reflect"
+	"rental-server/internal/backup"
+	"rental-server/internal/bankimport"
 	"rental-server/internal/domain"
+	"rental-server/internal/exporter"
+	"rental-server/internal/importer"
+	"rental-server/internal/report"
 	"rental-server/internal/repository"
+	graphqlapi "rental-server/internal/server/graphql"
+	"rental-server/internal/server/openapi"
 	"rental-server/internal/server/requests"
+	"slices"
 	"strconv"
+	"time"
 )
 
 var UserIdQueryParam = "userId"
 var ObjectNameQueryParam = "objectName"
 var RecordIndexQueryParam = "recordIndex"
+var LimitQueryParam = "limit"
+var CursorQueryParam = "cursor"
+var SortQueryParam = "sort"
+var OrderQueryParam = "order"
+var NamePrefixQueryParam = "namePrefix"
+var MinProfitQueryParam = "minProfit"
+var MaxProfitQueryParam = "maxProfit"
+var FromQueryParam = "from"
+var ToQueryParam = "to"
+var MinAmountQueryParam = "minAmount"
+var MaxAmountQueryParam = "maxAmount"
+var DryRunQueryParam = "dryRun"
+var MapQueryParam = "map"
+var CommaQueryParam = "comma"
+var DateLayoutQueryParam = "dateLayout"
+var FormatQueryParam = "format"
+var AccountQueryParam = "account"
+var ModeQueryParam = "mode"
+var GranularityQueryParam = "granularity"
+var GraphQLPath = "/graphql"
 
 type appHandler func(w http.ResponseWriter, r *http.Request) *appError
 
@@ -26,32 +61,66 @@ type appError struct {
 
 func (fn appHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if err := fn(w, r); err != nil {
+		if info := requestInfoFrom(r.Context()); info != nil {
+			info.err = err.Error
+		}
 		http.Error(w, err.Msg, err.Code)
 	}
 }
 
 type RentObjectServer struct {
-	rep repository.RentObjectRepository
+	rep      repository.RentObjectRepository
+	observer RequestObserver
+	logger   *slog.Logger
+	pinger   repository.Pinger
 	http.Handler
 }
 
-func NewRentObjectServer(rep repository.RentObjectRepository) *RentObjectServer {
+type Option func(*RentObjectServer)
+
+func NewRentObjectServer(rep repository.RentObjectRepository, options ...Option) *RentObjectServer {
 	server := &RentObjectServer{
 		rep: rep,
 	}
+	for _, option := range options {
+		option(server)
+	}
 
 	router := http.NewServeMux()
-	router.Handle("/addObject", appHandler(server.addObject))
-	router.Handle("/deleteObject", appHandler(server.deleteObject))
-	router.Handle("/updateObject", appHandler(server.updateObject))
-	router.Handle("/getObject", appHandler(server.getObject))
-	router.Handle("/getObjectInfo", appHandler(server.getObjectInfo))
-	router.Handle("/getAll", appHandler(server.getAll))
-	router.Handle("/addRecord", appHandler(server.addRecord))
-	router.Handle("/deleteRecord", appHandler(server.deleteRecord))
-	router.Handle("/updateRecord", appHandler(server.updateRecord))
-	router.Handle("/getRecord", appHandler(server.getRecord))
-	router.Handle("/getRecords", appHandler(server.getRecords))
+	handle := func(route string, handler http.Handler) {
+		router.Handle(route, server.instrument(route, handler))
+	}
+	handle("/addObject", appHandler(server.addObject))
+	handle("/deleteObject", appHandler(server.deleteObject))
+	handle("/updateObject", appHandler(server.updateObject))
+	handle("/getObject", appHandler(server.getObject))
+	handle("/getObjectInfo", appHandler(server.getObjectInfo))
+	handle("/getAll", appHandler(server.getAll))
+	handle("/addRecord", appHandler(server.addRecord))
+	handle("/deleteRecord", appHandler(server.deleteRecord))
+	handle("/updateRecord", appHandler(server.updateRecord))
+	handle("/getRecord", appHandler(server.getRecord))
+	handle("/getRecords", appHandler(server.getRecords))
+	handle("/importRecords", appHandler(server.importRecords))
+	handle("/exportObject", appHandler(server.exportObject))
+	handle("/exportAll", appHandler(server.exportAll))
+	handle("/getObjectReport", appHandler(server.getObjectReport))
+	handle("/getObjectTotals", appHandler(server.getObjectTotals))
+	handle("/getPeriodTotals", appHandler(server.getPeriodTotals))
+	handle("/getCategoryTotals", appHandler(server.getCategoryTotals))
+	handle("/exportBackup", appHandler(server.exportBackup))
+	handle("/restoreBackup", appHandler(server.restoreBackup))
+	handle("/addMatchRule", appHandler(server.addMatchRule))
+	handle("/deleteMatchRule", appHandler(server.deleteMatchRule))
+	handle("/getMatchRules", appHandler(server.getMatchRules))
+	handle("/importBankStatement", appHandler(server.importBankStatement))
+	handle("/confirmBankRecords", appHandler(server.confirmBankRecords))
+	handle(GraphQLPath, graphqlapi.NewHandler(rep))
+	handle(OpenAPIPath, openAPIHandler(NewOpenAPIDocument()))
+	handle(SwaggerUIPath, openapi.UIHandler(SwaggerUIPath, "Rental server API", OpenAPIPath))
+	// Probes are frequent, so they are not logged, traced or measured.
+	router.Handle(HealthzPath, appHandler(server.healthz))
+	router.Handle(ReadyzPath, appHandler(server.readyz))
 
 	server.Handler = router
 
@@ -61,11 +130,11 @@ func NewRentObjectServer(rep repository.RentObjectRepository) *RentObjectServer
 func (s *RentObjectServer) addObject(w http.ResponseWriter, r *http.Request) *appError {
 	var addObjectRequest requests.AddObjectRequest
 
-	if err := parseRequest(r.Body, &addObjectRequest); err != nil {
+	if err := parseRequest(r, &addObjectRequest); err != nil {
 		return &appError{err, "Error while parsing body", http.StatusUnprocessableEntity}
 	}
 
-	err := s.rep.Add(*addObjectRequest.UserID, *addObjectRequest.Object)
+	err := s.rep.Add(r.Context(), *addObjectRequest.UserID, *addObjectRequest.Object)
 	if err != nil {
 		return processRepositoryError(err)
 	}
@@ -77,11 +146,11 @@ func (s *RentObjectServer) addObject(w http.ResponseWriter, r *http.Request) *ap
 func (s *RentObjectServer) deleteObject(w http.ResponseWriter, r *http.Request) *appError {
 	var deleteObjectRequest requests.DeleteObjectRequest
 
-	if err := parseRequest(r.Body, &deleteObjectRequest); err != nil {
+	if err := parseRequest(r, &deleteObjectRequest); err != nil {
 		return &appError{err, "Error while parsing body", http.StatusUnprocessableEntity}
 	}
 
-	err := s.rep.Delete(*deleteObjectRequest.UserID, *deleteObjectRequest.ObjectName)
+	err := s.rep.Delete(r.Context(), *deleteObjectRequest.UserID, *deleteObjectRequest.ObjectName)
 	if err != nil {
 		return processRepositoryError(err)
 	}
@@ -91,10 +160,10 @@ func (s *RentObjectServer) deleteObject(w http.ResponseWriter, r *http.Request)
 func (s *RentObjectServer) updateObject(w http.ResponseWriter, r *http.Request) *appError {
 	var updateObjectRequest requests.UpdateObjectRequest
 
-	if err := parseRequest(r.Body, &updateObjectRequest); err != nil {
+	if err := parseRequest(r, &updateObjectRequest); err != nil {
 		return &appError{err, "Error while parsing body", http.StatusUnprocessableEntity}
 	}
-	err := s.rep.Update(*updateObjectRequest.UserID, *updateObjectRequest.ObjectName, *updateObjectRequest.UpdateInput)
+	err := s.rep.Update(r.Context(), *updateObjectRequest.UserID, *updateObjectRequest.ObjectName, *updateObjectRequest.UpdateInput)
 
 	if err != nil {
 		return processRepositoryError(err)
@@ -115,7 +184,7 @@ func (s *RentObjectServer) getObject(w http.ResponseWriter, r *http.Request) *ap
 		return &appError{errors.New("getObject: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
 	}
 
-	object, err := s.rep.GetByName(userID, objectName)
+	object, err := s.rep.GetByName(r.Context(), userID, objectName)
 
 	if err != nil {
 		return processRepositoryError(err)
@@ -125,6 +194,8 @@ func (s *RentObjectServer) getObject(w http.ResponseWriter, r *http.Request) *ap
 	return nil
 }
 
+// getAll returns a page of objects when any of objectQueryParams is given,
+// and all the objects otherwise.
 func (s *RentObjectServer) getAll(w http.ResponseWriter, r *http.Request) *appError {
 	query := r.URL.Query()
 
@@ -138,7 +209,22 @@ func (s *RentObjectServer) getAll(w http.ResponseWriter, r *http.Request) *appEr
 		return &appError{errors.New("getAll: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
 	}
 
-	objects, err := s.rep.GetAll(userID)
+	if isQueryHasAnyParameter(query, objectQueryParams...) {
+		objectQuery, err := getObjectQueryParams(query)
+		if err != nil {
+			return &appError{errors.New("getAll: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+		}
+
+		page, err := s.rep.FindObjects(r.Context(), userID, objectQuery)
+		if err != nil {
+			return processRepositoryError(err)
+		}
+
+		json.NewEncoder(w).Encode(page)
+		return nil
+	}
+
+	objects, err := s.rep.GetAll(r.Context(), userID)
 	if err != nil {
 		return processRepositoryError(err)
 	}
@@ -150,11 +236,11 @@ func (s *RentObjectServer) getAll(w http.ResponseWriter, r *http.Request) *appEr
 func (s *RentObjectServer) addRecord(w http.ResponseWriter, r *http.Request) *appError {
 	var addRecordRequest requests.AddRecordRequest
 
-	if err := parseRequest(r.Body, &addRecordRequest); err != nil {
+	if err := parseRequest(r, &addRecordRequest); err != nil {
 		return &appError{err, "Error while parsing body", http.StatusUnprocessableEntity}
 	}
 
-	_, err := s.rep.AddRecord(*addRecordRequest.UserID, *addRecordRequest.ObjectName, *addRecordRequest.Record)
+	_, err := s.rep.AddRecord(r.Context(), *addRecordRequest.UserID, *addRecordRequest.ObjectName, *addRecordRequest.Record)
 	if err != nil {
 		return processRepositoryError(err)
 	}
@@ -164,11 +250,11 @@ func (s *RentObjectServer) addRecord(w http.ResponseWriter, r *http.Request) *ap
 func (s *RentObjectServer) deleteRecord(w http.ResponseWriter, r *http.Request) *appError {
 	var deleteRecordRequest requests.DeleteRecordRequest
 
-	if err := parseRequest(r.Body, &deleteRecordRequest); err != nil {
+	if err := parseRequest(r, &deleteRecordRequest); err != nil {
 		return &appError{err, "Error while parsing body", http.StatusUnprocessableEntity}
 	}
 
-	err := s.rep.DeleteRecord(*deleteRecordRequest.UserID, *deleteRecordRequest.ObjectName, *deleteRecordRequest.RecordIndex)
+	err := s.rep.DeleteRecord(r.Context(), *deleteRecordRequest.UserID, *deleteRecordRequest.ObjectName, *deleteRecordRequest.RecordIndex)
 
 	if err != nil {
 		return processRepositoryError(err)
@@ -179,11 +265,11 @@ func (s *RentObjectServer) deleteRecord(w http.ResponseWriter, r *http.Request)
 func (s *RentObjectServer) updateRecord(w http.ResponseWriter, r *http.Request) *appError {
 	var updateRecordRequest requests.UpdateRecordRequest
 
-	if err := parseRequest(r.Body, &updateRecordRequest); err != nil {
+	if err := parseRequest(r, &updateRecordRequest); err != nil {
 		return &appError{err, "Error while parsing body", http.StatusUnprocessableEntity}
 	}
 
-	err := s.rep.UpdateRecord(*updateRecordRequest.UserID, *updateRecordRequest.ObjectName, *updateRecordRequest.RecordIndex, *updateRecordRequest.UpdateInput)
+	err := s.rep.UpdateRecord(r.Context(), *updateRecordRequest.UserID, *updateRecordRequest.ObjectName, *updateRecordRequest.RecordIndex, *updateRecordRequest.UpdateInput)
 	if err != nil {
 		return processRepositoryError(err)
 	}
@@ -203,7 +289,7 @@ func (s *RentObjectServer) getRecord(w http.ResponseWriter, r *http.Request) *ap
 	if errRec != nil || errUsr != nil {
 		return &appError{errors.New("getRecord: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
 	}
-	record, err := s.rep.GetRecordByIndex(userID, objectName, recordIndex)
+	record, err := s.rep.GetRecordByIndex(r.Context(), userID, objectName, recordIndex)
 
 	if err != nil {
 		return processRepositoryError(err)
@@ -213,6 +299,8 @@ func (s *RentObjectServer) getRecord(w http.ResponseWriter, r *http.Request) *ap
 	return nil
 }
 
+// getRecords returns a page of records with their indexes when any of
+// recordQueryParams is given, and all the records otherwise.
 func (s *RentObjectServer) getRecords(w http.ResponseWriter, r *http.Request) *appError {
 	query := r.URL.Query()
 	if !query.Has(ObjectNameQueryParam) || !query.Has(UserIdQueryParam) {
@@ -226,7 +314,22 @@ func (s *RentObjectServer) getRecords(w http.ResponseWriter, r *http.Request) *a
 		return &appError{errors.New("getRecords: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
 	}
 
-	records, err := s.rep.GetAllRecords(userID, objectName)
+	if isQueryHasAnyParameter(query, recordQueryParams...) {
+		recordQuery, err := getRecordQueryParams(query)
+		if err != nil {
+			return &appError{errors.New("getRecords: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+		}
+
+		page, err := s.rep.FindRecords(r.Context(), userID, objectName, recordQuery)
+		if err != nil {
+			return processRepositoryError(err)
+		}
+
+		json.NewEncoder(w).Encode(page)
+		return nil
+	}
+
+	records, err := s.rep.GetAllRecords(r.Context(), userID, objectName)
 	if err != nil {
 		return processRepositoryError(err)
 	}
@@ -235,6 +338,333 @@ func (s *RentObjectServer) getRecords(w http.ResponseWriter, r *http.Request) *a
 	return nil
 }
 
+func (s *RentObjectServer) importRecords(w http.ResponseWriter, r *http.Request) *appError {
+	query := r.URL.Query()
+	if !query.Has(ObjectNameQueryParam) || !query.Has(UserIdQueryParam) {
+		return &appError{errors.New("importRecords: incorrect query parameters name"), "Incorrect query parameters name", http.StatusUnprocessableEntity}
+	}
+
+	userID, errUsr := getUserIdParam(query)
+	objectName := getObjectNameParam(query)
+	dryRun, errDryRun := getBoolParam(query, DryRunQueryParam)
+	options, errOptions := getImportOptionsParams(query)
+
+	if errUsr != nil || errDryRun != nil || errOptions != nil {
+		return &appError{errors.New("importRecords: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+	}
+
+	records, rowErrors, err := importer.ParseCSV(r.Body, options)
+	if err != nil {
+		return &appError{err, "Error while parsing CSV: " + err.Error(), http.StatusUnprocessableEntity}
+	}
+
+	report, err := importer.AddRecords(r.Context(), s.rep, userID, objectName, records, rowErrors, dryRun)
+	if err != nil {
+		return processRepositoryError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(report.Errors) != 0 && !dryRun {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(report)
+	return nil
+}
+
+func (s *RentObjectServer) exportObject(w http.ResponseWriter, r *http.Request) *appError {
+	query := r.URL.Query()
+	if !query.Has(ObjectNameQueryParam) || !query.Has(UserIdQueryParam) {
+		return &appError{errors.New("exportObject: incorrect query parameters name"), "Incorrect query parameters name", http.StatusUnprocessableEntity}
+	}
+
+	userID, errUsr := getUserIdParam(query)
+	objectName := getObjectNameParam(query)
+	period, errPeriod := getPeriodParams(query)
+	format, errFormat := getExportFormatParam(query)
+	accounts, errAccounts := getAccountsParam(query)
+
+	if errUsr != nil || errPeriod != nil || errFormat != nil || errAccounts != nil {
+		return &appError{errors.New("exportObject: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+	}
+
+	object, err := s.rep.GetByNameInPeriod(r.Context(), userID, objectName, period)
+	if err != nil {
+		return processRepositoryError(err)
+	}
+
+	return writeExport(w, format, objectName, []domain.RentObject{object}, period, accounts)
+}
+
+func (s *RentObjectServer) exportAll(w http.ResponseWriter, r *http.Request) *appError {
+	query := r.URL.Query()
+	if !query.Has(UserIdQueryParam) {
+		return &appError{errors.New("exportAll: incorrect query parameters name"), "Incorrect query parameters name", http.StatusUnprocessableEntity}
+	}
+
+	userID, errUsr := getUserIdParam(query)
+	period, errPeriod := getPeriodParams(query)
+	format, errFormat := getExportFormatParam(query)
+	accounts, errAccounts := getAccountsParam(query)
+
+	if errUsr != nil || errPeriod != nil || errFormat != nil || errAccounts != nil {
+		return &appError{errors.New("exportAll: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+	}
+
+	objects, err := s.rep.GetAll(r.Context(), userID)
+	if err != nil {
+		return processRepositoryError(err)
+	}
+
+	return writeExport(w, format, "portfolio", objects, period, accounts)
+}
+
+func (s *RentObjectServer) getObjectReport(w http.ResponseWriter, r *http.Request) *appError {
+	query := r.URL.Query()
+	if !query.Has(ObjectNameQueryParam) || !query.Has(UserIdQueryParam) {
+		return &appError{errors.New("getObjectReport: incorrect query parameters name"), "Incorrect query parameters name", http.StatusUnprocessableEntity}
+	}
+
+	userID, errUsr := getUserIdParam(query)
+	objectName := getObjectNameParam(query)
+	period, errPeriod := getPeriodParams(query)
+
+	if errUsr != nil || errPeriod != nil {
+		return &appError{errors.New("getObjectReport: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+	}
+
+	object, err := s.rep.GetByNameInPeriod(r.Context(), userID, objectName, period)
+	if err != nil {
+		return processRepositoryError(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := report.WritePDF(buf, domain.NewRentObjectInfoForPeriod(object, period)); err != nil {
+		return &appError{err, "Error happend on server", http.StatusInternalServerError}
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": objectName + ".pdf"}))
+	w.Write(buf.Bytes())
+	return nil
+}
+
+func (s *RentObjectServer) exportBackup(w http.ResponseWriter, r *http.Request) *appError {
+	query := r.URL.Query()
+	if !query.Has(UserIdQueryParam) {
+		return &appError{errors.New("exportBackup: incorrect query parameters name"), "Incorrect query parameters name", http.StatusUnprocessableEntity}
+	}
+
+	userID, errUsr := getUserIdParam(query)
+
+	if errUsr != nil {
+		return &appError{errors.New("exportBackup: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+	}
+
+	b, err := backup.Create(r.Context(), s.rep, userID)
+	if err != nil {
+		return processRepositoryError(err)
+	}
+
+	filename := fmt.Sprintf("backup-%d-%s.json", userID, b.CreatedAt.Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	backup.Write(w, b)
+	return nil
+}
+
+func (s *RentObjectServer) restoreBackup(w http.ResponseWriter, r *http.Request) *appError {
+	query := r.URL.Query()
+	if !query.Has(UserIdQueryParam) {
+		return &appError{errors.New("restoreBackup: incorrect query parameters name"), "Incorrect query parameters name", http.StatusUnprocessableEntity}
+	}
+
+	userID, errUsr := getUserIdParam(query)
+	mode, errMode := backup.ParseMode(query.Get(ModeQueryParam))
+
+	if errUsr != nil || errMode != nil {
+		return &appError{errors.New("restoreBackup: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+	}
+
+	b, err := backup.Read(r.Body)
+	if err != nil {
+		return &appError{err, "Error while reading backup: " + err.Error(), http.StatusUnprocessableEntity}
+	}
+
+	report, err := backup.Restore(r.Context(), s.rep, userID, b, mode)
+	if err != nil {
+		return processRepositoryError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+	return nil
+}
+
+func (s *RentObjectServer) addMatchRule(w http.ResponseWriter, r *http.Request) *appError {
+	var addMatchRuleRequest requests.AddMatchRuleRequest
+
+	if err := parseRequest(r, &addMatchRuleRequest); err != nil {
+		return &appError{err, "Error while parsing body", http.StatusUnprocessableEntity}
+	}
+	if err := bankimport.ValidateRule(*addMatchRuleRequest.Rule); err != nil {
+		return &appError{err, err.Error(), http.StatusUnprocessableEntity}
+	}
+
+	_, err := s.rep.AddMatchRule(r.Context(), *addMatchRuleRequest.UserID, *addMatchRuleRequest.Rule)
+	if err != nil {
+		return processRepositoryError(err)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+func (s *RentObjectServer) deleteMatchRule(w http.ResponseWriter, r *http.Request) *appError {
+	var deleteMatchRuleRequest requests.DeleteMatchRuleRequest
+
+	if err := parseRequest(r, &deleteMatchRuleRequest); err != nil {
+		return &appError{err, "Error while parsing body", http.StatusUnprocessableEntity}
+	}
+
+	err := s.rep.DeleteMatchRule(r.Context(), *deleteMatchRuleRequest.UserID, *deleteMatchRuleRequest.RuleIndex)
+	if err != nil {
+		return processRepositoryError(err)
+	}
+	return nil
+}
+
+func (s *RentObjectServer) getMatchRules(w http.ResponseWriter, r *http.Request) *appError {
+	query := r.URL.Query()
+	if !query.Has(UserIdQueryParam) {
+		return &appError{errors.New("getMatchRules: incorrect query parameters name"), "Incorrect query parameters name", http.StatusUnprocessableEntity}
+	}
+
+	userID, errUsr := getUserIdParam(query)
+
+	if errUsr != nil {
+		return &appError{errors.New("getMatchRules: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+	}
+
+	rules, err := s.rep.GetMatchRules(r.Context(), userID)
+	if err != nil {
+		return processRepositoryError(err)
+	}
+
+	json.NewEncoder(w).Encode(rules)
+	return nil
+}
+
+// importBankStatement only proposes records for the payments of the
+// statement, which are added by confirmBankRecords.
+func (s *RentObjectServer) importBankStatement(w http.ResponseWriter, r *http.Request) *appError {
+	query := r.URL.Query()
+	if !query.Has(UserIdQueryParam) {
+		return &appError{errors.New("importBankStatement: incorrect query parameters name"), "Incorrect query parameters name", http.StatusUnprocessableEntity}
+	}
+
+	userID, errUsr := getUserIdParam(query)
+
+	if errUsr != nil {
+		return &appError{errors.New("importBankStatement: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+	}
+
+	statement, err := bankimport.ParseStatement(r.Body)
+	if err != nil {
+		return &appError{err, "Error while parsing statement: " + err.Error(), http.StatusUnprocessableEntity}
+	}
+
+	rules, err := s.rep.GetMatchRules(r.Context(), userID)
+	if err != nil {
+		return processRepositoryError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bankimport.Match(statement.Payments, rules))
+	return nil
+}
+
+func (s *RentObjectServer) confirmBankRecords(w http.ResponseWriter, r *http.Request) *appError {
+	var confirmRequest requests.ConfirmBankRecordsRequest
+
+	if err := parseRequest(r, &confirmRequest); err != nil {
+		return &appError{err, "Error while parsing body", http.StatusUnprocessableEntity}
+	}
+
+	userID := *confirmRequest.UserID
+	// Records are added per object in one operation after all objects are
+	// checked, and records identical to existing ones are skipped, so a
+	// confirm that failed midway can be retried without duplicating them.
+	var names []string
+	added := map[string][]domain.Record{}
+	existing := map[string][]domain.Record{}
+	for _, proposed := range *confirmRequest.Records {
+		records, ok := existing[proposed.ObjectName]
+		if !ok {
+			object, err := s.rep.GetByName(r.Context(), userID, proposed.ObjectName)
+			if err != nil {
+				return processRepositoryError(err)
+			}
+			names = append(names, proposed.ObjectName)
+			records = object.Records
+		}
+		if slices.ContainsFunc(records, proposed.Record.Equal) {
+			existing[proposed.ObjectName] = records
+			continue
+		}
+		existing[proposed.ObjectName] = append(records, proposed.Record)
+		added[proposed.ObjectName] = append(added[proposed.ObjectName], proposed.Record)
+	}
+	for _, name := range names {
+		if len(added[name]) == 0 {
+			continue
+		}
+		if err := s.rep.AddRecords(r.Context(), userID, name, added[name]); err != nil {
+			return processRepositoryError(err)
+		}
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	return nil
+}
+
+var exportContentTypes = map[string]string{
+	"csv":       "text/csv; charset=utf-8",
+	"xlsx":      "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"ledger":    "text/plain; charset=utf-8",
+	"beancount": "text/plain; charset=utf-8",
+}
+
+func writeExport(w http.ResponseWriter, format string, name string, objects []domain.RentObject, period domain.Period, accounts exporter.Accounts) *appError {
+	infos := make([]domain.RentObjectInfo, 0, len(objects))
+	for _, object := range objects {
+		infos = append(infos, domain.NewRentObjectInfoForPeriod(object, period))
+	}
+
+	write := exporter.WriteCSV
+	switch format {
+	case "xlsx":
+		write = exporter.WriteXLSX
+	case "ledger":
+		write = func(w io.Writer, infos []domain.RentObjectInfo) error {
+			return exporter.WriteLedger(w, infos, accounts)
+		}
+	case "beancount":
+		write = func(w io.Writer, infos []domain.RentObjectInfo) error {
+			return exporter.WriteBeancount(w, infos, accounts)
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := write(buf, infos); err != nil {
+		return &appError{err, "Error happend on server", http.StatusInternalServerError}
+	}
+
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + format}))
+	w.Write(buf.Bytes())
+	return nil
+}
+
 func (s *RentObjectServer) getObjectInfo(w http.ResponseWriter, r *http.Request) *appError {
 	query := r.URL.Query()
 	if !query.Has(ObjectNameQueryParam) || !query.Has(UserIdQueryParam) {
@@ -243,18 +673,65 @@ func (s *RentObjectServer) getObjectInfo(w http.ResponseWriter, r *http.Request)
 
 	userID, errUsr := getUserIdParam(query)
 	objectName := getObjectNameParam(query)
+	period, errPeriod := getPeriodParams(query)
 
-	if errUsr != nil {
+	if errUsr != nil || errPeriod != nil {
 		return &appError{errors.New("getObjectInfo: incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
 	}
 
-	object, err := s.rep.GetByName(userID, objectName)
+	object, err := s.rep.GetByNameInPeriod(r.Context(), userID, objectName, period)
 
 	if err != nil {
 		return processRepositoryError(err)
 	}
 
-	json.NewEncoder(w).Encode(domain.NewRentObjectInfo(object))
+	json.NewEncoder(w).Encode(domain.NewRentObjectInfoForPeriod(object, period))
+	return nil
+}
+
+func (s *RentObjectServer) getObjectTotals(w http.ResponseWriter, r *http.Request) *appError {
+	return s.getTotals(w, r, "getObjectTotals", func(ctx context.Context, userID int64, query repository.TotalsQuery) (any, error) {
+		return s.rep.TotalsByObject(ctx, userID, query)
+	})
+}
+
+func (s *RentObjectServer) getPeriodTotals(w http.ResponseWriter, r *http.Request) *appError {
+	return s.getTotals(w, r, "getPeriodTotals", func(ctx context.Context, userID int64, query repository.TotalsQuery) (any, error) {
+		return s.rep.TotalsByPeriod(ctx, userID, query)
+	})
+}
+
+func (s *RentObjectServer) getCategoryTotals(w http.ResponseWriter, r *http.Request) *appError {
+	return s.getTotals(w, r, "getCategoryTotals", func(ctx context.Context, userID int64, query repository.TotalsQuery) (any, error) {
+		return s.rep.TotalsByCategory(ctx, userID, query)
+	})
+}
+
+// getTotals parses the query parameters shared by the totals endpoints and
+// writes the totals that sum returns.
+func (s *RentObjectServer) getTotals(w http.ResponseWriter, r *http.Request, name string, sum func(context.Context, int64, repository.TotalsQuery) (any, error)) *appError {
+	query := r.URL.Query()
+	if !query.Has(UserIdQueryParam) {
+		return &appError{errors.New(name + ": incorrect query parameters name"), "Incorrect query parameters name", http.StatusUnprocessableEntity}
+	}
+
+	userID, errUsr := getUserIdParam(query)
+	period, errPeriod := getPeriodParams(query)
+
+	if errUsr != nil || errPeriod != nil {
+		return &appError{errors.New(name + ": incorrect query parameters value"), "Incorrect query parameters value", http.StatusUnprocessableEntity}
+	}
+
+	totals, err := sum(r.Context(), userID, repository.TotalsQuery{
+		Period:      period,
+		ObjectName:  getObjectNameParam(query),
+		Granularity: repository.Granularity(query.Get(GranularityQueryParam)),
+	})
+	if err != nil {
+		return processRepositoryError(err)
+	}
+
+	json.NewEncoder(w).Encode(totals)
 	return nil
 }
 
@@ -270,6 +747,170 @@ func getRecordIndexParam(query url.Values) (int, error) {
 	return strconv.Atoi(query.Get(RecordIndexQueryParam))
 }
 
+// objectQueryParams are the paging, sorting and filter parameters of
+// objects.
+var objectQueryParams = []string{
+	LimitQueryParam, CursorQueryParam, SortQueryParam, OrderQueryParam,
+	NamePrefixQueryParam, FromQueryParam, ToQueryParam, MinProfitQueryParam, MaxProfitQueryParam,
+}
+
+func getObjectQueryParams(query url.Values) (repository.ObjectQuery, error) {
+	objectQuery := repository.ObjectQuery{
+		NamePrefix: query.Get(NamePrefixQueryParam),
+		SortBy:     repository.SortField(query.Get(SortQueryParam)),
+		Cursor:     query.Get(CursorQueryParam),
+	}
+
+	var err error
+	if objectQuery.Limit, err = getIntParam(query, LimitQueryParam); err != nil {
+		return objectQuery, err
+	}
+	if objectQuery.Descending, err = getDescendingParam(query); err != nil {
+		return objectQuery, err
+	}
+	if objectQuery.Period, err = getPeriodParams(query); err != nil {
+		return objectQuery, err
+	}
+	if objectQuery.MinProfit, err = getRUBParam(query, MinProfitQueryParam); err != nil {
+		return objectQuery, err
+	}
+	if objectQuery.MaxProfit, err = getRUBParam(query, MaxProfitQueryParam); err != nil {
+		return objectQuery, err
+	}
+	return objectQuery, nil
+}
+
+// recordQueryParams are the paging, sorting and filter parameters of
+// records.
+var recordQueryParams = []string{
+	LimitQueryParam, CursorQueryParam, SortQueryParam, OrderQueryParam,
+	FromQueryParam, ToQueryParam, MinAmountQueryParam, MaxAmountQueryParam,
+}
+
+func getRecordQueryParams(query url.Values) (repository.RecordQuery, error) {
+	recordQuery := repository.RecordQuery{
+		SortBy: repository.SortField(query.Get(SortQueryParam)),
+		Cursor: query.Get(CursorQueryParam),
+	}
+
+	var err error
+	if recordQuery.Limit, err = getIntParam(query, LimitQueryParam); err != nil {
+		return recordQuery, err
+	}
+	if recordQuery.Descending, err = getDescendingParam(query); err != nil {
+		return recordQuery, err
+	}
+	if recordQuery.Period, err = getPeriodParams(query); err != nil {
+		return recordQuery, err
+	}
+	if recordQuery.MinAmount, err = getRUBParam(query, MinAmountQueryParam); err != nil {
+		return recordQuery, err
+	}
+	if recordQuery.MaxAmount, err = getRUBParam(query, MaxAmountQueryParam); err != nil {
+		return recordQuery, err
+	}
+	return recordQuery, nil
+}
+
+func getIntParam(query url.Values, param string) (int, error) {
+	if !query.Has(param) {
+		return 0, nil
+	}
+	return strconv.Atoi(query.Get(param))
+}
+
+func getDescendingParam(query url.Values) (bool, error) {
+	switch query.Get(OrderQueryParam) {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	default:
+		return false, errors.New("order should be asc or desc")
+	}
+}
+
+func getRUBParam(query url.Values, param string) (*domain.RUB, error) {
+	if !query.Has(param) {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(query.Get(param), 64)
+	if err != nil {
+		return nil, err
+	}
+	rub := domain.RUB(value)
+	return &rub, nil
+}
+
+// getDateParam accepts both RFC 3339 timestamps and plain dates.
+func getDateParam(query url.Values, param string) (*time.Time, error) {
+	if !query.Has(param) {
+		return nil, nil
+	}
+	value := query.Get(param)
+	date, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		date, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+func getBoolParam(query url.Values, param string) (bool, error) {
+	if !query.Has(param) {
+		return false, nil
+	}
+	return strconv.ParseBool(query.Get(param))
+}
+
+func getImportOptionsParams(query url.Values) (importer.Options, error) {
+	options := importer.Options{DateLayout: query.Get(DateLayoutQueryParam)}
+
+	if query.Has(MapQueryParam) {
+		mapping, err := importer.ParseMapping(query[MapQueryParam])
+		if err != nil {
+			return options, err
+		}
+		options.Mapping = mapping
+	}
+
+	if comma := []rune(query.Get(CommaQueryParam)); len(comma) == 1 {
+		options.Comma = comma[0]
+	} else if len(comma) > 1 {
+		return options, errors.New("comma must be a single character")
+	}
+	return options, nil
+}
+
+func getExportFormatParam(query url.Values) (string, error) {
+	format := query.Get(FormatQueryParam)
+	if format == "" {
+		return "csv", nil
+	}
+	if _, ok := exportContentTypes[format]; !ok {
+		return "", errors.New("unknown export format")
+	}
+	return format, nil
+}
+
+func getAccountsParam(query url.Values) (exporter.Accounts, error) {
+	return exporter.ParseAccounts(query[AccountQueryParam])
+}
+
+func getPeriodParams(query url.Values) (domain.Period, error) {
+	var period domain.Period
+	var err error
+	if period.From, err = getDateParam(query, FromQueryParam); err != nil {
+		return period, err
+	}
+	if period.To, err = getDateParam(query, ToQueryParam); err != nil {
+		return period, err
+	}
+	return period, nil
+}
+
 func isQueryHasParameters(query url.Values, parameters ...string) bool {
 	for _, p := range parameters {
 		if !query.Has(p) {
@@ -279,8 +920,22 @@ func isQueryHasParameters(query url.Values, parameters ...string) bool {
 	return true
 }
 
-func parseRequest(r io.Reader, req any) error {
-	if err := json.NewDecoder(r).Decode(req); err != nil {
+func isQueryHasAnyParameter(query url.Values, parameters ...string) bool {
+	for _, p := range parameters {
+		if query.Has(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRequest decodes the body of the request, and notes its user for the
+// log of the request.
+func parseRequest(r *http.Request, req any) error {
+	_, span := tracer.Start(r.Context(), "parseRequest")
+	err := json.NewDecoder(r.Body).Decode(req)
+	span.End()
+	if err != nil {
 		return err
 	}
 
@@ -288,17 +943,39 @@ func parseRequest(r io.Reader, req any) error {
 		return err
 	}
 
+	if info := requestInfoFrom(r.Context()); info != nil {
+		if userID, ok := reflect.ValueOf(req).Elem().FieldByName("UserID").Interface().(*int64); ok {
+			info.userID = userID
+		}
+	}
 	return nil
 }
 
+// StatusClientClosedRequest is the status of requests whose client went away,
+// as nginx logs it. The client does not get the response.
+const StatusClientClosedRequest = 499
+
 func processRepositoryError(err error) *appError {
+	switch {
+	case errors.Is(err, repository.TimeoutError), errors.Is(err, context.DeadlineExceeded):
+		return &appError{err, "Storage did not respond in time", http.StatusGatewayTimeout}
+	case errors.Is(err, repository.CanceledError), errors.Is(err, context.Canceled):
+		return &appError{err, "Request canceled", StatusClientClosedRequest}
+	}
+
 	switch err {
 	case domain.RecordNotFoundError:
 		return &appError{err, "Record not found", http.StatusNotFound}
+	case domain.MatchRuleNotFoundError:
+		return &appError{err, "Match rule not found", http.StatusNotFound}
 	case repository.ObjectNotFoundError:
 		return &appError{err, "Object not found", http.StatusNotFound}
 	case repository.ObjectAlreadyExists:
 		return &appError{err, "Object already exists", http.StatusConflict}
+	case repository.ConcurrentUpdateError:
+		return &appError{err, "Object changed concurrently, try again", http.StatusConflict}
+	case repository.InvalidQueryError:
+		return &appError{err, "Invalid query", http.StatusUnprocessableEntity}
 	default:
 		return &appError{err, "Error happend on server", http.StatusInternalServerError}
 	}
@@ -0,0 +1,74 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rental-server/internal/backup"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository/memory"
+	"rental-server/internal/server"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackup(t *testing.T) {
+	object := domain.NewRentObject("Склад", "", 10)
+	object.AddRecord(domain.Record{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 100})
+
+	t.Run("Happy path. Export backup and restore it to another server", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_ = rep.Add(ctx, dummyUserID, object)
+		_, _ = rep.AddMatchRule(ctx, dummyUserID, dummyRule)
+
+		responce := httptest.NewRecorder()
+		s := server.NewRentObjectServer(rep)
+		s.ServeHTTP(responce, newExportBackupRequest(dummyUserID))
+		assertStatus(t, responce.Code, http.StatusOK)
+		assert.Contains(t, responce.Header().Get("Content-Disposition"), "attachment; filename=backup-1-")
+
+		other := memory.NewMemoryObjectRepository(nil)
+		request := newRestoreBackupRequest(dummyUserID, "replace", responce.Body.String())
+		responce = httptest.NewRecorder()
+		server.NewRentObjectServer(other).ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		var report backup.Report
+		json.NewDecoder(responce.Body).Decode(&report)
+		assert.Equal(t, backup.Report{Mode: backup.Replace, ObjectsAdded: 1, RecordsAdded: 1, RulesAdded: 1}, report)
+
+		got, _ := other.GetByName(ctx, dummyUserID, object.Name)
+		assert.Equal(t, object, got)
+	})
+
+	t.Run("Unsupported version", func(t *testing.T) {
+		s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(nil))
+
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newRestoreBackupRequest(dummyUserID, "", `{"version": 99}`))
+		assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("Unknown mode", func(t *testing.T) {
+		s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(nil))
+
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newRestoreBackupRequest(dummyUserID, "overwrite", `{"version": 1}`))
+		assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+	})
+}
+
+func newExportBackupRequest(userID int64) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/exportBackup?%s=%d", server.UserIdQueryParam, userID), nil)
+	return req
+}
+
+func newRestoreBackupRequest(userID int64, mode string, data string) *http.Request {
+	uri := fmt.Sprintf("/restoreBackup?%s=%d&%s=%s", server.UserIdQueryParam, userID, server.ModeQueryParam, mode)
+	req, _ := http.NewRequest(http.MethodPost, uri, strings.NewReader(data))
+	return req
+}
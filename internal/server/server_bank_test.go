@@ -0,0 +1,210 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rental-server/internal/bankimport"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository/memory"
+	"rental-server/internal/server"
+	"rental-server/internal/server/requests"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var dummyRule = domain.MatchRule{ObjectName: dummyObject.Name, Field: "rent", Direction: domain.IncomingPayment, Counterparty: "7701234567"}
+
+const dummyStatement = `1CClientBankExchange
+ВерсияФормата=1.03
+Кодировка=Windows
+РасчСчет=40702810900000000001
+СекцияДокумент=Платежное поручение
+Номер=15
+Дата=09.01.2025
+Сумма=50000.00
+ПлательщикСчет=40702810500000000002
+Плательщик1=ООО "Ромашка"
+ПлательщикИНН=7701234567
+ПолучательСчет=40702810900000000001
+ДатаПоступило=10.01.2025
+НазначениеПлатежа=Арендная плата за январь
+КонецДокумента
+СекцияДокумент=Платежное поручение
+Номер=16
+Дата=11.01.2025
+Сумма=10.00
+ПлательщикСчет=40702810500000000003
+Плательщик1=ООО "Лютик"
+ПолучательСчет=40702810900000000001
+ДатаПоступило=11.01.2025
+КонецДокумента
+КонецФайла
+`
+
+func TestMatchRules(t *testing.T) {
+	t.Run("Happy path. Add and get rules", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		s := server.NewRentObjectServer(rep)
+
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newAddMatchRuleRequest(dummyUserID, dummyRule))
+		assertStatus(t, responce.Code, http.StatusCreated)
+
+		responce = httptest.NewRecorder()
+		s.ServeHTTP(responce, newGetMatchRulesRequest(dummyUserID))
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		var got []domain.MatchRule
+		json.NewDecoder(responce.Body).Decode(&got)
+		assert.Equal(t, []domain.MatchRule{dummyRule}, got)
+	})
+
+	t.Run("Invalid rule", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		s := server.NewRentObjectServer(rep)
+
+		rule := dummyRule
+		rule.Field = "income"
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newAddMatchRuleRequest(dummyUserID, rule))
+		assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+
+		rules, _ := rep.GetMatchRules(ctx, dummyUserID)
+		assert.Empty(t, rules)
+	})
+
+	t.Run("Delete rule", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		index, _ := rep.AddMatchRule(ctx, dummyUserID, dummyRule)
+		s := server.NewRentObjectServer(rep)
+
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newDeleteMatchRuleRequest(dummyUserID, index))
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		responce = httptest.NewRecorder()
+		s.ServeHTTP(responce, newDeleteMatchRuleRequest(dummyUserID, index))
+		assertStatus(t, responce.Code, http.StatusNotFound)
+	})
+}
+
+func TestImportBankStatement(t *testing.T) {
+	t.Run("Happy path. Propose records and confirm them", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_ = rep.Add(ctx, dummyUserID, dummyObject)
+		_, _ = rep.AddMatchRule(ctx, dummyUserID, dummyRule)
+		s := server.NewRentObjectServer(rep)
+
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newImportBankStatementRequest(dummyUserID, dummyStatement))
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		var proposal bankimport.Proposal
+		json.NewDecoder(responce.Body).Decode(&proposal)
+		want := []bankimport.ProposedRecord{
+			{ObjectName: dummyObject.Name, Record: domain.Record{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 50000}},
+		}
+		assert.Equal(t, want, proposal.Records)
+		assert.Len(t, proposal.Matched, 1)
+		assert.Len(t, proposal.Unmatched, 1)
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, dummyObject.Name)
+		assert.Empty(t, records, "records are added only when confirmed")
+
+		responce = httptest.NewRecorder()
+		s.ServeHTTP(responce, newConfirmBankRecordsRequest(dummyUserID, proposal.Records))
+		assertStatus(t, responce.Code, http.StatusCreated)
+
+		records, _ = rep.GetAllRecords(ctx, dummyUserID, dummyObject.Name)
+		assert.Equal(t, []domain.Record{want[0].Record}, records)
+	})
+
+	t.Run("Not a statement", func(t *testing.T) {
+		s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(nil))
+
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newImportBankStatementRequest(dummyUserID, "date,rent\n"))
+		assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("Nothing is confirmed when an object is missing", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_ = rep.Add(ctx, dummyUserID, dummyObject)
+		s := server.NewRentObjectServer(rep)
+
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newConfirmBankRecordsRequest(dummyUserID, []bankimport.ProposedRecord{
+			{ObjectName: dummyObject.Name, Record: domain.Record{Rent: 1}},
+			{ObjectName: "missing", Record: domain.Record{Rent: 2}},
+		}))
+		assertStatus(t, responce.Code, http.StatusNotFound)
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, dummyObject.Name)
+		assert.Empty(t, records)
+	})
+
+	t.Run("Confirming again does not duplicate records", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_ = rep.Add(ctx, dummyUserID, dummyObject)
+		s := server.NewRentObjectServer(rep)
+		first := domain.Record{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 50000}
+		second := domain.Record{Date: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Rent: 50000}
+
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newConfirmBankRecordsRequest(dummyUserID, []bankimport.ProposedRecord{
+			{ObjectName: dummyObject.Name, Record: first},
+		}))
+		assertStatus(t, responce.Code, http.StatusCreated)
+
+		responce = httptest.NewRecorder()
+		s.ServeHTTP(responce, newConfirmBankRecordsRequest(dummyUserID, []bankimport.ProposedRecord{
+			{ObjectName: dummyObject.Name, Record: first},
+			{ObjectName: dummyObject.Name, Record: second},
+		}))
+		assertStatus(t, responce.Code, http.StatusCreated)
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, dummyObject.Name)
+		assert.Equal(t, []domain.Record{first, second}, records)
+	})
+}
+
+func newAddMatchRuleRequest(userID int64, rule domain.MatchRule) *http.Request {
+	buf := &bytes.Buffer{}
+	json.NewEncoder(buf).Encode(requests.AddMatchRuleRequest{UserID: &userID, Rule: &rule})
+
+	req, _ := http.NewRequest(http.MethodPost, "/addMatchRule", buf)
+	return req
+}
+
+func newDeleteMatchRuleRequest(userID int64, ruleIndex int) *http.Request {
+	buf := &bytes.Buffer{}
+	json.NewEncoder(buf).Encode(requests.DeleteMatchRuleRequest{UserID: &userID, RuleIndex: &ruleIndex})
+
+	req, _ := http.NewRequest(http.MethodPost, "/deleteMatchRule", buf)
+	return req
+}
+
+func newGetMatchRulesRequest(userID int64) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, fmt.Sprintf("/getMatchRules?%s=%d", server.UserIdQueryParam, userID), nil)
+	return req
+}
+
+func newImportBankStatementRequest(userID int64, data string) *http.Request {
+	uri := fmt.Sprintf("/importBankStatement?%s=%d", server.UserIdQueryParam, userID)
+	req, _ := http.NewRequest(http.MethodPost, uri, strings.NewReader(data))
+	return req
+}
+
+func newConfirmBankRecordsRequest(userID int64, records []bankimport.ProposedRecord) *http.Request {
+	buf := &bytes.Buffer{}
+	json.NewEncoder(buf).Encode(requests.ConfirmBankRecordsRequest{UserID: &userID, Records: &records})
+
+	req, _ := http.NewRequest(http.MethodPost, "/confirmBankRecords", buf)
+	return req
+}
@@ -0,0 +1,57 @@
+package server_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"rental-server/internal/repository/memory"
+	"rental-server/internal/server"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type pinger struct {
+	err error
+}
+
+func (p *pinger) Ping(ctx context.Context) error {
+	return p.err
+}
+
+func TestHealth(t *testing.T) {
+	storage := &pinger{}
+	s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(nil), server.WithPinger(storage))
+
+	get := func(path string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, request)
+		return responce
+	}
+
+	t.Run("Should be alive and ready while storage answers", func(t *testing.T) {
+		assertStatus(t, get(server.HealthzPath).Code, http.StatusOK)
+		assertStatus(t, get(server.ReadyzPath).Code, http.StatusOK)
+	})
+
+	t.Run("Should stay alive but not ready while storage is down", func(t *testing.T) {
+		storage.err = errors.New("server selection timeout")
+		defer func() { storage.err = nil }()
+
+		assertStatus(t, get(server.HealthzPath).Code, http.StatusOK)
+		responce := get(server.ReadyzPath)
+		assertStatus(t, responce.Code, http.StatusServiceUnavailable)
+		assert.NotContains(t, responce.Body.String(), "server selection timeout")
+	})
+
+	t.Run("Should be ready without a pinger", func(t *testing.T) {
+		s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(nil))
+		request, _ := http.NewRequest(http.MethodGet, server.ReadyzPath, nil)
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, request)
+
+		assertStatus(t, responce.Code, http.StatusOK)
+	})
+}
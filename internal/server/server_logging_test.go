@@ -0,0 +1,84 @@
+package server_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository/memory"
+	"rental-server/internal/server"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// brokenRepository fails to read objects.
+type brokenRepository struct {
+	*memory.MemoryObjectRepository
+}
+
+func (r brokenRepository) GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	return domain.RentObject{}, errors.New("storage is down")
+}
+
+func TestRequestLogging(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+	s := server.NewRentObjectServer(brokenRepository{memory.NewMemoryObjectRepository(nil)}, server.WithLogger(logger))
+
+	lastLog := func(t *testing.T) map[string]any {
+		t.Helper()
+		lines := bytes.Split(bytes.TrimSpace(logs.Bytes()), []byte("\n"))
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(lines[len(lines)-1], &entry))
+		return entry
+	}
+
+	t.Run("Should log requests with a new request ID", func(t *testing.T) {
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newAddObjectRequest(dummyUserID, domain.NewRentObject("Name", "Description", 100)))
+		assertStatus(t, responce.Code, http.StatusCreated)
+
+		id := responce.Header().Get(server.RequestIDHeader)
+		assert.Len(t, id, 32)
+		entry := lastLog(t)
+		assert.Equal(t, "INFO", entry["level"])
+		assert.Equal(t, id, entry["request_id"])
+		assert.Equal(t, http.MethodPost, entry["method"])
+		assert.Equal(t, "/addObject", entry["route"])
+		assert.Equal(t, float64(http.StatusCreated), entry["status"])
+		assert.Equal(t, float64(dummyUserID), entry["user_id"])
+		assert.Contains(t, entry, "duration")
+		assert.NotContains(t, entry, "error")
+	})
+
+	t.Run("Should log the error of server errors with the given request ID", func(t *testing.T) {
+		request := newGetObjectRequest(dummyUserID, "Name")
+		request.Header.Set(server.RequestIDHeader, "from-proxy")
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusInternalServerError)
+
+		assert.Equal(t, "from-proxy", responce.Header().Get(server.RequestIDHeader))
+		assert.NotContains(t, responce.Body.String(), "storage is down")
+		entry := lastLog(t)
+		assert.Equal(t, "ERROR", entry["level"])
+		assert.Equal(t, "from-proxy", entry["request_id"])
+		assert.Equal(t, float64(dummyUserID), entry["user_id"])
+		assert.Equal(t, "storage is down", entry["error"])
+	})
+
+	t.Run("Should replace invalid request IDs", func(t *testing.T) {
+		request := newGetObjectRequest(dummyUserID, "Name")
+		request.Header.Set(server.RequestIDHeader, "forged\nline")
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, request)
+
+		assert.Len(t, responce.Header().Get(server.RequestIDHeader), 32)
+	})
+}
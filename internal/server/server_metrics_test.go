@@ -0,0 +1,43 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository/memory"
+	"rental-server/internal/server"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type observedRequest struct {
+	route string
+	code  int
+}
+
+type requestObserver struct {
+	requests []observedRequest
+}
+
+func (o *requestObserver) ObserveRequest(route string, code int, duration time.Duration) {
+	o.requests = append(o.requests, observedRequest{route, code})
+}
+
+func TestRequestObserver(t *testing.T) {
+	observer := &requestObserver{}
+	s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(nil), server.WithRequestObserver(observer))
+
+	for _, request := range []*http.Request{
+		newAddObjectRequest(dummyUserID, domain.NewRentObject("Name", "Description", 100)),
+		newDeleteObjectRequest(dummyUserID, "Unknown"),
+	} {
+		s.ServeHTTP(httptest.NewRecorder(), request)
+	}
+
+	assert.Equal(t, []observedRequest{
+		{"/addObject", http.StatusCreated},
+		{"/deleteObject", http.StatusNotFound},
+	}, observer.requests)
+}
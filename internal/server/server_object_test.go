@@ -2,19 +2,28 @@ package server_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"rental-server/internal/domain"
+	"rental-server/internal/exporter"
+	"rental-server/internal/repository"
 	"rental-server/internal/repository/memory"
 	"rental-server/internal/server"
 	"rental-server/internal/server/requests"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
 )
 
+var ctx = context.Background()
+
 var dummyObject = domain.NewRentObject("Name", "Description", 1000)
 var dummyUserID int64 = 1
 
@@ -29,7 +38,7 @@ func TestAddObject(t *testing.T) {
 		s.ServeHTTP(responce, request)
 		assertStatus(t, responce.Code, http.StatusCreated)
 
-		objects, _ := rep.GetAll(dummyUserID)
+		objects, _ := rep.GetAll(ctx, dummyUserID)
 		if !assert.Len(t, objects, 1) {
 			t.Fatal()
 		}
@@ -64,7 +73,7 @@ func TestDeleteObject(t *testing.T) {
 		s.ServeHTTP(responce, request)
 		assertStatus(t, responce.Code, http.StatusOK)
 
-		objects, _ := rep.GetAll(dummyUserID)
+		objects, _ := rep.GetAll(ctx, dummyUserID)
 		assert.Len(t, objects, 0)
 	})
 	t.Run("Should return UnprocessableEntity on wrong request", func(t *testing.T) {
@@ -110,7 +119,7 @@ func TestUpdateObject(t *testing.T) {
 
 		newObject := dummyObject.Update(updateInput)
 
-		got, _ := rep.GetByName(dummyUserID, dummyObject.Name)
+		got, _ := rep.GetByName(ctx, dummyUserID, newObject.Name)
 
 		assert.Equal(t, newObject, got)
 	})
@@ -139,7 +148,7 @@ func TestUpdateObject(t *testing.T) {
 func TestGetObjectByID(t *testing.T) {
 	object := dummyObject
 	rep := memory.NewMemoryObjectRepository(nil)
-	_ = rep.Add(dummyUserID, object)
+	_ = rep.Add(ctx, dummyUserID, object)
 
 	s := server.NewRentObjectServer(rep)
 
@@ -155,13 +164,46 @@ func TestGetObjectByID(t *testing.T) {
 	assert.JSONEq(t, buf.String(), responce.Body.String())
 }
 
+// slowRepository reads objects only when its context is done.
+type slowRepository struct {
+	*memory.MemoryObjectRepository
+}
+
+func (r slowRepository) GetByName(ctx context.Context, userID int64, objectName string) (domain.RentObject, error) {
+	<-ctx.Done()
+	return domain.RentObject{}, ctx.Err()
+}
+
+func TestRepositoryTimeout(t *testing.T) {
+	t.Run("Should return GatewayTimeout when storage is slow", func(t *testing.T) {
+		rep := repository.WithTimeouts(slowRepository{memory.NewMemoryObjectRepository(nil)}, repository.Timeouts{Read: 10 * time.Millisecond})
+		s := server.NewRentObjectServer(rep)
+
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newGetObjectRequest(dummyUserID, "Name"))
+
+		assertStatus(t, responce.Code, http.StatusGatewayTimeout)
+	})
+
+	t.Run("Should stop when client goes away", func(t *testing.T) {
+		s := server.NewRentObjectServer(slowRepository{memory.NewMemoryObjectRepository(nil)})
+		canceled, cancel := context.WithCancel(ctx)
+		cancel()
+
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, newGetObjectRequest(dummyUserID, "Name").WithContext(canceled))
+
+		assertStatus(t, responce.Code, server.StatusClientClosedRequest)
+	})
+}
+
 func TestGetAll(t *testing.T) {
 	rep := memory.NewMemoryObjectRepository(nil)
 	var objects []domain.RentObject
 	for i := 0; i < 10; i++ {
 		object := dummyObject
 		object.Name = fmt.Sprintf("Name%d", i)
-		_ = rep.Add(dummyUserID, object)
+		_ = rep.Add(ctx, dummyUserID, object)
 		objects = append(objects, object)
 	}
 
@@ -179,6 +221,60 @@ func TestGetAll(t *testing.T) {
 	assert.Equal(t, objects, gotObjects)
 }
 
+func TestFindObjects(t *testing.T) {
+	rep := memory.NewMemoryObjectRepository(nil)
+	for i := 0; i < 5; i++ {
+		object := dummyObject
+		object.Name = fmt.Sprintf("Name%d", i)
+		object.Area = float64(100 * (5 - i))
+		_ = rep.Add(ctx, dummyUserID, object)
+	}
+
+	s := server.NewRentObjectServer(rep)
+
+	t.Run("Should return page with next cursor", func(t *testing.T) {
+		request := newGetAllPageRequest(dummyUserID, url.Values{server.SortQueryParam: {"area"}, server.LimitQueryParam: {"2"}})
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		var got repository.Page[domain.RentObject]
+		json.NewDecoder(responce.Body).Decode(&got)
+
+		if assert.Len(t, got.Items, 2) {
+			assert.Equal(t, "Name4", got.Items[0].Name)
+			assert.Equal(t, "Name3", got.Items[1].Name)
+		}
+
+		request = newGetAllPageRequest(dummyUserID, url.Values{server.SortQueryParam: {"area"}, server.CursorQueryParam: {got.NextCursor}})
+		responce = httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		got = repository.Page[domain.RentObject]{}
+		json.NewDecoder(responce.Body).Decode(&got)
+		assert.Len(t, got.Items, 3)
+		assert.Empty(t, got.NextCursor)
+	})
+
+	t.Run("Should return UnprocessableEntity on wrong parameters", func(t *testing.T) {
+		for _, params := range []url.Values{
+			{server.SortQueryParam: {"date"}},
+			{server.OrderQueryParam: {"up"}},
+			{server.LimitQueryParam: {"many"}},
+			{server.CursorQueryParam: {"garbage"}},
+		} {
+			request := newGetAllPageRequest(dummyUserID, params)
+			responce := httptest.NewRecorder()
+
+			s.ServeHTTP(responce, request)
+			assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+		}
+	})
+}
+
 func TestGetObjectInfo(t *testing.T) {
 	object := domain.RentObject{
 		Area: 100,
@@ -215,6 +311,180 @@ func TestGetObjectInfo(t *testing.T) {
 
 }
 
+func TestGetObjectInfoForPeriod(t *testing.T) {
+	object := domain.RentObject{Area: 100}
+	for month := time.January; month <= time.December; month++ {
+		object.AddRecord(domain.Record{Date: time.Date(2024+int(month)%2, month, 1, 0, 0, 0, 0, time.UTC), Rent: 100, EarthRent: 50})
+	}
+
+	store := memory.MemoryStore{
+		dummyUserID: {
+			dummyObject.Name: object,
+		},
+	}
+	s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(store))
+
+	t.Run("should report only records of the period", func(t *testing.T) {
+		request := newGetObjectInfoRequest(dummyUserID, dummyObject.Name)
+		request.URL.RawQuery += "&" + url.Values{server.FromQueryParam: {"2025-01-01"}, server.ToQueryParam: {"2026-01-01"}}.Encode()
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		var got domain.RentObjectInfo
+		_ = json.NewDecoder(responce.Body).Decode(&got)
+
+		assert.Len(t, got.RecordsInfo, 6)
+		assert.Equal(t, domain.RUB(300), got.Total.Profit)
+		assert.Equal(t, domain.RUB(3), got.Total.ProfitByArea)
+	})
+
+	t.Run("should return 422 on malformed dates", func(t *testing.T) {
+		request := newGetObjectInfoRequest(dummyUserID, dummyObject.Name)
+		request.URL.RawQuery += "&" + url.Values{server.FromQueryParam: {"2025"}}.Encode()
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+
+		assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+	})
+}
+
+func TestExport(t *testing.T) {
+	first := domain.NewRentObject("First", "", 10)
+	first.AddRecord(domain.Record{Date: time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC), Rent: 100})
+	first.AddRecord(domain.Record{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 200})
+	second := domain.NewRentObject("Second", "", 10)
+
+	store := memory.MemoryStore{
+		dummyUserID: {first.Name: first, second.Name: second},
+	}
+	s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(store))
+
+	t.Run("Export object to CSV for the period", func(t *testing.T) {
+		request := newExportRequest("/exportObject", url.Values{
+			server.UserIdQueryParam:     {fmt.Sprint(dummyUserID)},
+			server.ObjectNameQueryParam: {first.Name},
+			server.FromQueryParam:       {"2025-01-01"},
+		})
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusOK)
+		assert.Equal(t, "text/csv; charset=utf-8", responce.Header().Get("Content-Type"))
+		assert.Equal(t, `attachment; filename=First.csv`, responce.Header().Get("Content-Disposition"))
+
+		rows, err := csv.NewReader(responce.Body).ReadAll()
+		assert.NoError(t, err)
+		if assert.Len(t, rows, 3) {
+			assert.Equal(t, []string{"First", "2025-01-01"}, rows[1][:2])
+			assert.Equal(t, []string{"First", "total", "200"}, rows[2][:3])
+		}
+	})
+
+	t.Run("Export all objects to XLSX", func(t *testing.T) {
+		request := newExportRequest("/exportAll", url.Values{
+			server.UserIdQueryParam: {fmt.Sprint(dummyUserID)},
+			server.FormatQueryParam: {"xlsx"},
+		})
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		f, err := excelize.OpenReader(responce.Body)
+		if !assert.NoError(t, err) {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		assert.Equal(t, []string{exporter.PortfolioSheet, "First", "Second"}, f.GetSheetList())
+	})
+
+	t.Run("Export all objects to beancount with configured accounts", func(t *testing.T) {
+		request := newExportRequest("/exportAll", url.Values{
+			server.UserIdQueryParam:  {fmt.Sprint(dummyUserID)},
+			server.FormatQueryParam:  {"beancount"},
+			server.AccountQueryParam: {"balance=Assets:Bank:Tinkoff"},
+		})
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusOK)
+		assert.Equal(t, `attachment; filename=portfolio.beancount`, responce.Header().Get("Content-Disposition"))
+		assert.Contains(t, responce.Body.String(), "2025-01-01 * \"First\"\n  Income:Rent:First    -200.00 RUB\n  Assets:Bank:Tinkoff   200.00 RUB\n")
+	})
+
+	t.Run("Unknown account field", func(t *testing.T) {
+		request := newExportRequest("/exportAll", url.Values{
+			server.UserIdQueryParam:  {fmt.Sprint(dummyUserID)},
+			server.FormatQueryParam:  {"ledger"},
+			server.AccountQueryParam: {"profit=Assets:Bank"},
+		})
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		request := newExportRequest("/exportAll", url.Values{
+			server.UserIdQueryParam: {fmt.Sprint(dummyUserID)},
+			server.FormatQueryParam: {"pdf"},
+		})
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("Object not found", func(t *testing.T) {
+		request := newExportRequest("/exportObject", url.Values{
+			server.UserIdQueryParam:     {fmt.Sprint(dummyUserID)},
+			server.ObjectNameQueryParam: {"Unknown"},
+		})
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusNotFound)
+	})
+}
+
+func TestGetObjectReport(t *testing.T) {
+	object := domain.NewRentObject("Склад", "", 10)
+	object.AddRecord(domain.Record{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 100})
+	s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(memory.MemoryStore{
+		dummyUserID: {object.Name: object},
+	}))
+
+	t.Run("Happy path. Get PDF report", func(t *testing.T) {
+		request := newExportRequest("/getObjectReport", url.Values{
+			server.UserIdQueryParam:     {fmt.Sprint(dummyUserID)},
+			server.ObjectNameQueryParam: {object.Name},
+			server.FromQueryParam:       {"2025-01-01"},
+			server.ToQueryParam:         {"2025-02-01"},
+		})
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusOK)
+		assert.Equal(t, "application/pdf", responce.Header().Get("Content-Type"))
+		assert.True(t, bytes.HasPrefix(responce.Body.Bytes(), []byte("%PDF-")))
+	})
+
+	t.Run("Object not found", func(t *testing.T) {
+		request := newExportRequest("/getObjectReport", url.Values{
+			server.UserIdQueryParam:     {fmt.Sprint(dummyUserID)},
+			server.ObjectNameQueryParam: {"Unknown"},
+		})
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusNotFound)
+	})
+}
+
 func newAddObjectRequest(userId int64, object domain.RentObject) *http.Request {
 	buf := &bytes.Buffer{}
 	data := requests.AddObjectRequest{
@@ -266,6 +536,17 @@ func newGetAllRequest(userId int64) *http.Request {
 	return req
 }
 
+func newGetAllPageRequest(userId int64, params url.Values) *http.Request {
+	params.Set(server.UserIdQueryParam, fmt.Sprint(userId))
+	req, _ := http.NewRequest(http.MethodGet, "/getAll?"+params.Encode(), nil)
+	return req
+}
+
+func newExportRequest(path string, params url.Values) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, path+"?"+params.Encode(), nil)
+	return req
+}
+
 func newGetObjectInfoRequest(userId int64, objectName string) *http.Request {
 	path := fmt.Sprintf("/getObjectInfo?%s=%d&%s=%s", server.UserIdQueryParam, userId, server.ObjectNameQueryParam, objectName)
 	req, _ := http.NewRequest(http.MethodGet, path, nil)
@@ -6,11 +6,16 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"rental-server/internal/domain"
+	"rental-server/internal/importer"
+	"rental-server/internal/repository"
 	"rental-server/internal/repository/memory"
 	"rental-server/internal/server"
 	"rental-server/internal/server/requests"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -20,7 +25,7 @@ var dummyRecord = domain.Record{}
 func TestAddRecord(t *testing.T) {
 	t.Run("Happy path. Add record", func(t *testing.T) {
 		rep := memory.NewMemoryObjectRepository(nil)
-		_ = rep.Add(dummyUserID, dummyObject)
+		_ = rep.Add(ctx, dummyUserID, dummyObject)
 
 		s := server.NewRentObjectServer(rep)
 
@@ -30,7 +35,7 @@ func TestAddRecord(t *testing.T) {
 		s.ServeHTTP(responce, request)
 		assertStatus(t, responce.Code, http.StatusOK)
 
-		object, _ := rep.GetByName(dummyUserID, dummyObject.Name)
+		object, _ := rep.GetByName(ctx, dummyUserID, dummyObject.Name)
 
 		assert.Contains(t, object.Records, dummyRecord)
 	})
@@ -38,8 +43,8 @@ func TestAddRecord(t *testing.T) {
 
 func TestDeleteRecord(t *testing.T) {
 	rep := memory.NewMemoryObjectRepository(nil)
-	_ = rep.Add(dummyUserID, dummyObject)
-	recordPos, _ := rep.AddRecord(dummyUserID, dummyObject.Name, dummyRecord)
+	_ = rep.Add(ctx, dummyUserID, dummyObject)
+	recordPos, _ := rep.AddRecord(ctx, dummyUserID, dummyObject.Name, dummyRecord)
 
 	s := server.NewRentObjectServer(rep)
 
@@ -49,7 +54,7 @@ func TestDeleteRecord(t *testing.T) {
 	s.ServeHTTP(responce, request)
 	assertStatus(t, responce.Code, http.StatusOK)
 
-	object, _ := rep.GetByName(dummyUserID, dummyObject.Name)
+	object, _ := rep.GetByName(ctx, dummyUserID, dummyObject.Name)
 	assert.NotContains(t, object.Records, dummyRecord)
 
 }
@@ -58,7 +63,7 @@ func TestUpdateRecord(t *testing.T) {
 	rep := memory.NewMemoryObjectRepository(nil)
 	object := dummyObject
 	recordPos := object.AddRecord(dummyRecord)
-	_ = rep.Add(dummyUserID, object)
+	_ = rep.Add(ctx, dummyUserID, object)
 
 	s := server.NewRentObjectServer(rep)
 
@@ -72,7 +77,7 @@ func TestUpdateRecord(t *testing.T) {
 	assertStatus(t, responce.Code, http.StatusOK)
 
 	want := dummyRecord.Update(updateInput)
-	got, _ := rep.GetRecordByIndex(dummyUserID, dummyObject.Name, recordPos)
+	got, _ := rep.GetRecordByIndex(ctx, dummyUserID, dummyObject.Name, recordPos)
 
 	assert.Equal(t, got, want)
 }
@@ -82,7 +87,7 @@ func TestGetRecordByIndex(t *testing.T) {
 	object := dummyObject
 	record := domain.Record{Rent: 1000}
 	recordPos := object.AddRecord(record)
-	_ = rep.Add(dummyUserID, object)
+	_ = rep.Add(ctx, dummyUserID, object)
 
 	s := server.NewRentObjectServer(rep)
 
@@ -104,7 +109,7 @@ func TestGetRecords(t *testing.T) {
 
 	object.AddRecord(dummyRecord)
 	object.AddRecord(dummyRecord)
-	_ = rep.Add(dummyUserID, object)
+	_ = rep.Add(ctx, dummyUserID, object)
 
 	s := server.NewRentObjectServer(rep)
 
@@ -120,6 +125,125 @@ func TestGetRecords(t *testing.T) {
 	assert.Len(t, got, 2)
 }
 
+func TestFindRecords(t *testing.T) {
+	rep := memory.NewMemoryObjectRepository(nil)
+	object := dummyObject
+	for month := 1; month <= 6; month++ {
+		object.AddRecord(domain.Record{Date: time.Date(2025, time.Month(month), 1, 0, 0, 0, 0, time.UTC), Rent: domain.RUB(month * 100)})
+	}
+	_ = rep.Add(ctx, dummyUserID, object)
+
+	s := server.NewRentObjectServer(rep)
+
+	request := newGetRecordsPageRequest(dummyUserID, dummyObject.Name, url.Values{
+		server.FromQueryParam:      {"2025-02-01"},
+		server.ToQueryParam:        {"2025-06-01T00:00:00Z"},
+		server.MinAmountQueryParam: {"300"},
+		server.SortQueryParam:      {"amount"},
+		server.OrderQueryParam:     {"desc"},
+	})
+	responce := httptest.NewRecorder()
+
+	s.ServeHTTP(responce, request)
+	assertStatus(t, responce.Code, http.StatusOK)
+
+	var got repository.Page[repository.RecordEntry]
+	json.NewDecoder(responce.Body).Decode(&got)
+
+	var indexes []int
+	for _, entry := range got.Items {
+		indexes = append(indexes, entry.Index)
+	}
+	assert.Equal(t, []int{4, 3, 2}, indexes)
+}
+
+func TestImportRecords(t *testing.T) {
+	data := "Месяц;Аренда;Охрана\n01.01.2025;\"10 000,50\";1 000\n01.02.2025;10 000;\n"
+	mapping := url.Values{server.MapQueryParam: {"date=Месяц", "rent=Аренда", "security=Охрана"}}
+
+	t.Run("Happy path. Import records", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_ = rep.Add(ctx, dummyUserID, dummyObject)
+		s := server.NewRentObjectServer(rep)
+
+		request := newImportRecordsRequest(dummyUserID, dummyObject.Name, mapping, data)
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		var got importer.Report
+		json.NewDecoder(responce.Body).Decode(&got)
+		assert.Equal(t, 2, got.Imported)
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, dummyObject.Name)
+		assert.Equal(t, []domain.Record{
+			{Date: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), Rent: 10000.5, Security: 1000},
+			{Date: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC), Rent: 10000},
+		}, records)
+	})
+
+	t.Run("Dry run reports invalid rows", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_ = rep.Add(ctx, dummyUserID, dummyObject)
+		s := server.NewRentObjectServer(rep)
+
+		params := url.Values{server.DryRunQueryParam: {"true"}}
+		for key, values := range mapping {
+			params[key] = values
+		}
+		request := newImportRecordsRequest(dummyUserID, dummyObject.Name, params, data+"31.02.2025;1;1\n")
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		var got importer.Report
+		json.NewDecoder(responce.Body).Decode(&got)
+		assert.Equal(t, importer.Report{Records: 2, DryRun: true, Errors: []importer.RowError{
+			{Line: 4, Column: "Месяц", Message: `Invalid date "31.02.2025"`},
+		}}, got)
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, dummyObject.Name)
+		assert.Empty(t, records)
+	})
+
+	t.Run("Invalid rows fail import", func(t *testing.T) {
+		rep := memory.NewMemoryObjectRepository(nil)
+		_ = rep.Add(ctx, dummyUserID, dummyObject)
+		s := server.NewRentObjectServer(rep)
+
+		request := newImportRecordsRequest(dummyUserID, dummyObject.Name, mapping, data+"01.03.2025;много;\n")
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+
+		records, _ := rep.GetAllRecords(ctx, dummyUserID, dummyObject.Name)
+		assert.Empty(t, records)
+	})
+
+	t.Run("Unknown field in mapping", func(t *testing.T) {
+		s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(nil))
+
+		request := newImportRecordsRequest(dummyUserID, dummyObject.Name, url.Values{server.MapQueryParam: {"date=Месяц", "income=Аренда"}}, data)
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("Object not found", func(t *testing.T) {
+		s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(nil))
+
+		request := newImportRecordsRequest(dummyUserID, dummyObject.Name, mapping, data)
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusNotFound)
+	})
+}
+
 func newAddRecordRequest(userID int64, objectName string, record domain.Record) *http.Request {
 	buf := &bytes.Buffer{}
 
@@ -177,3 +301,17 @@ func newGetRecordsRequest(userID int64, objectName string) *http.Request {
 	req, _ := http.NewRequest(http.MethodGet, uri, nil)
 	return req
 }
+
+func newGetRecordsPageRequest(userID int64, objectName string, params url.Values) *http.Request {
+	params.Set(server.UserIdQueryParam, fmt.Sprint(userID))
+	params.Set(server.ObjectNameQueryParam, objectName)
+	req, _ := http.NewRequest(http.MethodGet, "/getRecords?"+params.Encode(), nil)
+	return req
+}
+
+func newImportRecordsRequest(userID int64, objectName string, params url.Values, data string) *http.Request {
+	params.Set(server.UserIdQueryParam, fmt.Sprint(userID))
+	params.Set(server.ObjectNameQueryParam, objectName)
+	req, _ := http.NewRequest(http.MethodPost, "/importRecords?"+params.Encode(), strings.NewReader(data))
+	return req
+}
@@ -0,0 +1,55 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"rental-server/internal/repository/memory"
+	"rental-server/internal/server"
+	"rental-server/internal/server/openapi"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPI(t *testing.T) {
+	s := server.NewRentObjectServer(memory.NewMemoryObjectRepository(nil))
+
+	t.Run("Should serve document describing every endpoint", func(t *testing.T) {
+		request, _ := http.NewRequest(http.MethodGet, server.OpenAPIPath, nil)
+		responce := httptest.NewRecorder()
+
+		s.ServeHTTP(responce, request)
+		assertStatus(t, responce.Code, http.StatusOK)
+		assert.Equal(t, "application/json", responce.Header().Get("Content-Type"))
+
+		var doc openapi.Document
+		if err := json.NewDecoder(responce.Body).Decode(&doc); !assert.NoError(t, err) {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, openapi.Version, doc.OpenAPI)
+		for _, path := range []string{
+			"/addObject", "/deleteObject", "/updateObject", "/getObject", "/getObjectInfo", "/getAll",
+			"/addRecord", "/deleteRecord", "/updateRecord", "/getRecord", "/getRecords", "/importRecords",
+			"/exportObject", "/exportAll", "/getObjectReport", "/exportBackup", "/restoreBackup",
+			"/addMatchRule", "/deleteMatchRule", "/getMatchRules", "/importBankStatement", "/confirmBankRecords",
+			"/getObjectTotals", "/getPeriodTotals", "/getCategoryTotals", "/healthz", "/readyz",
+		} {
+			assert.Contains(t, doc.Paths, path)
+		}
+		for _, schema := range []string{"RentObject", "Record", "RentObjectInfo", "AddObjectRequest", "UpdateRecordInput", "PageRentObject", "PageRecordEntry"} {
+			assert.Contains(t, doc.Components.Schemas, schema)
+		}
+	})
+
+	t.Run("Should serve bundled swagger ui", func(t *testing.T) {
+		for _, path := range []string{server.SwaggerUIPath, server.SwaggerUIPath + "swagger-ui-bundle.js"} {
+			request, _ := http.NewRequest(http.MethodGet, path, nil)
+			responce := httptest.NewRecorder()
+
+			s.ServeHTTP(responce, request)
+			assertStatus(t, responce.Code, http.StatusOK)
+		}
+	})
+}
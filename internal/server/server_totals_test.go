@@ -0,0 +1,76 @@
+package server_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/memory"
+	"rental-server/internal/server"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTotals(t *testing.T) {
+	rep := memory.NewMemoryObjectRepository(nil)
+	object := domain.NewRentObject("Shop", "", 10)
+	object.AddRecord(domain.Record{Date: time.Date(2024, time.January, 10, 0, 0, 0, 0, time.UTC), Rent: 1000, Heat: 100})
+	object.AddRecord(domain.Record{Date: time.Date(2024, time.April, 10, 0, 0, 0, 0, time.UTC), Rent: 2000})
+	rep.Add(ctx, dummyUserID, object)
+	s := server.NewRentObjectServer(rep)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		request, _ := http.NewRequest(http.MethodGet, path, nil)
+		responce := httptest.NewRecorder()
+		s.ServeHTTP(responce, request)
+		return responce
+	}
+
+	t.Run("Should return totals of each object in the period", func(t *testing.T) {
+		responce := get(fmt.Sprintf("/getObjectTotals?userId=%d&to=2024-02-01", dummyUserID))
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		var got []repository.ObjectTotals
+		json.NewDecoder(responce.Body).Decode(&got)
+		assert.Equal(t, []repository.ObjectTotals{
+			{Name: "Shop", Totals: repository.Totals{Income: 1000, Expenses: 100, Profit: 900, Records: 1}},
+		}, got)
+	})
+
+	t.Run("Should return totals of each quarter", func(t *testing.T) {
+		responce := get(fmt.Sprintf("/getPeriodTotals?userId=%d&objectName=Shop&granularity=quarter", dummyUserID))
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		var got []repository.PeriodTotals
+		json.NewDecoder(responce.Body).Decode(&got)
+		if assert.Len(t, got, 2) {
+			assert.True(t, got[1].Start.Equal(time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)))
+			assert.Equal(t, domain.RUB(2000), got[1].Income)
+		}
+	})
+
+	t.Run("Should return sums of categories", func(t *testing.T) {
+		responce := get(fmt.Sprintf("/getCategoryTotals?userId=%d", dummyUserID))
+		assertStatus(t, responce.Code, http.StatusOK)
+
+		var got []repository.CategoryTotal
+		json.NewDecoder(responce.Body).Decode(&got)
+		if assert.Len(t, got, len(repository.Categories)) {
+			assert.Equal(t, repository.CategoryTotal{Category: "heat", Amount: 100}, got[1])
+		}
+	})
+
+	t.Run("Should return 404 for a missing object", func(t *testing.T) {
+		responce := get(fmt.Sprintf("/getCategoryTotals?userId=%d&objectName=Missing", dummyUserID))
+		assertStatus(t, responce.Code, http.StatusNotFound)
+	})
+
+	t.Run("Should return 422 for unknown granularity", func(t *testing.T) {
+		responce := get(fmt.Sprintf("/getPeriodTotals?userId=%d&granularity=week", dummyUserID))
+		assertStatus(t, responce.Code, http.StatusUnprocessableEntity)
+	})
+}
@@ -0,0 +1,50 @@
+package server_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"rental-server/internal/domain"
+	"rental-server/internal/repository"
+	"rental-server/internal/repository/memory"
+	"rental-server/internal/server"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	rep := repository.WithTracing(memory.NewMemoryObjectRepository(nil))
+	s := server.NewRentObjectServer(rep)
+
+	traceID := "4bf92f3577b34da6a3ce929d0e0e4736"
+	request := newAddObjectRequest(dummyUserID, domain.NewRentObject("Name", "Description", 100))
+	request.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	responce := httptest.NewRecorder()
+	s.ServeHTTP(responce, request)
+	assertStatus(t, responce.Code, http.StatusCreated)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	names := []string{}
+	for _, span := range spans {
+		names = append(names, span.Name())
+		assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+	}
+	assert.Equal(t, []string{"parseRequest", "repository.Add", "POST /addObject"}, names)
+
+	handler := spans[2]
+	assert.Equal(t, spans[0].Parent().SpanID(), handler.SpanContext().SpanID())
+	assert.Equal(t, spans[1].Parent().SpanID(), handler.SpanContext().SpanID())
+	assert.Contains(t, handler.Attributes(), attribute.Int("http.response.status_code", http.StatusCreated))
+	assert.Contains(t, handler.Attributes(), attribute.String("http.route", "/addObject"))
+	assert.Contains(t, handler.Attributes(), attribute.Int64("rental.user_id", dummyUserID))
+}
@@ -0,0 +1,370 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"rental-server/internal/backup"
+	"rental-server/internal/bankimport"
+	"rental-server/internal/domain"
+	"rental-server/internal/exporter"
+	"rental-server/internal/importer"
+	"rental-server/internal/repository"
+	graphqlapi "rental-server/internal/server/graphql"
+	"rental-server/internal/server/openapi"
+	"rental-server/internal/server/requests"
+	"strconv"
+	"time"
+)
+
+var OpenAPIPath = "/openapi.json"
+var SwaggerUIPath = "/docs/"
+
+func NewOpenAPIDocument() *openapi.Document {
+	doc := openapi.NewDocument(openapi.Info{
+		Title:       "Rental server API",
+		Description: "Rent objects of a user and monthly records of their income and expenses.",
+		Version:     "1.0.0",
+	})
+
+	userID := openapi.Parameter{Name: UserIdQueryParam, In: "query", Required: true, Schema: doc.SchemaOf(int64(0))}
+	objectName := openapi.Parameter{Name: ObjectNameQueryParam, In: "query", Required: true, Schema: doc.SchemaOf("")}
+	recordIndex := openapi.Parameter{Name: RecordIndexQueryParam, In: "query", Required: true, Schema: doc.SchemaOf(0)}
+	optional := func(name string, v any, description string) openapi.Parameter {
+		schema := doc.SchemaOf(v)
+		if _, ok := v.(time.Time); ok {
+			schema.Format = ""
+		}
+		return openapi.Parameter{Name: name, In: "query", Description: description, Schema: schema}
+	}
+	limit := optional(LimitQueryParam, 0, fmt.Sprintf("Page size, %d by default and at most %d", repository.DefaultPageLimit, repository.MaxPageLimit))
+	cursor := optional(CursorQueryParam, "", "Value of next_cursor of the previous page")
+	order := optional(OrderQueryParam, "", "asc (default) or desc")
+	period := func(what string) []openapi.Parameter {
+		return []openapi.Parameter{
+			optional(FromQueryParam, time.Time{}, "Only "+what+" dated at or after this date, RFC 3339 or YYYY-MM-DD"),
+			optional(ToQueryParam, time.Time{}, "Only "+what+" dated before this date, RFC 3339 or YYYY-MM-DD"),
+		}
+	}
+
+	body := func(v any) *openapi.RequestBody {
+		return &openapi.RequestBody{Required: true, Content: openapi.JSONContent(doc.RequestSchemaOf(v))}
+	}
+	ok := func(description string, v any) *openapi.Response {
+		response := &openapi.Response{Description: description}
+		if v != nil {
+			response.Content = openapi.JSONContent(doc.SchemaOf(v))
+		}
+		return response
+	}
+	oneOf := func(description string, values ...any) *openapi.Response {
+		schema := &openapi.Schema{}
+		for _, v := range values {
+			schema.OneOf = append(schema.OneOf, doc.SchemaOf(v))
+		}
+		return &openapi.Response{Description: description, Content: openapi.JSONContent(schema)}
+	}
+	responses := func(success string, response *openapi.Response, errorCodes ...int) map[string]*openapi.Response {
+		result := map[string]*openapi.Response{success: response}
+		for _, code := range append(errorCodes, http.StatusUnprocessableEntity, http.StatusInternalServerError, http.StatusGatewayTimeout) {
+			result[strconv.Itoa(code)] = &openapi.Response{Description: errorDescriptions[code], Content: openapi.TextContent()}
+		}
+		return result
+	}
+
+	doc.Post("/addObject", &openapi.Operation{
+		OperationID: "addObject",
+		Summary:     "Add a rent object",
+		Tags:        []string{"objects"},
+		RequestBody: body(requests.AddObjectRequest{}),
+		Responses:   responses("201", ok("Object created", nil), http.StatusConflict),
+	})
+	doc.Post("/deleteObject", &openapi.Operation{
+		OperationID: "deleteObject",
+		Summary:     "Delete a rent object",
+		Tags:        []string{"objects"},
+		RequestBody: body(requests.DeleteObjectRequest{}),
+		Responses:   responses("200", ok("Object deleted", nil), http.StatusNotFound),
+	})
+	doc.Post("/updateObject", &openapi.Operation{
+		OperationID: "updateObject",
+		Summary:     "Update name, description or area of a rent object",
+		Tags:        []string{"objects"},
+		RequestBody: body(requests.UpdateObjectRequest{}),
+		Responses:   responses("200", ok("Object updated", nil), http.StatusNotFound),
+	})
+	doc.Get("/getObject", &openapi.Operation{
+		OperationID: "getObject",
+		Summary:     "Get a rent object with its records",
+		Tags:        []string{"objects"},
+		Parameters:  []openapi.Parameter{userID, objectName},
+		Responses:   responses("200", ok("Rent object", domain.RentObject{}), http.StatusNotFound),
+	})
+	doc.Get("/getObjectInfo", &openapi.Operation{
+		OperationID: "getObjectInfo",
+		Summary:     "Get a rent object report with computed income, expenses and profit",
+		Tags:        []string{"objects"},
+		Parameters:  append([]openapi.Parameter{userID, objectName}, period("records")...),
+		Responses:   responses("200", ok("Rent object report", domain.RentObjectInfo{}), http.StatusNotFound),
+	})
+	doc.Get("/getAll", &openapi.Operation{
+		OperationID: "getAll",
+		Summary:     "Get rent objects of a user",
+		Description: "Returns all the objects sorted by name, or a page of them when any of the paging, sorting or filter parameters is given.",
+		Tags:        []string{"objects"},
+		Parameters: append([]openapi.Parameter{
+			userID, limit, cursor, order,
+			optional(SortQueryParam, "", "name (default), area or profit"),
+			optional(NamePrefixQueryParam, "", "Only objects whose name starts with the prefix"),
+			optional(MinProfitQueryParam, domain.RUB(0), "Only objects with at least this profit"),
+			optional(MaxProfitQueryParam, domain.RUB(0), "Only objects with at most this profit"),
+		}, period("records counted in the profit")...),
+		Responses: responses("200", oneOf("Rent objects or a page of them", []domain.RentObject{}, repository.Page[domain.RentObject]{})),
+	})
+	doc.Post("/addRecord", &openapi.Operation{
+		OperationID: "addRecord",
+		Summary:     "Add a record to a rent object",
+		Tags:        []string{"records"},
+		RequestBody: body(requests.AddRecordRequest{}),
+		Responses:   responses("200", ok("Record added", nil), http.StatusNotFound),
+	})
+	doc.Post("/deleteRecord", &openapi.Operation{
+		OperationID: "deleteRecord",
+		Summary:     "Delete a record of a rent object",
+		Tags:        []string{"records"},
+		RequestBody: body(requests.DeleteRecordRequest{}),
+		Responses:   responses("200", ok("Record deleted", nil), http.StatusNotFound, http.StatusConflict),
+	})
+	doc.Post("/updateRecord", &openapi.Operation{
+		OperationID: "updateRecord",
+		Summary:     "Update a record of a rent object",
+		Tags:        []string{"records"},
+		RequestBody: body(requests.UpdateRecordRequest{}),
+		Responses:   responses("200", ok("Record updated", nil), http.StatusNotFound),
+	})
+	doc.Get("/getRecord", &openapi.Operation{
+		OperationID: "getRecord",
+		Summary:     "Get a record of a rent object by index",
+		Tags:        []string{"records"},
+		Parameters:  []openapi.Parameter{userID, objectName, recordIndex},
+		Responses:   responses("200", ok("Record", domain.Record{}), http.StatusNotFound),
+	})
+	doc.Get("/getRecords", &openapi.Operation{
+		OperationID: "getRecords",
+		Summary:     "Get records of a rent object",
+		Description: "Returns all the records sorted by date, or a page of them with their indexes when any of the paging, sorting or filter parameters is given.",
+		Tags:        []string{"records"},
+		Parameters: append([]openapi.Parameter{
+			userID, objectName, limit, cursor, order,
+			optional(SortQueryParam, "", "date (default) or amount, which is the record profit"),
+			optional(MinAmountQueryParam, domain.RUB(0), "Only records with at least this profit"),
+			optional(MaxAmountQueryParam, domain.RUB(0), "Only records with at most this profit"),
+		}, period("records")...),
+		Responses: responses("200", oneOf("Records or a page of them", []domain.Record{}, repository.Page[repository.RecordEntry]{}), http.StatusNotFound),
+	})
+
+	doc.Post("/importRecords", &openapi.Operation{
+		OperationID: "importRecords",
+		Summary:     "Add records of a rent object from CSV with a header row",
+		Description: "Amounts may use comma decimals and spaces between thousands. " +
+			"Nothing is added on a dry run or when any row is invalid, and the invalid rows are reported.",
+		Tags: []string{"records"},
+		Parameters: []openapi.Parameter{
+			userID, objectName,
+			optional(DryRunQueryParam, false, "Only validate the rows"),
+			{
+				Name: MapQueryParam, In: "query",
+				Description: "Column mapping as field=Column, repeated for each column. Several columns mapped to an amount field are summed up. " +
+					"Without mapping the columns named as record fields are imported.",
+				Schema: doc.SchemaOf([]string{}),
+			},
+			optional(CommaQueryParam, "", "Field separator, guessed from the header by default"),
+			optional(DateLayoutQueryParam, "", "Go time layout of dates, e.g. 02.01.2006. Common layouts and month names are guessed by default"),
+		},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.CSVContent()},
+		Responses: func() map[string]*openapi.Response {
+			result := responses("200", ok("Import report", importer.Report{}), http.StatusNotFound)
+			result["422"].Content["application/json"] = openapi.MediaType{Schema: doc.SchemaOf(importer.Report{})}
+			result["422"].Description += ", or import report with invalid rows"
+			return result
+		}(),
+	})
+
+	format := optional(FormatQueryParam, "", "csv (default), xlsx, ledger or beancount")
+	account := openapi.Parameter{
+		Name: AccountQueryParam, In: "query",
+		Description: "Account of ledger and beancount exports as field=Account, repeated for each field, or balance=Account for the account of the profit. " +
+			fmt.Sprintf("%s in the account is replaced with the object name, e.g. rent=Income:Rent:%s.", exporter.ObjectPlaceholder, exporter.ObjectPlaceholder),
+		Schema: doc.SchemaOf([]string{}),
+	}
+	exported := &openapi.Response{
+		Description: "Records with computed income, expenses and profit, and totals rows, or a transaction for each record in ledger and beancount",
+		Content:     openapi.FileContent(exportContentTypes["csv"], exportContentTypes["xlsx"], exportContentTypes["ledger"]),
+	}
+	doc.Get("/exportObject", &openapi.Operation{
+		OperationID: "exportObject",
+		Summary:     "Export records of a rent object to CSV, XLSX, ledger or beancount",
+		Tags:        []string{"export"},
+		Parameters:  append([]openapi.Parameter{userID, objectName, format, account}, period("records")...),
+		Responses:   responses("200", exported, http.StatusNotFound),
+	})
+	doc.Get("/exportAll", &openapi.Operation{
+		OperationID: "exportAll",
+		Summary:     "Export records of all rent objects of a user to CSV, XLSX, ledger or beancount",
+		Description: "The XLSX workbook has a portfolio sheet with the totals of every object and a sheet per object. " +
+			"Ledger and beancount transactions are ordered by object name and date, so that re-exports diff cleanly.",
+		Tags:       []string{"export"},
+		Parameters: append([]openapi.Parameter{userID, format, account}, period("records")...),
+		Responses:  responses("200", exported),
+	})
+
+	doc.Get("/getObjectReport", &openapi.Operation{
+		OperationID: "getObjectReport",
+		Summary:     "Get a PDF statement of a rent object for its owner",
+		Description: "The statement has the object details, a table of records, expenses by category, totals and profit per square meter.",
+		Tags:        []string{"export"},
+		Parameters:  append([]openapi.Parameter{userID, objectName}, period("records")...),
+		Responses: responses("200", &openapi.Response{
+			Description: "PDF statement",
+			Content:     openapi.FileContent("application/pdf"),
+		}, http.StatusNotFound),
+	})
+
+	totalsObject := optional(ObjectNameQueryParam, "", "Only records of the object, of all objects by default")
+	doc.Get("/getObjectTotals", &openapi.Operation{
+		OperationID: "getObjectTotals",
+		Summary:     "Get income, expenses and profit of each rent object of a user",
+		Description: "Objects without records in the period have zero totals.",
+		Tags:        []string{"analytics"},
+		Parameters:  append([]openapi.Parameter{userID, totalsObject}, period("records")...),
+		Responses:   responses("200", ok("Totals sorted by object name", []repository.ObjectTotals{}), http.StatusNotFound),
+	})
+	doc.Get("/getPeriodTotals", &openapi.Operation{
+		OperationID: "getPeriodTotals",
+		Summary:     "Get income, expenses and profit of each month, quarter or year",
+		Description: "Periods start in UTC, and periods without records are left out.",
+		Tags:        []string{"analytics"},
+		Parameters: append([]openapi.Parameter{
+			userID, totalsObject,
+			optional(GranularityQueryParam, "", "month (default), quarter or year"),
+		}, period("records")...),
+		Responses: responses("200", ok("Totals sorted by period", []repository.PeriodTotals{}), http.StatusNotFound),
+	})
+	doc.Get("/getCategoryTotals", &openapi.Operation{
+		OperationID: "getCategoryTotals",
+		Summary:     "Get the sum of each income and expense category",
+		Tags:        []string{"analytics"},
+		Parameters:  append([]openapi.Parameter{userID, totalsObject}, period("records")...),
+		Responses:   responses("200", ok("Sums of rent and each expense", []repository.CategoryTotal{}), http.StatusNotFound),
+	})
+
+	doc.Get("/exportBackup", &openapi.Operation{
+		OperationID: "exportBackup",
+		Summary:     "Back up the objects, records and match rules of a user",
+		Tags:        []string{"backup"},
+		Parameters:  []openapi.Parameter{userID},
+		Responses:   responses("200", ok(fmt.Sprintf("Backup of version %d", backup.Version), backup.Backup{})),
+	})
+	doc.Post("/restoreBackup", &openapi.Operation{
+		OperationID: "restoreBackup",
+		Summary:     "Restore a backup to a user",
+		Description: "In merge mode the objects, records and match rules that the user lacks are added, and existing objects take the description and area of the backup. " +
+			"In replace mode all the data of the user is deleted first. The backup may come from another user or server.",
+		Tags: []string{"backup"},
+		Parameters: []openapi.Parameter{
+			userID,
+			optional(ModeQueryParam, "", "merge (default) or replace"),
+		},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSONContent(doc.SchemaOf(backup.Backup{}))},
+		Responses:   responses("200", ok("Restore report", backup.Report{})),
+	})
+
+	doc.Post("/addMatchRule", &openapi.Operation{
+		OperationID: "addMatchRule",
+		Summary:     "Add a rule that matches bank payments to a record field of a rent object",
+		Description: "A payment matches when it meets every condition that is set. A counterparty of 10 or 12 digits is compared with the INN, " +
+			"otherwise it is a part of the counterparty name, as the purpose is a part of the purpose of payment.",
+		Tags:        []string{"bank"},
+		RequestBody: body(requests.AddMatchRuleRequest{}),
+		Responses:   responses("201", ok("Match rule added", nil)),
+	})
+	doc.Post("/deleteMatchRule", &openapi.Operation{
+		OperationID: "deleteMatchRule",
+		Summary:     "Delete a match rule by index",
+		Tags:        []string{"bank"},
+		RequestBody: body(requests.DeleteMatchRuleRequest{}),
+		Responses:   responses("200", ok("Match rule deleted", nil), http.StatusNotFound),
+	})
+	doc.Get("/getMatchRules", &openapi.Operation{
+		OperationID: "getMatchRules",
+		Summary:     "Get match rules of a user in the order they are tried",
+		Tags:        []string{"bank"},
+		Parameters:  []openapi.Parameter{userID},
+		Responses:   responses("200", ok("Match rules", []domain.MatchRule{})),
+	})
+	doc.Post("/importBankStatement", &openapi.Operation{
+		OperationID: "importBankStatement",
+		Summary:     "Match payments of a 1CClientBankExchange statement to rent objects",
+		Description: "Each payment goes to the first rule it matches, and the matched payments are summed up into a record per object and month. " +
+			"Nothing is added until the proposed records are confirmed.",
+		Tags:        []string{"bank"},
+		Parameters:  []openapi.Parameter{userID},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.FileContent("text/plain")},
+		Responses:   responses("200", ok("Proposed records with matched and unmatched payments", bankimport.Proposal{})),
+	})
+	doc.Post("/confirmBankRecords", &openapi.Operation{
+		OperationID: "confirmBankRecords",
+		Summary:     "Add the proposed records, possibly edited, to their rent objects, skipping records that were already added",
+		Tags:        []string{"bank"},
+		RequestBody: body(requests.ConfirmBankRecordsRequest{}),
+		Responses:   responses("201", ok("Records added", nil), http.StatusNotFound),
+	})
+
+	doc.Get(HealthzPath, &openapi.Operation{
+		OperationID: "healthz",
+		Summary:     "Check that the server is alive",
+		Description: "Succeeds whether the storage is available or not.",
+		Tags:        []string{"health"},
+		Responses:   map[string]*openapi.Response{"200": {Description: "Server is alive", Content: openapi.TextContent()}},
+	})
+	doc.Get(ReadyzPath, &openapi.Operation{
+		OperationID: "readyz",
+		Summary:     "Check that the server can serve requests",
+		Description: "Fails while the storage does not answer pings.",
+		Tags:        []string{"health"},
+		Responses: map[string]*openapi.Response{
+			"200": {Description: "Server is ready", Content: openapi.TextContent()},
+			"503": {Description: "Storage is unavailable", Content: openapi.TextContent()},
+		},
+	})
+
+	doc.Post(GraphQLPath, &openapi.Operation{
+		OperationID: "graphql",
+		Summary:     "Query objects, records and computed metrics, or modify them, with GraphQL",
+		Tags:        []string{"graphql"},
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSONContent(doc.SchemaOf(graphqlapi.Request{}))},
+		Responses:   responses("200", ok("GraphQL result with data and errors", map[string]any{})),
+	})
+
+	return doc
+}
+
+var errorDescriptions = map[int]string{
+	http.StatusNotFound:            "Object, record or match rule not found",
+	http.StatusConflict:            "Object already exists, or changed concurrently",
+	http.StatusUnprocessableEntity: "Malformed request body or query parameters",
+	http.StatusInternalServerError: "Error happend on server",
+	http.StatusGatewayTimeout:      "Storage did not respond in time",
+}
+
+func openAPIHandler(doc *openapi.Document) http.Handler {
+	data, err := json.Marshal(doc)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, "Error happend on server", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
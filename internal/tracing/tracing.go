@@ -0,0 +1,117 @@
+// Package tracing sets up OpenTelemetry tracing of the service. Spans are
+// made with the global tracer provider, so packages only depend on the
+// OpenTelemetry API, and nothing is traced until Start is called.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const ServiceName = "rental-server"
+
+// Exporters of spans.
+const (
+	// ExportNone traces nothing.
+	ExportNone = "none"
+	// ExportOTLP sends spans to the collector of the standard
+	// OTEL_EXPORTER_OTLP_ENDPOINT environment variable, by gRPC.
+	ExportOTLP = "otlp"
+	// ExportStdout writes spans to the standard output as JSON.
+	ExportStdout = "stdout"
+	// ExportFile appends spans to a file as JSON, which works offline.
+	ExportFile = "file"
+)
+
+// Start sets up the global tracer provider with the exporter, and the
+// propagation of W3C trace context. path is the file of ExportFile,
+// traces.json by default. The returned function flushes spans and stops
+// tracing.
+func Start(ctx context.Context, exporter string, path string) (func(context.Context) error, error) {
+	var spanExporter sdktrace.SpanExporter
+	var file io.Closer
+	var err error
+	switch exporter {
+	case "", ExportNone:
+		return func(context.Context) error { return nil }, nil
+	case ExportOTLP:
+		spanExporter, err = otlptracegrpc.New(ctx)
+	case ExportStdout:
+		spanExporter, err = stdouttrace.New()
+	case ExportFile:
+		if path == "" {
+			path = "traces.json"
+		}
+		var f *os.File
+		if f, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644); err != nil {
+			return nil, err
+		}
+		if spanExporter, err = stdouttrace.New(stdouttrace.WithWriter(f)); err != nil {
+			f.Close()
+			return nil, err
+		}
+		file = f
+	default:
+		return nil, fmt.Errorf("unknown traces exporter %q, want none, otlp, stdout or file", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if file != nil {
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// LogHandler adds the IDs of the trace and span of the context to records,
+// so that logs can be found from traces.
+type LogHandler struct {
+	slog.Handler
+}
+
+func NewLogHandler(handler slog.Handler) LogHandler {
+	return LogHandler{handler}
+}
+
+func (h LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanContext.TraceID().String()),
+			slog.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return LogHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h LogHandler) WithGroup(name string) slog.Handler {
+	return LogHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,68 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"rental-server/internal/tracing"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestLogHandler(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(tracing.NewLogHandler(slog.NewJSONHandler(&logs, nil))).With("component", "test")
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "span")
+	defer span.End()
+
+	t.Run("Should add IDs of the span", func(t *testing.T) {
+		logs.Reset()
+		logger.InfoContext(ctx, "traced")
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+		assert.Equal(t, span.SpanContext().TraceID().String(), entry["trace_id"])
+		assert.Equal(t, span.SpanContext().SpanID().String(), entry["span_id"])
+		assert.Equal(t, "test", entry["component"])
+	})
+
+	t.Run("Should leave out IDs without a span", func(t *testing.T) {
+		logs.Reset()
+		logger.InfoContext(context.Background(), "untraced")
+
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(logs.Bytes(), &entry))
+		assert.NotContains(t, entry, "trace_id")
+	})
+}
+
+func TestStart(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Should write spans to the file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces.json")
+		stop, err := tracing.Start(ctx, tracing.ExportFile, path)
+		require.NoError(t, err)
+
+		_, span := otel.Tracer("test").Start(ctx, "operation")
+		span.End()
+		require.NoError(t, stop(ctx))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), `"Name":"operation"`)
+		assert.Contains(t, string(data), tracing.ServiceName)
+	})
+
+	t.Run("Should reject unknown exporters", func(t *testing.T) {
+		_, err := tracing.Start(ctx, "zipkin", "")
+		assert.Error(t, err)
+	})
+}